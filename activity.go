@@ -1,5 +1,11 @@
 package notionapi
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Author represents the author of an Edit
 type Author struct {
 	ID    string `json:"id"`
@@ -21,6 +27,9 @@ type Edit struct {
 	BlockID   string `json:"block_id"`
 	BlockData struct {
 		BlockValue Block `json:"block_value"`
+		// for Type == "block-changed"
+		Before *BlockSnapshot `json:"before"`
+		After  *BlockSnapshot `json:"after"`
 	} `json:"block_data"`
 	NavigableBlockID string `json:"navigable_block_id"`
 
@@ -56,3 +65,119 @@ type Activity struct {
 
 	RawJSON map[string]interface{} `json:"-"`
 }
+
+// BlockSnapshot is a state of a block before or after an Edit
+type BlockSnapshot struct {
+	BlockValue *Block `json:"block_value"`
+}
+
+// EditKind is a kind of change described by an Edit
+type EditKind int
+
+const (
+	// EditUnknown is an edit we don't recognize
+	EditUnknown EditKind = iota
+	EditBlockCreated
+	EditBlockChanged
+	EditBlockDeleted
+	EditCommentAdded
+	// EditCollectionRowChanged is a row of a collection that was
+	// created, changed or deleted
+	EditCollectionRowChanged
+	EditPermissionChanged
+)
+
+var editKindNames = []string{
+	"unknown",
+	"block created",
+	"block changed",
+	"block deleted",
+	"comment added",
+	"collection row changed",
+	"permission changed",
+}
+
+func (k EditKind) String() string {
+	if k < 0 || int(k) >= len(editKindNames) {
+		return editKindNames[EditUnknown]
+	}
+	return editKindNames[k]
+}
+
+// Kind returns a kind of the edit, based on Type
+func (e *Edit) Kind() EditKind {
+	typ := e.Type
+	switch {
+	case strings.Contains(typ, "permission"):
+		return EditPermissionChanged
+	case typ == "comment-created":
+		return EditCommentAdded
+	case strings.HasPrefix(typ, "collection-row-"):
+		return EditCollectionRowChanged
+	case strings.HasPrefix(typ, "block-"):
+		if e.CollectionRowID != "" && e.CollectionRowID == e.BlockID {
+			return EditCollectionRowChanged
+		}
+	}
+	switch typ {
+	case "block-created":
+		return EditBlockCreated
+	case "block-changed":
+		return EditBlockChanged
+	case "block-deleted":
+		return EditBlockDeleted
+	}
+	return EditUnknown
+}
+
+func snapshotBlock(s *BlockSnapshot) *Block {
+	if s == nil || s.BlockValue == nil || s.BlockValue.ID == "" {
+		return nil
+	}
+	return s.BlockValue
+}
+
+// Before returns the block before the edit. nil for created blocks
+// and edits that are not about blocks
+func (e *Edit) Before() *Block {
+	if b := snapshotBlock(e.BlockData.Before); b != nil {
+		return b
+	}
+	if e.BlockData.BlockValue.ID == "" || strings.HasSuffix(e.Type, "-created") {
+		return nil
+	}
+	if strings.HasSuffix(e.Type, "-deleted") {
+		return &e.BlockData.BlockValue
+	}
+	return nil
+}
+
+// After returns the block after the edit. nil for deleted blocks
+// and edits that are not about blocks
+func (e *Edit) After() *Block {
+	if b := snapshotBlock(e.BlockData.After); b != nil {
+		return b
+	}
+	if e.BlockData.BlockValue.ID == "" || strings.HasSuffix(e.Type, "-deleted") {
+		return nil
+	}
+	return &e.BlockData.BlockValue
+}
+
+func msToTime(ms int64) time.Time {
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC()
+}
+
+// Time returns the time of the edit
+func (e *Edit) Time() time.Time {
+	return msToTime(e.Timestamp)
+}
+
+// Time returns the time the activity started. Zero if unknown
+func (a *Activity) Time() time.Time {
+	ms, err := strconv.ParseInt(a.StartTime, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return msToTime(ms)
+}
@@ -0,0 +1,226 @@
+package notionapi
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultActivityPageSize is how many activities ActivityIterator asks
+// for with each request if ActivityLogRequest.PageSize is not set
+const DefaultActivityPageSize = 50
+
+// ActivityLogRequest describes which activities of a space to iterate over
+type ActivityLogRequest struct {
+	SpaceID string
+	// if not zero, only return activities that started at or after Since
+	Since time.Time
+	// if not zero, only return activities that started before Until
+	Until time.Time
+	// if set, only return activities in this page or its sub-pages
+	AncestorID string
+	// number of activities fetched with each request
+	PageSize int
+}
+
+// ActivityIterator iterates over the activity log of a space, from the
+// most recent activity, fetching it from the server as needed:
+//
+//	it := client.IterateActivityLog(ctx, req)
+//	for it.Next() {
+//		for _, edit := range it.Activity().Edits {
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ActivityIterator struct {
+	client *Client
+	ctx    context.Context
+	req    ActivityLogRequest
+
+	recordMap *RecordMap
+	ids       []string
+	nextID    string
+	done      bool
+	// table:id of a block or collection => id of its parent block,
+	// for ActivityLogRequest.AncestorID
+	parents map[string]string
+
+	activity *Activity
+	err      error
+}
+
+// IterateActivityLog returns an iterator over the activity log of a space
+func (c *Client) IterateActivityLog(ctx context.Context, req ActivityLogRequest) *ActivityIterator {
+	if req.PageSize <= 0 {
+		req.PageSize = DefaultActivityPageSize
+	}
+	if req.AncestorID != "" {
+		req.AncestorID = ToDashID(req.AncestorID)
+	}
+	return &ActivityIterator{
+		client:  c,
+		ctx:     ctx,
+		req:     req,
+		parents: map[string]string{},
+	}
+}
+
+func (it *ActivityIterator) fetchMore() error {
+	rsp, err := it.client.GetActivityLogCtx(it.ctx, it.req.SpaceID, it.nextID, it.req.PageSize)
+	if err != nil {
+		return err
+	}
+	it.recordMap = rsp.RecordMap
+	it.ids = rsp.ActivityIDs
+	it.nextID = rsp.NextID
+	if len(rsp.ActivityIDs) < it.req.PageSize {
+		it.done = true
+	}
+	return nil
+}
+
+// parent returns id of a block that is a parent of a block or a
+// collection, "" if there is none. Records that are not in the
+// response are fetched from the server
+func (it *ActivityIterator) parent(table string, id string) (string, error) {
+	key := table + ":" + id
+	if p, ok := it.parents[key]; ok {
+		return p, nil
+	}
+	var parentTable, parentID string
+	switch table {
+	case TableBlock:
+		var b *Block
+		if r := it.recordMap.Blocks[id]; r != nil {
+			b = r.Block
+		}
+		if b == nil {
+			blocks, err := it.client.GetBlockRecordsCtx(it.ctx, []string{id})
+			if err != nil {
+				return "", err
+			}
+			b = blocks[0]
+		}
+		if b != nil {
+			parentTable, parentID = b.ParentTable, b.ParentID
+		}
+	case TableCollection:
+		var c *Collection
+		if r := it.recordMap.Collections[id]; r != nil {
+			c = r.Collection
+		}
+		if c == nil {
+			colls, err := it.client.GetCollectionRecordsCtx(it.ctx, []string{id})
+			if err != nil {
+				return "", err
+			}
+			c = colls[0]
+		}
+		if c != nil {
+			parentTable, parentID = c.ParentTable, c.ParentID
+		}
+	}
+	res := ""
+	switch parentTable {
+	case TableBlock:
+		res = parentID
+	case TableCollection:
+		var err error
+		if res, err = it.parent(TableCollection, parentID); err != nil {
+			return "", err
+		}
+	}
+	it.parents[key] = res
+	return res, nil
+}
+
+// inAncestor returns true if the activity is in ActivityLogRequest.AncestorID page
+func (it *ActivityIterator) inAncestor(a *Activity) (bool, error) {
+	if it.req.AncestorID == "" {
+		return true, nil
+	}
+	id := a.NavigableBlockID
+	if id == "" {
+		id = a.CollectionRowID
+	}
+	if id == "" && a.ParentTable == TableBlock {
+		id = a.ParentID
+	}
+	// guard against cycles
+	for i := 0; id != "" && i < 256; i++ {
+		if id == it.req.AncestorID {
+			return true, nil
+		}
+		var err error
+		if id, err = it.parent(TableBlock, id); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// next returns the next activity from the current response that
+// matches the request
+func (it *ActivityIterator) next() (*Activity, error) {
+	for len(it.ids) > 0 {
+		id := it.ids[0]
+		it.ids = it.ids[1:]
+		rec := it.recordMap.Activities[id]
+		if rec == nil || rec.Activity == nil {
+			continue
+		}
+		a := rec.Activity
+		t := a.Time()
+		if !it.req.Until.IsZero() && !t.Before(it.req.Until) {
+			continue
+		}
+		if !it.req.Since.IsZero() && t.Before(it.req.Since) {
+			// activities are from the most recent so the rest is older
+			it.ids = nil
+			it.done = true
+			return nil, nil
+		}
+		ok, err := it.inAncestor(a)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// Next advances to the next activity. Returns false when there are no
+// more activities or there was an error
+func (it *ActivityIterator) Next() bool {
+	for it.err == nil {
+		if it.recordMap != nil {
+			a, err := it.next()
+			if err != nil {
+				it.err = err
+				break
+			}
+			if a != nil {
+				it.activity = a
+				return true
+			}
+		}
+		if it.done {
+			break
+		}
+		it.err = it.fetchMore()
+	}
+	it.activity = nil
+	return false
+}
+
+// Activity returns the current activity
+func (it *ActivityIterator) Activity() *Activity {
+	return it.activity
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ActivityIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,115 @@
+package notionapi_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/notiontest"
+)
+
+func TestActivityLog(t *testing.T) {
+	s := notiontest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+
+	putBlock := func(id, parentID, parentTable string) {
+		require.NoError(t, s.Store.Put(notionapi.TableBlock, map[string]interface{}{
+			"id":           id,
+			"type":         "page",
+			"alive":        true,
+			"parent_id":    parentID,
+			"parent_table": parentTable,
+		}))
+	}
+	putBlock("root", "space", "space")
+	putBlock("sub", "root", "block")
+	putBlock("other", "space", "space")
+	putBlock("table", "root", "block")
+	require.NoError(t, s.Store.Put(notionapi.TableCollection, map[string]interface{}{
+		"id":           "coll",
+		"parent_id":    "table",
+		"parent_table": "block",
+	}))
+	putBlock("row", "coll", "collection")
+
+	day := func(d int) string {
+		ms := time.Date(2021, 5, d, 12, 0, 0, 0, time.UTC).UnixNano() / 1e6
+		return fmt.Sprintf("%d", ms)
+	}
+	putActivity := func(id string, d int, pageID string, edit map[string]interface{}) {
+		a := map[string]interface{}{
+			"id":                 id,
+			"space_id":           "space",
+			"start_time":         day(d),
+			"end_time":           day(d),
+			"navigable_block_id": pageID,
+			"edits":              []interface{}{edit},
+		}
+		if pageID == "row" {
+			delete(a, "navigable_block_id")
+			a["collection_row_id"] = "row"
+		}
+		require.NoError(t, s.Store.Put(notionapi.TableActivity, a))
+	}
+	putActivity("a1", 1, "root", map[string]interface{}{
+		"type":       "block-created",
+		"block_id":   "b1",
+		"block_data": map[string]interface{}{"block_value": map[string]interface{}{"id": "b1", "version": 1}},
+	})
+	putActivity("a2", 2, "sub", map[string]interface{}{
+		"type":     "block-changed",
+		"block_id": "b2",
+		"block_data": map[string]interface{}{
+			"before": map[string]interface{}{"block_value": map[string]interface{}{"id": "b2", "version": 1}},
+			"after":  map[string]interface{}{"block_value": map[string]interface{}{"id": "b2", "version": 2}},
+		},
+	})
+	putActivity("a3", 3, "other", map[string]interface{}{"type": "comment-created"})
+	putActivity("a4", 4, "row", map[string]interface{}{"type": "block-changed", "block_id": "row", "collection_row_id": "row"})
+	putActivity("a5", 5, "other", map[string]interface{}{"type": "space-permission-changed"})
+
+	collect := func(req notionapi.ActivityLogRequest) ([]string, []notionapi.EditKind) {
+		req.SpaceID = "space"
+		req.PageSize = 2
+		it := client.IterateActivityLog(context.Background(), req)
+		var ids []string
+		var kinds []notionapi.EditKind
+		for it.Next() {
+			a := it.Activity()
+			ids = append(ids, a.ID)
+			kinds = append(kinds, a.Edits[0].Kind())
+		}
+		require.NoError(t, it.Err())
+		return ids, kinds
+	}
+	ids, kinds := collect(notionapi.ActivityLogRequest{})
+	require.Equal(t, []string{"a5", "a4", "a3", "a2", "a1"}, ids)
+	require.Equal(t, []notionapi.EditKind{
+		notionapi.EditPermissionChanged,
+		notionapi.EditCollectionRowChanged,
+		notionapi.EditCommentAdded,
+		notionapi.EditBlockChanged,
+		notionapi.EditBlockCreated,
+	}, kinds)
+
+	ids, _ = collect(notionapi.ActivityLogRequest{
+		Since: time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2021, 5, 5, 0, 0, 0, 0, time.UTC),
+	})
+	require.Equal(t, []string{"a4", "a3", "a2"}, ids)
+
+	ids, _ = collect(notionapi.ActivityLogRequest{AncestorID: "root"})
+	require.Equal(t, []string{"a4", "a2", "a1"}, ids)
+
+	it := client.IterateActivityLog(context.Background(), notionapi.ActivityLogRequest{SpaceID: "space", AncestorID: "sub"})
+	require.True(t, it.Next())
+	edit := it.Activity().Edits[0]
+	require.Equal(t, 1, int(edit.Before().Version))
+	require.Equal(t, 2, int(edit.After().Version))
+	require.False(t, it.Next())
+	require.NoError(t, it.Err())
+}
@@ -0,0 +1,46 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestEditKind(t *testing.T) {
+	s := `[
+{"type":"block-created","timestamp":1620043200000,"block_id":"b1","block_data":{"block_value":{"id":"b1","version":1}}},
+{"type":"block-changed","block_id":"b1","block_data":{"before":{"block_value":{"id":"b1","version":1}},"after":{"block_value":{"id":"b1","version":2}}}},
+{"type":"block-deleted","block_id":"b1","block_data":{"block_value":{"id":"b1","version":3,"alive":false}}},
+{"type":"block-changed","block_id":"r1","collection_row_id":"r1","block_data":{"block_value":{"id":"r1"}}},
+{"type":"comment-created","comment_id":"c1"},
+{"type":"space-permission-changed"},
+{"type":"something-new"}
+]`
+	var edits []Edit
+	assert.NoError(t, json.Unmarshal([]byte(s), &edits))
+	exp := []EditKind{
+		EditBlockCreated,
+		EditBlockChanged,
+		EditBlockDeleted,
+		EditCollectionRowChanged,
+		EditCommentAdded,
+		EditPermissionChanged,
+		EditUnknown,
+	}
+	for i, e := range edits {
+		assert.Equal(t, exp[i], e.Kind(), e.Type)
+	}
+	assert.Equal(t, "block created", edits[0].Kind().String())
+	assert.Equal(t, time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC), edits[0].Time())
+
+	assert.Nil(t, edits[0].Before())
+	assert.Equal(t, 1, int(edits[0].After().Version))
+	assert.Equal(t, 1, int(edits[1].Before().Version))
+	assert.Equal(t, 2, int(edits[1].After().Version))
+	assert.Equal(t, 3, int(edits[2].Before().Version))
+	assert.Nil(t, edits[2].After())
+	assert.Nil(t, edits[4].Before())
+	assert.Nil(t, edits[4].After())
+}
@@ -0,0 +1,304 @@
+package notionapi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aggregations used in rollups (ColumnSchema.Aggregation) and in
+// aggregations of collection views
+const (
+	AggregationShowOriginal     = "show_original"
+	AggregationCount            = "count"
+	AggregationCountValues      = "count_values"
+	AggregationUnique           = "unique"
+	AggregationEmpty            = "empty"
+	AggregationNotEmpty         = "not_empty"
+	AggregationPercentEmpty     = "percent_empty"
+	AggregationPercentNotEmpty  = "percent_not_empty"
+	AggregationSum              = "sum"
+	AggregationAverage          = "average"
+	AggregationMedian           = "median"
+	AggregationMin              = "min"
+	AggregationMax              = "max"
+	AggregationRange            = "range"
+	AggregationEarliestDate     = "earliest_date"
+	AggregationLatestDate       = "latest_date"
+	AggregationDateRange        = "date_range"
+	AggregationChecked          = "checked"
+	AggregationUnchecked        = "unchecked"
+	AggregationPercentChecked   = "percent_checked"
+	AggregationPercentUnchecked = "percent_unchecked"
+)
+
+// hasServerValue returns true if v is a formula or rollup cell whose
+// value was computed by Notion and is only available as text
+func hasServerValue(v *CellValue) bool {
+	if v.Formula != nil || v.IsEmpty() {
+		return false
+	}
+	return v.Type == ColumnTypeFormula || v.Type == ColumnTypeRollup
+}
+
+// layouts of dates in formula and rollup cells computed by Notion
+var serverDateLayouts = []string{
+	"2006-01-02",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"Jan 2, 2006 3:04 PM",
+	"January 2, 2006 3:04 PM",
+}
+
+// cellNumber returns a number value of a cell, false if it doesn't have one
+func cellNumber(v *CellValue) (float64, bool) {
+	if v.Formula != nil {
+		return v.Formula.Number, v.Formula.Type == FormulaNumber
+	}
+	if hasServerValue(v) {
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.Text), 64)
+		return n, err == nil
+	}
+	if v.Type != ColumnTypeNumber || v.IsEmpty() {
+		return 0, false
+	}
+	return v.Number, true
+}
+
+// cellTime returns a date value of a cell, false if it doesn't have one
+func cellTime(v *CellValue) (time.Time, bool) {
+	if v.Formula != nil {
+		return v.Formula.Date, v.Formula.Type == FormulaDate && !v.Formula.Date.IsZero()
+	}
+	if hasServerValue(v) {
+		for _, ts := range v.TextSpans {
+			for _, attr := range ts.Attrs {
+				if AttrGetType(attr) == AttrDate {
+					t := parseDateTime(AttrGetDate(attr))
+					return t, !t.IsZero()
+				}
+			}
+		}
+		s := strings.TrimSpace(v.Text)
+		for _, layout := range serverDateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	return v.Time, !v.Time.IsZero()
+}
+
+func cellChecked(v *CellValue) bool {
+	if v.Formula != nil {
+		return v.Formula.Type == FormulaCheckbox && v.Formula.Checkbox
+	}
+	return v.Checkbox
+}
+
+// cellValues returns distinct values in a cell e.g. each option of
+// a multi select
+func cellValues(v *CellValue) []string {
+	switch v.Type {
+	case ColumnTypeMultiSelect, ColumnTypeSelect:
+		var res []string
+		for _, o := range v.Options {
+			res = append(res, o.Value)
+		}
+		return res
+	case ColumnTypePerson, ColumnTypeCreatedBy, ColumnTypeLastEditedBy:
+		return v.UserIDs
+	case ColumnTypeRelation:
+		return v.BlockIDs
+	case ColumnTypeFile:
+		return v.FileURLs
+	}
+	if v.IsEmpty() && v.Formula == nil {
+		return nil
+	}
+	return []string{v.Text}
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// aggregate calculates aggregation over values of cells
+func aggregate(aggregation string, values []*CellValue) (*FormulaValue, error) {
+	nEmpty := 0
+	nChecked := 0
+	var nums []float64
+	var times []time.Time
+	var texts []string
+	for _, v := range values {
+		vals := cellValues(v)
+		if len(vals) == 0 {
+			nEmpty++
+		}
+		texts = append(texts, vals...)
+		if n, ok := cellNumber(v); ok {
+			nums = append(nums, n)
+		}
+		if t, ok := cellTime(v); ok {
+			times = append(times, t)
+		}
+		if cellChecked(v) {
+			nChecked++
+		}
+	}
+	n := len(values)
+
+	switch aggregation {
+	case "", AggregationShowOriginal:
+		return textValue(strings.Join(texts, ", ")), nil
+	case AggregationCount:
+		return numberValue(float64(n)), nil
+	case AggregationCountValues:
+		return numberValue(float64(len(texts))), nil
+	case AggregationUnique:
+		seen := map[string]bool{}
+		for _, s := range texts {
+			seen[s] = true
+		}
+		return numberValue(float64(len(seen))), nil
+	case AggregationEmpty:
+		return numberValue(float64(nEmpty)), nil
+	case AggregationNotEmpty:
+		return numberValue(float64(n - nEmpty)), nil
+	case AggregationPercentEmpty:
+		return numberValue(percent(nEmpty, n)), nil
+	case AggregationPercentNotEmpty:
+		return numberValue(percent(n-nEmpty, n)), nil
+	case AggregationChecked:
+		return numberValue(float64(nChecked)), nil
+	case AggregationUnchecked:
+		return numberValue(float64(n - nChecked)), nil
+	case AggregationPercentChecked:
+		return numberValue(percent(nChecked, n)), nil
+	case AggregationPercentUnchecked:
+		return numberValue(percent(n-nChecked, n)), nil
+	case AggregationSum, AggregationAverage, AggregationMedian, AggregationMin, AggregationMax, AggregationRange:
+		if len(nums) == 0 {
+			return numberValue(0), nil
+		}
+		sort.Float64s(nums)
+		sum := 0.0
+		for _, n := range nums {
+			sum += n
+		}
+		switch aggregation {
+		case AggregationSum:
+			return numberValue(sum), nil
+		case AggregationAverage:
+			return numberValue(sum / float64(len(nums))), nil
+		case AggregationMedian:
+			mid := len(nums) / 2
+			if len(nums)%2 == 1 {
+				return numberValue(nums[mid]), nil
+			}
+			return numberValue((nums[mid-1] + nums[mid]) / 2), nil
+		case AggregationMin:
+			return numberValue(nums[0]), nil
+		case AggregationMax:
+			return numberValue(nums[len(nums)-1]), nil
+		}
+		return numberValue(nums[len(nums)-1] - nums[0]), nil
+	case AggregationEarliestDate, AggregationLatestDate, AggregationDateRange:
+		if len(times) == 0 {
+			if aggregation == AggregationDateRange {
+				return numberValue(0), nil
+			}
+			return dateValue(time.Time{}), nil
+		}
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].Before(times[j])
+		})
+		switch aggregation {
+		case AggregationEarliestDate:
+			return dateValue(times[0]), nil
+		case AggregationLatestDate:
+			return dateValue(times[len(times)-1]), nil
+		}
+		// in days
+		d := times[len(times)-1].Sub(times[0])
+		return numberValue(math.Floor(d.Hours() / 24)), nil
+	}
+	return nil, fmt.Errorf("unsupported aggregation '%s'", aggregation)
+}
+
+// TableAggregation is an aggregation shown under a column of a table
+type TableAggregation struct {
+	Property   string
+	Aggregator string
+	Value      *FormulaValue
+	// true if Notion didn't return the value and we calculated it from
+	// the rows
+	Local bool
+}
+
+// formulaValue converts aggregation result returned by Notion.
+// Returns nil if we don't understand the value
+func (r *AggregationResult) formulaValue() *FormulaValue {
+	switch v := r.Value.(type) {
+	case float64:
+		return numberValue(v)
+	case string:
+		return textValue(v)
+	case map[string]interface{}:
+		// dates are returned as {"type": "date", "start_date": "2021-05-03"}
+		if s, ok := v["start_date"].(string); ok {
+			d := &Date{StartDate: s}
+			d.StartTime, _ = v["start_time"].(string)
+			return dateValue(parseDateTime(d))
+		}
+	}
+	return nil
+}
+
+// buildAggregations returns aggregations of a table view. Uses values
+// calculated by Notion if it returned them and calculates them from rows
+// if it didn't
+func buildAggregations(tv *TableView, res *QueryCollectionResponse) map[string]*TableAggregation {
+	cv := tv.CollectionView
+	if cv.Query == nil {
+		return nil
+	}
+	var results map[string]*AggregationResult
+	if res.Result.ReducerResults != nil {
+		results = res.Result.ReducerResults.AggregationResults
+	}
+	var m map[string]*TableAggregation
+	for _, a := range cv.Query.aggregations() {
+		ta := &TableAggregation{
+			Property:   a.Property,
+			Aggregator: a.Aggregator,
+		}
+		if r := results[aggregationReducerName(a)]; r != nil {
+			ta.Value = r.formulaValue()
+		}
+		if ta.Value == nil {
+			var values []*CellValue
+			for _, row := range tv.Rows {
+				values = append(values, row.Value(a.Property))
+			}
+			v, err := aggregate(a.Aggregator, values)
+			if err != nil {
+				continue
+			}
+			ta.Value = v
+			ta.Local = true
+		}
+		if m == nil {
+			m = map[string]*TableAggregation{}
+		}
+		m[a.Property] = ta
+	}
+	return m
+}
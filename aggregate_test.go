@@ -0,0 +1,233 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2021, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	values := []*CellValue{
+		{Type: ColumnTypeNumber, Text: "1", Number: 1, TextSpans: []*TextSpan{{Text: "1"}}},
+		{Type: ColumnTypeNumber, Text: "4", Number: 4, TextSpans: []*TextSpan{{Text: "4"}}},
+		{Type: ColumnTypeNumber, Text: "4", Number: 4, TextSpans: []*TextSpan{{Text: "4"}}},
+		{Type: ColumnTypeNumber},
+	}
+	tests := []struct {
+		aggregation string
+		exp         string
+	}{
+		{AggregationCount, "4"},
+		{AggregationCountValues, "3"},
+		{AggregationUnique, "2"},
+		{AggregationEmpty, "1"},
+		{AggregationNotEmpty, "3"},
+		{AggregationPercentEmpty, "25"},
+		{AggregationSum, "9"},
+		{AggregationAverage, "3"},
+		{AggregationMedian, "4"},
+		{AggregationMin, "1"},
+		{AggregationMax, "4"},
+		{AggregationRange, "3"},
+		{AggregationShowOriginal, "1, 4, 4"},
+	}
+	for _, test := range tests {
+		v, err := aggregate(test.aggregation, values)
+		assert.NoError(t, err)
+		assert.Equal(t, test.exp, v.String(), test.aggregation)
+	}
+
+	checks := []*CellValue{
+		{Type: ColumnTypeCheckbox, Checkbox: true},
+		{Type: ColumnTypeCheckbox},
+		{Type: ColumnTypeCheckbox},
+		{Type: ColumnTypeCheckbox, Checkbox: true},
+	}
+	v, err := aggregate(AggregationPercentChecked, checks)
+	assert.NoError(t, err)
+	assert.Equal(t, 50.0, v.Number)
+
+	dates := []*CellValue{
+		{Type: ColumnTypeDate, Time: day(7)},
+		{Type: ColumnTypeDate, Time: day(3)},
+		{Type: ColumnTypeDate},
+	}
+	v, err = aggregate(AggregationEarliestDate, dates)
+	assert.NoError(t, err)
+	assert.Equal(t, day(3), v.Date)
+	v, err = aggregate(AggregationLatestDate, dates)
+	assert.NoError(t, err)
+	assert.Equal(t, day(7), v.Date)
+	v, err = aggregate(AggregationDateRange, dates)
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, v.Number)
+
+	multi := []*CellValue{
+		{Type: ColumnTypeMultiSelect, Options: []*CollectionColumnOption{{Value: "a"}, {Value: "b"}}},
+		{Type: ColumnTypeMultiSelect, Options: []*CollectionColumnOption{{Value: "b"}}},
+	}
+	v, err = aggregate(AggregationUnique, multi)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, v.Number)
+
+	_, err = aggregate("foo", values)
+	assert.Error(t, err)
+}
+
+func TestTableViewAggregations(t *testing.T) {
+	cvJSON := `{
+	"id": "cv",
+	"type": "table",
+	"format": {"table_properties": [{"property": "title", "visible": true}, {"property": "num", "visible": true}]},
+	"query2": {
+		"aggregations": [{"property": "title", "aggregator": "count"}, {"property": "num", "aggregator": "sum"}],
+		"aggregate": [{"property": "num", "aggregation_type": "max"}, {"property": "due", "aggregation_type": "latest_date"}]
+	}
+}`
+	var cv CollectionView
+	assert.NoError(t, json.Unmarshal([]byte(cvJSON), &cv))
+	lr := MakeLoaderReducer(cv.Query)
+	assert.Equal(t, 4, len(lr.Reducers))
+	r := lr.Reducers["aggregation:num:sum"].(*ReducerAggregation)
+	assert.Equal(t, AggregationSum, r.Aggregation.Aggregator)
+
+	// Notion returned only the count
+	rspJSON := `{
+	"recordMap": {"block": {
+		"r1": {"value": {"id": "r1", "properties": {"title": [["one"]], "num": [["2"]], "due": [["‣", [["d", {"type": "date", "start_date": "2021-05-03"}]]]]}}},
+		"r2": {"value": {"id": "r2", "properties": {"title": [["two"]], "num": [["3.5"]]}}}
+	}},
+	"result": {"type": "reducer", "reducerResults": {
+		"collection_group_results": {"type": "results", "blockIds": ["r1", "r2"], "total": 2},
+		"aggregation:title:count": {"type": "aggregation", "aggregationResult": {"type": "number", "value": 12}}
+	}}
+}`
+	var rsp QueryCollectionResponse
+	assert.NoError(t, json.Unmarshal([]byte(rspJSON), &rsp))
+	assert.NoError(t, ParseRecordMap(rsp.RecordMap))
+	tv := &TableView{
+		Page:           &Page{ID: "page"},
+		CollectionView: &cv,
+		Collection: &Collection{
+			Schema: map[string]*ColumnSchema{
+				"title": {Name: "Name", Type: ColumnTypeTitle},
+				"num":   {Name: "Num", Type: ColumnTypeNumber},
+				"due":   {Name: "Due", Type: ColumnTypeDate},
+			},
+		},
+	}
+	c := &Client{}
+	assert.NoError(t, c.buildTableView(tv, &rsp))
+	assert.Equal(t, 3, len(tv.Aggregations))
+
+	a := tv.Aggregations["title"]
+	assert.False(t, a.Local)
+	assert.Equal(t, 12.0, a.Value.Number)
+	a = tv.Aggregations["num"]
+	assert.True(t, a.Local)
+	assert.Equal(t, AggregationSum, a.Aggregator)
+	assert.Equal(t, 5.5, a.Value.Number)
+	a = tv.Aggregations["due"]
+	assert.True(t, a.Local)
+	assert.Equal(t, time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC), a.Value.Date)
+}
+
+func TestBuildAggregations(t *testing.T) {
+	cv := &CollectionView{
+		Query: &Query{
+			Aggregations: []QueryAggregation{
+				{Property: "title", Aggregator: AggregationCount},
+				{Property: "num", Aggregator: AggregationSum},
+			},
+		},
+	}
+	tv := &TableView{
+		CollectionView: cv,
+		Collection: &Collection{
+			Schema: map[string]*ColumnSchema{
+				"title": {Name: "Name", Type: ColumnTypeTitle},
+				"num":   {Name: "Num", Type: ColumnTypeNumber},
+			},
+		},
+	}
+	for _, num := range []string{"2", "3"} {
+		row := &TableRow{
+			TableView: tv,
+			Page: &Block{Properties: map[string]interface{}{
+				"num": []interface{}{[]interface{}{num}},
+			}},
+		}
+		tv.Rows = append(tv.Rows, row)
+	}
+
+	// Notion returned the count but not the sum
+	rsp := &QueryCollectionResponse{}
+	rsp.Result.ReducerResults = &ReducerResults{
+		AggregationResults: map[string]*AggregationResult{
+			"aggregation:title:count": {Type: "number", Value: 7.0},
+		},
+	}
+	m := buildAggregations(tv, rsp)
+	assert.Equal(t, 2, len(m))
+	assert.False(t, m["title"].Local)
+	assert.Equal(t, 7.0, m["title"].Value.Number)
+	assert.True(t, m["num"].Local)
+	assert.Equal(t, 5.0, m["num"].Value.Number)
+
+	// no reducer results at all
+	m = buildAggregations(tv, &QueryCollectionResponse{})
+	assert.True(t, m["title"].Local)
+	assert.Equal(t, 2.0, m["title"].Value.Number)
+}
+
+func TestAggregateServerValues(t *testing.T) {
+	cv := &CollectionView{
+		Query: &Query{
+			Aggregations: []QueryAggregation{
+				{Property: "f", Aggregator: AggregationSum},
+				{Property: "r", Aggregator: AggregationLatestDate},
+			},
+		},
+	}
+	tv := &TableView{
+		CollectionView: cv,
+		Collection: &Collection{
+			Schema: map[string]*ColumnSchema{
+				"title": {Name: "Name", Type: ColumnTypeTitle},
+				// the formula can't be evaluated locally, Notion gives us the values
+				"f": {Name: "F", Type: ColumnTypeFormula},
+				"r": {Name: "R", Type: ColumnTypeRollup},
+			},
+		},
+	}
+	props := []map[string]interface{}{
+		{
+			"f": []interface{}{[]interface{}{"1.5"}},
+			"r": []interface{}{[]interface{}{"‣", []interface{}{[]interface{}{"d", map[string]interface{}{"type": "date", "start_date": "2021-05-03"}}}}},
+		},
+		{
+			"f": []interface{}{[]interface{}{"2"}},
+			"r": []interface{}{[]interface{}{"May 7, 2021"}},
+		},
+		{},
+	}
+	for _, p := range props {
+		tv.Rows = append(tv.Rows, &TableRow{TableView: tv, Page: &Block{Properties: p}})
+	}
+	m := buildAggregations(tv, &QueryCollectionResponse{})
+	assert.Equal(t, 3.5, m["f"].Value.Number)
+	assert.Equal(t, time.Date(2021, 5, 7, 0, 0, 0, 0, time.UTC), m["r"].Value.Date)
+
+	var values []*CellValue
+	for _, row := range tv.Rows {
+		values = append(values, row.Value("r"))
+	}
+	v, err := aggregate(AggregationEarliestDate, values)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC), v.Date)
+}
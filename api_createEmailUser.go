@@ -1,11 +1,17 @@
 package notionapi
 
 import (
+	"context"
 	"errors"
 )
 
 // CreateEmailUser invites a new user through his email address
 func (c *Client) CreateEmailUser(email string) (*NotionUser, error) {
+	return c.CreateEmailUserCtx(context.Background(), email)
+}
+
+// CreateEmailUserCtx is like CreateEmailUser but takes a context
+func (c *Client) CreateEmailUserCtx(ctx context.Context, email string) (*NotionUser, error) {
 	req := struct {
 		Email string `json:"email"`
 	}{
@@ -18,7 +24,7 @@ func (c *Client) CreateEmailUser(email string) (*NotionUser, error) {
 	}
 
 	apiURL := "/api/v3/createEmailUser"
-	err := c.doNotionAPI(apiURL, req, &rsp, nil)
+	err := c.doNotionAPI(ctx, apiURL, req, &rsp, nil)
 
 	recordMap := rsp.RecordMap
 	ParseRecordMap(recordMap)
@@ -1,5 +1,7 @@
 package notionapi
 
+import "context"
+
 // /api/v3/getActivityLog request
 type getActivityLogRequest struct {
 	SpaceID         string `json:"spaceId"`
@@ -19,6 +21,11 @@ type GetActivityLogResponse struct {
 // GetActivityLog executes a raw API call /api/v3/getActivityLog.
 // If startingAfterId is "", starts at the most recent log entry.
 func (c *Client) GetActivityLog(spaceID string, startingAfterID string, limit int) (*GetActivityLogResponse, error) {
+	return c.GetActivityLogCtx(context.Background(), spaceID, startingAfterID, limit)
+}
+
+// GetActivityLogCtx is like GetActivityLog but takes a context
+func (c *Client) GetActivityLogCtx(ctx context.Context, spaceID string, startingAfterID string, limit int) (*GetActivityLogResponse, error) {
 	req := &getActivityLogRequest{
 		SpaceID:         spaceID,
 		StartingAfterID: startingAfterID,
@@ -27,9 +34,12 @@ func (c *Client) GetActivityLog(spaceID string, startingAfterID string, limit in
 	var rsp GetActivityLogResponse
 	var err error
 	apiURL := "/api/v3/getActivityLog"
-	if err = c.doNotionAPI(apiURL, req, &rsp, &rsp.RawJSON); err != nil {
+	if err = c.doNotionAPI(ctx, apiURL, req, &rsp, &rsp.RawJSON); err != nil {
 		return nil, err
 	}
+	if rsp.RecordMap == nil {
+		rsp.RecordMap = &RecordMap{}
+	}
 	if err = ParseRecordMap(rsp.RecordMap); err != nil {
 		return nil, err
 	}
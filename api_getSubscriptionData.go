@@ -1,5 +1,7 @@
 package notionapi
 
+import "context"
+
 type SubscriptionDataSpaceUsers struct {
 	UserID       string        `json:"userId"`
 	Role         string        `json:"role"`
@@ -64,6 +66,11 @@ type SubscriptionData struct {
 
 // GetSubscriptionData executes a raw API call /api/v3/getSubscriptionData
 func (c *Client) GetSubscriptionData(spaceID string) (*SubscriptionData, error) {
+	return c.GetSubscriptionDataCtx(context.Background(), spaceID)
+}
+
+// GetSubscriptionDataCtx is like GetSubscriptionData but takes a context
+func (c *Client) GetSubscriptionDataCtx(ctx context.Context, spaceID string) (*SubscriptionData, error) {
 	req := &struct {
 		SpaceID string `json:"spaceId"`
 	}{
@@ -73,7 +80,7 @@ func (c *Client) GetSubscriptionData(spaceID string) (*SubscriptionData, error)
 	var rsp SubscriptionData
 	var err error
 	apiURL := "/api/v3/getSubscriptionData"
-	err = c.doNotionAPI(apiURL, req, &rsp, &rsp.RawJSON)
+	err = c.doNotionAPI(ctx, apiURL, req, &rsp, &rsp.RawJSON)
 	if err != nil {
 		return nil, err
 	}
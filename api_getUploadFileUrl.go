@@ -1,7 +1,9 @@
 package notionapi
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
@@ -35,7 +37,7 @@ func (r *GetUploadFileUrlResponse) Parse() {
 }
 
 // getUploadFileURL executes a raw API call: POST /api/v3/getUploadFileUrl
-func (c *Client) getUploadFileURL(name, contentType string) (*GetUploadFileUrlResponse, error) {
+func (c *Client) getUploadFileURL(ctx context.Context, name, contentType string) (*GetUploadFileUrlResponse, error) {
 
 	req := &getUploadFileUrlRequest{
 		Bucket:      "secure",
@@ -46,7 +48,7 @@ func (c *Client) getUploadFileURL(name, contentType string) (*GetUploadFileUrlRe
 	var rsp GetUploadFileUrlResponse
 	var err error
 	const apiURL = "/api/v3/getUploadFileUrl"
-	err = c.doNotionAPI(apiURL, req, &rsp, &rsp.RawJSON)
+	err = c.doNotionAPI(ctx, apiURL, req, &rsp, &rsp.RawJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -87,8 +89,8 @@ func GetFileContentType(file *os.File) (contentType string, err error) {
 	return
 }
 
-// TODO: Support adding new records to collections and other non-block parent tables
-// SetNewRecordOp creates an operation to create a new record
+// SetNewRecordOp creates an operation to create a new record in a block.
+// Use Collection.NewRowOps to create a row of a collection
 func (c *Client) SetNewRecordOp(userID string, parent *Block, recordType string) (newBlock *Block, operation *Operation) {
 	newID := uuid.New().String()
 	now := Now()
@@ -120,8 +122,13 @@ func (c *Client) SetNewRecordOp(userID string, parent *Block, recordType string)
 
 // UploadFile Uploads a file to notion's asset hosting(aws s3)
 func (c *Client) UploadFile(file *os.File) (fileID, fileURL string, err error) {
+	return c.UploadFileCtx(context.Background(), file)
+}
+
+// UploadFileCtx is like UploadFile but takes a context
+func (c *Client) UploadFileCtx(ctx context.Context, file *os.File) (fileID, fileURL string, err error) {
 	contentType, err := GetFileContentType(file)
-	c.logf("contentType: %s", contentType)
+	c.logDebug("uploading file", "name", file.Name(), "contentType", contentType)
 
 	if err != nil {
 		err = fmt.Errorf("couldn't figure out the content-type of the file: %s", err)
@@ -134,10 +141,14 @@ func (c *Client) UploadFile(file *os.File) (fileID, fileURL string, err error) {
 		return
 	}
 
-	fileSize := fi.Size()
+	return c.uploadData(ctx, file.Name(), contentType, file, fi.Size())
+}
 
+// uploadData uploads size bytes from r as a file with a given name and
+// content type
+func (c *Client) uploadData(ctx context.Context, name string, contentType string, r io.Reader, size int64) (fileID, fileURL string, err error) {
 	// 1. getUploadFileURL
-	uploadFileURLResp, err := c.getUploadFileURL(file.Name(), contentType)
+	uploadFileURLResp, err := c.getUploadFileURL(ctx, name, contentType)
 	if err != nil {
 		err = fmt.Errorf("get upload file URL error: %s", err)
 		return
@@ -146,21 +157,27 @@ func (c *Client) UploadFile(file *os.File) (fileID, fileURL string, err error) {
 	// 2. Upload file to amazon - PUT
 	httpClient := c.getHTTPClient()
 
-	req, err := http.NewRequest(http.MethodPut, uploadFileURLResp.SignedPutURL, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadFileURLResp.SignedPutURL, r)
 	if err != nil {
 		return
 	}
-	req.ContentLength = fileSize
+	req.ContentLength = size
 	req.TransferEncoding = []string{"identity"} // disable chunked (unsupported by aws)
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", userAgent)
 
+	ev, timeStart := c.startRequest(ctx, endpointUpload, http.MethodPut, req.URL.String(), size, 0)
+	status := 0
+	defer func() {
+		c.finishRequest(ev, timeStart, status, 0, err)
+	}()
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return
 	}
 
 	defer resp.Body.Close()
+	status = resp.StatusCode
 	if resp.StatusCode != 200 {
 		var contents []byte
 		contents, err = ioutil.ReadAll(resp.Body)
@@ -1,5 +1,7 @@
 package notionapi
 
+import "context"
+
 // /api/v3/loadCachedPageChunk request
 type loadCachedPageChunkRequest struct {
 	Page            loadCachedPageChunkRequestPage `json:"page"`
@@ -48,6 +50,11 @@ type RecordMap struct {
 
 // LoadPageChunk executes a raw API call /api/v3/loadCachedPageChunk
 func (c *Client) LoadCachedPageChunk(pageID string, chunkNo int, cur *cursor) (*LoadCachedPageChunkResponse, error) {
+	return c.LoadCachedPageChunkCtx(context.Background(), pageID, chunkNo, cur)
+}
+
+// LoadCachedPageChunkCtx is like LoadCachedPageChunk but takes a context
+func (c *Client) LoadCachedPageChunkCtx(ctx context.Context, pageID string, chunkNo int, cur *cursor) (*LoadCachedPageChunkResponse, error) {
 	// emulating notion's website api usage: 30 items on first request,
 	// 50 on subsequent requests
 	limit := 30
@@ -68,7 +75,7 @@ func (c *Client) LoadCachedPageChunk(pageID string, chunkNo int, cur *cursor) (*
 	var rsp LoadCachedPageChunkResponse
 	var err error
 	apiURL := "/api/v3/loadCachedPageChunk"
-	if err = c.doNotionAPI(apiURL, req, &rsp, &rsp.RawJSON); err != nil {
+	if err = c.doNotionAPI(ctx, apiURL, req, &rsp, &rsp.RawJSON); err != nil {
 		return nil, err
 	}
 	if err = ParseRecordMap(rsp.RecordMap); err != nil {
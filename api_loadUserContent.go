@@ -1,6 +1,9 @@
 package notionapi
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
 
 type LoadUserResponse struct {
 	ID    string `json:"id"`
@@ -17,6 +20,11 @@ type LoadUserResponse struct {
 }
 
 func (c *Client) LoadUserContent() (*LoadUserResponse, error) {
+	return c.LoadUserContentCtx(context.Background())
+}
+
+// LoadUserContentCtx is like LoadUserContent but takes a context
+func (c *Client) LoadUserContentCtx(ctx context.Context) (*LoadUserResponse, error) {
 	req := struct{}{}
 
 	var rsp struct {
@@ -25,7 +33,7 @@ func (c *Client) LoadUserContent() (*LoadUserResponse, error) {
 	apiURL := "/api/v3/loadUserContent"
 	result := LoadUserResponse{}
 
-	err := c.doNotionAPI(apiURL, req, &rsp, &result.RawJSON)
+	err := c.doNotionAPI(ctx, apiURL, req, &rsp, &result.RawJSON)
 	if err != nil {
 		return nil, err
 	}
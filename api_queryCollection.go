@@ -1,13 +1,38 @@
 package notionapi
 
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
 const (
 	// key in LoaderReducer.Reducers map
 	ReducerCollectionGroupResultsName = "collection_group_results"
+
+	// DefaultCollectionPageSize is how many rows we ask for in a single
+	// queryCollection request if Client.CollectionPageSize is not set
+	DefaultCollectionPageSize = 50
 )
 
+// ReducerAggregation is a reducer that calculates an aggregation of
+// a column
+type ReducerAggregation struct {
+	Type        string           `json:"type"` // "aggregation"
+	Aggregation QueryAggregation `json:"aggregation"`
+}
+
+// AggregationResult is a result of ReducerAggregation
+type AggregationResult struct {
+	Type  string      `json:"type"` // e.g. "number"
+	Value interface{} `json:"value"`
+}
+
 type ReducerCollectionGroupResults struct {
 	Type  string `json:"type"`
 	Limit int    `json:"limit"`
+	// for reducers of groups in a board view, selects rows in a group
+	Filter map[string]interface{} `json:"filter,omitempty"`
 }
 
 // /api/v3/queryCollection request
@@ -31,6 +56,52 @@ type CollectionGroupResults struct {
 type ReducerResults struct {
 	// TODO: probably more types
 	CollectionGroupResults *CollectionGroupResults `json:"collection_group_results"`
+	// for board views, rows of each group keyed by reducer name
+	// e.g. "results:select:Done"
+	GroupResults map[string]*CollectionGroupResults `json:"-"`
+	// results of aggregation reducers keyed by reducer name
+	// e.g. "aggregation:title:count"
+	AggregationResults map[string]*AggregationResult `json:"-"`
+}
+
+// UnmarshalJSON decodes collection_group_results and results of
+// per-group and aggregation reducers
+func (r *ReducerResults) UnmarshalJSON(d []byte) error {
+	var m map[string]json.RawMessage
+	if err := jsonit.Unmarshal(d, &m); err != nil {
+		return err
+	}
+	for name, v := range m {
+		if strings.HasPrefix(name, "aggregation:") {
+			var ar struct {
+				AggregationResult *AggregationResult `json:"aggregationResult"`
+			}
+			if err := jsonit.Unmarshal(v, &ar); err != nil {
+				return err
+			}
+			if r.AggregationResults == nil {
+				r.AggregationResults = map[string]*AggregationResult{}
+			}
+			r.AggregationResults[name] = ar.AggregationResult
+			continue
+		}
+		if name != ReducerCollectionGroupResultsName && !strings.HasPrefix(name, "results:") {
+			continue
+		}
+		var gr CollectionGroupResults
+		if err := jsonit.Unmarshal(v, &gr); err != nil {
+			return err
+		}
+		if name == ReducerCollectionGroupResultsName {
+			r.CollectionGroupResults = &gr
+			continue
+		}
+		if r.GroupResults == nil {
+			r.GroupResults = map[string]*CollectionGroupResults{}
+		}
+		r.GroupResults[name] = &gr
+	}
+	return nil
 }
 
 // QueryCollectionResponse is json response for /api/v3/queryCollection
@@ -61,31 +132,237 @@ func MakeLoaderReducer(query *Query) *LoaderReducer {
 	if query != nil {
 		res.Sort = query.Sort
 		res.Filter = query.Filter
+		res.SearchQuery = query.SearchQuery
+		for _, a := range query.aggregations() {
+			res.Reducers[aggregationReducerName(a)] = &ReducerAggregation{
+				Type:        "aggregation",
+				Aggregation: a,
+			}
+		}
 	}
 	res.Reducers[ReducerCollectionGroupResultsName] = &ReducerCollectionGroupResults{
 		Type:  "results",
-		Limit: 50,
+		Limit: DefaultCollectionPageSize,
 	}
 	// set some default value, should over-ride with User.TimeZone
 	res.UserTimeZone = "America/Los_Angeles"
 	return res
 }
 
-// QueryCollection executes a raw API call /api/v3/queryCollection
-func (c *Client) QueryCollection(req QueryCollectionRequest, query *Query) (*QueryCollectionResponse, error) {
-	if req.Loader == nil {
-		req.Loader = MakeLoaderReducer(query)
+// MakeViewLoaderReducer is like MakeLoaderReducer for the query of a
+// collection view. For board views it also asks for rows of each group
+func MakeViewLoaderReducer(cv *CollectionView) *LoaderReducer {
+	res := MakeLoaderReducer(cv.Query)
+	if cv.Type != CollectionViewTypeBoard {
+		return res
+	}
+	for _, g := range cv.boardGroups() {
+		filter, err := groupFilter(g.Property, g.Value).ToMap(nil)
+		if err != nil {
+			continue
+		}
+		res.Reducers[groupReducerName(g.Value)] = &ReducerCollectionGroupResults{
+			Type:   "results",
+			Limit:  DefaultCollectionPageSize,
+			Filter: filter,
+		}
+	}
+	return res
+}
+
+// aggregationReducerName returns the name of a reducer for aggregation a
+// e.g. "aggregation:title:count"
+func aggregationReducerName(a QueryAggregation) string {
+	return "aggregation:" + a.Property + ":" + a.Aggregator
+}
+
+func (c *Client) getCollectionPageSize() int {
+	if c.CollectionPageSize > 0 {
+		return c.CollectionPageSize
+	}
+	return DefaultCollectionPageSize
+}
+
+// withResultsLimit returns a copy of loader with the limit of
+// collection_group_results and per-group reducers set to limit.
+// Returns nil if loader is not a *LoaderReducer we know how to change
+func withResultsLimit(loader interface{}, limit int) *LoaderReducer {
+	lr, ok := loader.(*LoaderReducer)
+	if !ok || lr == nil {
+		return nil
+	}
+	r, ok := lr.Reducers[ReducerCollectionGroupResultsName].(*ReducerCollectionGroupResults)
+	if !ok || r == nil {
+		return nil
+	}
+	res := *lr
+	res.Reducers = map[string]interface{}{}
+	for k, v := range lr.Reducers {
+		if r, ok := v.(*ReducerCollectionGroupResults); ok && r != nil {
+			v = &ReducerCollectionGroupResults{
+				Type:   r.Type,
+				Limit:  limit,
+				Filter: r.Filter,
+			}
+		}
+		res.Reducers[k] = v
+	}
+	return &res
+}
+
+// resultsProgress returns how many rows we got in all results reducers
+// and the biggest total. more is true if any reducer has more rows
+func (r *QueryCollectionResponse) resultsProgress() (n int, total int, more bool) {
+	rr := r.Result.ReducerResults
+	if rr == nil {
+		return 0, 0, false
+	}
+	all := []*CollectionGroupResults{rr.CollectionGroupResults}
+	for _, gr := range rr.GroupResults {
+		all = append(all, gr)
+	}
+	for _, gr := range all {
+		if gr == nil {
+			continue
+		}
+		n += len(gr.BlockIds)
+		if gr.Total > total {
+			total = gr.Total
+		}
+		if len(gr.BlockIds) < gr.Total {
+			more = true
+		}
+	}
+	return n, total, more
+}
+
+// GroupResults returns results of collection_group_results reducer,
+// nil if there are none
+func (r *QueryCollectionResponse) GroupResults() *CollectionGroupResults {
+	if r.Result.ReducerResults == nil {
+		return nil
 	}
+	return r.Result.ReducerResults.CollectionGroupResults
+}
+
+func mergeRecords(dst map[string]*Record, src map[string]*Record) map[string]*Record {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = map[string]*Record{}
+	}
+	for id, r := range src {
+		dst[id] = r
+	}
+	return dst
+}
+
+// mergeRecordMap adds records from src to dst. Records in src
+// over-write records in dst
+func mergeRecordMap(dst *RecordMap, src *RecordMap) {
+	if src == nil {
+		return
+	}
+	dst.Activities = mergeRecords(dst.Activities, src.Activities)
+	dst.Blocks = mergeRecords(dst.Blocks, src.Blocks)
+	dst.Spaces = mergeRecords(dst.Spaces, src.Spaces)
+	dst.NotionUsers = mergeRecords(dst.NotionUsers, src.NotionUsers)
+	dst.UsersRoot = mergeRecords(dst.UsersRoot, src.UsersRoot)
+	dst.UserSettings = mergeRecords(dst.UserSettings, src.UserSettings)
+	dst.Collections = mergeRecords(dst.Collections, src.Collections)
+	dst.CollectionViews = mergeRecords(dst.CollectionViews, src.CollectionViews)
+	dst.Comments = mergeRecords(dst.Comments, src.Comments)
+	dst.Discussions = mergeRecords(dst.Discussions, src.Discussions)
+}
+
+func (c *Client) queryCollectionOnce(ctx context.Context, req QueryCollectionRequest) (*QueryCollectionResponse, error) {
 	var rsp QueryCollectionResponse
-	var err error
 	apiURL := "/api/v3/queryCollection"
-	err = c.doNotionAPI(apiURL, req, &rsp, &rsp.RawJSON)
+	err := c.doNotionAPI(ctx, apiURL, req, &rsp, &rsp.RawJSON)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.RecordMap == nil {
+		rsp.RecordMap = &RecordMap{}
+	}
+	return &rsp, nil
+}
+
+// QueryCollection executes a raw API call /api/v3/queryCollection.
+// If req.Loader is not set, it fetches all rows and merges the results.
+// Each request asks for Client.CollectionPageSize more rows than the
+// previous one (see queryCollectionPaged)
+func (c *Client) QueryCollection(req QueryCollectionRequest, query *Query) (*QueryCollectionResponse, error) {
+	return c.QueryCollectionCtx(context.Background(), req, query)
+}
+
+// QueryCollectionCtx is like QueryCollection but takes a context
+func (c *Client) QueryCollectionCtx(ctx context.Context, req QueryCollectionRequest, query *Query) (*QueryCollectionResponse, error) {
+	var loader *LoaderReducer
+	if req.Loader == nil {
+		loader = MakeLoaderReducer(query)
+	}
+	return c.queryCollectionPaged(ctx, req, loader)
+}
+
+// QueryCollectionViewCtx is like QueryCollectionCtx but uses the query of
+// a collection view. For board views it also gets rows of each group
+// (see ReducerResults.GroupResults)
+func (c *Client) QueryCollectionViewCtx(ctx context.Context, req QueryCollectionRequest, cv *CollectionView) (*QueryCollectionResponse, error) {
+	var loader *LoaderReducer
+	if req.Loader == nil {
+		loader = MakeViewLoaderReducer(cv)
+	}
+	return c.queryCollectionPaged(ctx, req, loader)
+}
+
+// queryCollectionPaged fetches all rows if loader is given, growing the
+// limit by Client.CollectionPageSize with each request. Otherwise it uses
+// req.Loader as is
+func (c *Client) queryCollectionPaged(ctx context.Context, req QueryCollectionRequest, loader *LoaderReducer) (*QueryCollectionResponse, error) {
+	paginate := loader != nil
+	pageSize := c.getCollectionPageSize()
+	limit := pageSize
+	if paginate {
+		req.Loader = withResultsLimit(loader, limit)
+	}
+	rsp, err := c.queryCollectionOnce(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: fetch more if exceeded limit
+
+	// Notion has no offset for results so to get more rows we ask for
+	// them again, with a limit bigger by a page. Getting n rows takes
+	// n/pageSize requests and downloads O(n^2/pageSize) rows, which is
+	// still better than a single request for all rows of a big
+	// collection. We merge record maps in case a row was removed in the
+	// meantime
+	for paginate {
+		n, total, more := rsp.resultsProgress()
+		if !more {
+			break
+		}
+		c.vlogf("QueryCollection: got %d rows, total is %d\n", n, total)
+		limit += pageSize
+		if limit > total {
+			limit = total
+		}
+		req.Loader = withResultsLimit(req.Loader, limit)
+		next, err := c.queryCollectionOnce(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		mergeRecordMap(rsp.RecordMap, next.RecordMap)
+		rsp.Result = next.Result
+		rsp.RawJSON = next.RawJSON
+		// stop if the server didn't give us more rows
+		if nextN, _, _ := next.resultsProgress(); nextN <= n {
+			break
+		}
+	}
 	if err := ParseRecordMap(rsp.RecordMap); err != nil {
 		return nil, err
 	}
-	return &rsp, nil
+	return rsp, nil
 }
@@ -0,0 +1,372 @@
+package notionapi
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	// SearchSortRelevance sorts search results by relevance (the default)
+	SearchSortRelevance = "relevance"
+	// SearchSortLastEdited sorts search results by last edited time, newest first
+	SearchSortLastEdited = "lastEdited"
+	// SearchSortCreated sorts search results by created time, newest first
+	SearchSortCreated = "created"
+
+	// DefaultSearchLimit is how many results we ask for if
+	// SearchRequest.Limit is not set
+	DefaultSearchLimit = 20
+
+	// MaxIterateSearchResults is how many results SearchIterator returns
+	// at most
+	MaxIterateSearchResults = 1000
+
+	// Notion marks matched text in SearchHighlight with this tag
+	searchHighlightTag = "gzkNfoUU"
+)
+
+// SearchRequest describes a search in a workspace.
+// The search API has no filters for OnlyTitles and IncludeCollections
+// so we filter results on the client. A response can have fewer than
+// Limit results and its Total includes results we removed
+type SearchRequest struct {
+	// text to search for
+	Query string
+	// workspace to search in
+	SpaceID string
+	// if set, only return pages that are descendants of this page
+	AncestorID string
+	// if true, only return pages whose title has all words of the query
+	OnlyTitles bool
+	// if set, only return pages created by those users
+	CreatedBy []string
+	// if not zero, only return pages last edited in this time range
+	EditedAfter  time.Time
+	EditedBefore time.Time
+	// if true, also return pages that are rows of collections (databases)
+	IncludeCollections bool
+	// SearchSortRelevance (the default), SearchSortLastEdited or SearchSortCreated
+	Sort string
+	// maximum number of results, DefaultSearchLimit if 0
+	Limit int
+}
+
+type searchDate struct {
+	Type      string `json:"type"`
+	StartDate string `json:"start_date"`
+}
+
+type searchTimeRange struct {
+	Starting *searchDate `json:"starting,omitempty"`
+	Ending   *searchDate `json:"ending,omitempty"`
+}
+
+type searchFilters struct {
+	IsDeletedOnly             bool             `json:"isDeletedOnly"`
+	ExcludeTemplates          bool             `json:"excludeTemplates"`
+	IsNavigableOnly           bool             `json:"isNavigableOnly"`
+	NavigableBlockContentOnly bool             `json:"navigableBlockContentOnly"`
+	RequireEditPermissions    bool             `json:"requireEditPermissions"`
+	Ancestors                 []string         `json:"ancestors"`
+	CreatedBy                 []string         `json:"createdBy"`
+	EditedBy                  []string         `json:"editedBy"`
+	LastEditedTime            *searchTimeRange `json:"lastEditedTime,omitempty"`
+	CreatedTime               *searchTimeRange `json:"createdTime,omitempty"`
+}
+
+// /api/v3/search request
+type searchRequest struct {
+	Type    string        `json:"type"` // "BlocksInSpace"
+	Query   string        `json:"query"`
+	SpaceID string        `json:"spaceId"`
+	Limit   int           `json:"limit"`
+	Filters searchFilters `json:"filters"`
+	Sort    struct {
+		Field string `json:"field"`
+	} `json:"sort"`
+	Source string `json:"source"`
+}
+
+// SearchHighlight describes where the query matched
+type SearchHighlight struct {
+	// matched text, matches are wrapped in Notion's custom tag
+	Text string `json:"text"`
+	// path (titles of parent pages) of the result
+	PathText string `json:"pathText"`
+}
+
+// PlainText returns Text without tags marking matches
+func (h *SearchHighlight) PlainText() string {
+	s := strings.Replace(h.Text, "<"+searchHighlightTag+">", "", -1)
+	return strings.Replace(s, "</"+searchHighlightTag+">", "", -1)
+}
+
+// Matches returns parts of Text that matched the query
+func (h *SearchHighlight) Matches() []string {
+	var res []string
+	start := "<" + searchHighlightTag + ">"
+	end := "</" + searchHighlightTag + ">"
+	s := h.Text
+	for {
+		i := strings.Index(s, start)
+		if i < 0 {
+			return res
+		}
+		s = s[i+len(start):]
+		j := strings.Index(s, end)
+		if j < 0 {
+			return append(res, s)
+		}
+		res = append(res, s[:j])
+		s = s[j+len(end):]
+	}
+}
+
+// SearchResult is a single result of a search
+type SearchResult struct {
+	ID          string           `json:"id"`
+	IsNavigable bool             `json:"isNavigable"`
+	Score       float64          `json:"score"`
+	SpaceID     string           `json:"spaceId"`
+	Highlight   *SearchHighlight `json:"highlight"`
+
+	// resolved from SearchResponse.RecordMap, can be nil
+	Block *Block `json:"-"`
+}
+
+// SearchResponse is a response to /api/v3/search
+type SearchResponse struct {
+	// results, ordered by SearchRequest.Sort
+	Results []*SearchResult `json:"results"`
+	// total number of results
+	Total     int        `json:"total"`
+	RecordMap *RecordMap `json:"recordMap"`
+
+	RawJSON map[string]interface{} `json:"-"`
+}
+
+func makeSearchDate(t time.Time) *searchDate {
+	if t.IsZero() {
+		return nil
+	}
+	return &searchDate{
+		Type:      "date",
+		StartDate: t.Format("2006-01-02"),
+	}
+}
+
+func makeSearchRequest(req *SearchRequest) *searchRequest {
+	res := &searchRequest{
+		Type:    "BlocksInSpace",
+		Query:   req.Query,
+		SpaceID: req.SpaceID,
+		Limit:   req.Limit,
+		Source:  "quick_find_input_change",
+	}
+	if res.Limit <= 0 {
+		res.Limit = DefaultSearchLimit
+	}
+	res.Sort.Field = req.Sort
+	if res.Sort.Field == "" {
+		res.Sort.Field = SearchSortRelevance
+	}
+	f := &res.Filters
+	f.Ancestors = []string{}
+	if req.AncestorID != "" {
+		f.Ancestors = append(f.Ancestors, ToDashID(req.AncestorID))
+	}
+	f.CreatedBy = append([]string{}, req.CreatedBy...)
+	f.EditedBy = []string{}
+	if !req.EditedAfter.IsZero() || !req.EditedBefore.IsZero() {
+		f.LastEditedTime = &searchTimeRange{
+			Starting: makeSearchDate(req.EditedAfter),
+			Ending:   makeSearchDate(req.EditedBefore),
+		}
+	}
+	return res
+}
+
+// keepResult returns false if r doesn't match options of req that
+// the search API has no filters for
+func (req *SearchRequest) keepResult(r *SearchResult) bool {
+	if r.Block == nil {
+		return !req.OnlyTitles
+	}
+	if !req.IncludeCollections && r.Block.ParentTable == TableCollection {
+		return false
+	}
+	if req.OnlyTitles {
+		title := strings.ToLower(r.Block.Title)
+		for _, word := range strings.Fields(strings.ToLower(req.Query)) {
+			if !strings.Contains(title, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (req *SearchRequest) filterResults(results []*SearchResult) []*SearchResult {
+	var res []*SearchResult
+	for _, r := range results {
+		if req.keepResult(r) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Search executes a raw API call /api/v3/search
+func (c *Client) Search(req SearchRequest) (*SearchResponse, error) {
+	return c.SearchCtx(context.Background(), req)
+}
+
+// SearchCtx is like Search but takes a context
+func (c *Client) SearchCtx(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	rsp, err := c.search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rsp.Results = req.filterResults(rsp.Results)
+	return rsp, nil
+}
+
+// search returns results of a search before filtering them on the client
+func (c *Client) search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	var rsp SearchResponse
+	apiURL := "/api/v3/search"
+	err := c.doNotionAPI(ctx, apiURL, makeSearchRequest(&req), &rsp, &rsp.RawJSON)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.RecordMap == nil {
+		rsp.RecordMap = &RecordMap{}
+	}
+	if err = ParseRecordMap(rsp.RecordMap); err != nil {
+		return nil, err
+	}
+	for _, r := range rsp.Results {
+		rec := rsp.RecordMap.Blocks[r.ID]
+		if rec == nil || rec.Block == nil {
+			continue
+		}
+		if err = parseProperties(rec.Block); err != nil {
+			return nil, err
+		}
+		r.Block = rec.Block
+	}
+	return &rsp, nil
+}
+
+// SearchIterator returns results of a search one at a time, see
+// IterateSearch:
+//
+//	it := client.IterateSearch(ctx, req)
+//	for it.Next() {
+//		res := it.Result()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type SearchIterator struct {
+	client   *Client
+	ctx      context.Context
+	req      SearchRequest
+	pageSize int
+
+	results []*SearchResult
+	seen    map[string]bool
+	total   int
+	done    bool
+
+	result *SearchResult
+	err    error
+}
+
+// IterateSearch returns an iterator over results of a search.
+//
+// The search API has no cursor or offset. When the iterator needs more
+// results it runs the search again, asking for req.Limit more results
+// than before, and skips the ones it already returned. Iterating over n
+// results downloads O(n^2) results so the iteration stops after
+// MaxIterateSearchResults results. To get all results in one request
+// use Search with a bigger Limit
+func (c *Client) IterateSearch(ctx context.Context, req SearchRequest) *SearchIterator {
+	pageSize := req.Limit
+	if pageSize <= 0 {
+		pageSize = DefaultSearchLimit
+	}
+	req.Limit = 0
+	return &SearchIterator{
+		client:   c,
+		ctx:      ctx,
+		req:      req,
+		pageSize: pageSize,
+		seen:     map[string]bool{},
+		total:    -1,
+	}
+}
+
+func (it *SearchIterator) fetchMore() error {
+	it.req.Limit += it.pageSize
+	if it.req.Limit >= MaxIterateSearchResults {
+		it.req.Limit = MaxIterateSearchResults
+		it.done = true
+	}
+	rsp, err := it.client.search(it.ctx, it.req)
+	if err != nil {
+		return err
+	}
+	it.total = rsp.Total
+	it.results = it.results[:0]
+	nNew := 0
+	for _, r := range rsp.Results {
+		if it.seen[r.ID] {
+			continue
+		}
+		it.seen[r.ID] = true
+		nNew++
+		if it.req.keepResult(r) {
+			it.results = append(it.results, r)
+		}
+	}
+	n := len(rsp.Results)
+	if nNew == 0 || n >= rsp.Total || n < it.req.Limit {
+		it.done = true
+	}
+	return nil
+}
+
+// Next advances to the next result. Returns false when there are no
+// more results or there was an error
+func (it *SearchIterator) Next() bool {
+	for it.err == nil {
+		if len(it.results) > 0 {
+			it.result = it.results[0]
+			it.results = it.results[1:]
+			return true
+		}
+		if it.done {
+			break
+		}
+		it.err = it.fetchMore()
+	}
+	it.result = nil
+	return false
+}
+
+// Result returns the current result
+func (it *SearchIterator) Result() *SearchResult {
+	return it.result
+}
+
+// Total returns total number of results reported by the server,
+// -1 before the first request
+func (it *SearchIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *SearchIterator) Err() error {
+	return it.err
+}
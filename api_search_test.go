@@ -0,0 +1,181 @@
+package notionapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/notiontest"
+)
+
+func TestSearch(t *testing.T) {
+	s := notiontest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+
+	day := func(d int) int64 {
+		return time.Date(2021, 5, d, 12, 0, 0, 0, time.UTC).UnixNano() / 1e6
+	}
+	put := func(id, typ, parentID, title string, edited int64, content ...interface{}) {
+		require.NoError(t, s.Store.Put(notionapi.TableBlock, map[string]interface{}{
+			"id":               id,
+			"type":             typ,
+			"alive":            true,
+			"space_id":         "space",
+			"parent_id":        parentID,
+			"parent_table":     "block",
+			"created_by":       "user-1",
+			"created_time":     edited,
+			"last_edited_time": edited,
+			"content":          content,
+			"properties": map[string]interface{}{
+				"title": []interface{}{[]interface{}{title}},
+			},
+		}))
+	}
+	put("root", "page", "", "Notes", day(1), "p1", "p2")
+	put("p1", "page", "root", "Go tips", day(2), "t1")
+	put("t1", "text", "p1", "use go vet", day(2))
+	put("p2", "page", "root", "Rust", day(3), "t2")
+	put("t2", "text", "p2", "go or rust?", day(3))
+	for i := 0; i < 5; i++ {
+		put(fmt.Sprintf("g%d", i), "page", "p1", fmt.Sprintf("Go %d", i), day(4+i))
+	}
+
+	rsp, err := client.Search(notionapi.SearchRequest{Query: "go tips", SpaceID: "space"})
+	require.NoError(t, err)
+	require.Equal(t, 1, rsp.Total)
+	res := rsp.Results[0]
+	require.Equal(t, "p1", res.ID)
+	require.Equal(t, "Go tips", res.Block.Title)
+	require.Equal(t, []string{"Go tips"}, res.Highlight.Matches())
+	require.Equal(t, "Notes", res.Highlight.PathText)
+
+	// titles rank above content, content matches are found unless OnlyTitles
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "rust"})
+	require.NoError(t, err)
+	require.Equal(t, 1, rsp.Total)
+	require.Equal(t, "Rust", rsp.Results[0].Highlight.PlainText())
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "vet"})
+	require.NoError(t, err)
+	require.Equal(t, "p1", rsp.Results[0].ID)
+	require.Equal(t, "use go vet", rsp.Results[0].Highlight.PlainText())
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "vet", OnlyTitles: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, rsp.Total)
+	require.Equal(t, 0, len(rsp.Results))
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "go tips", OnlyTitles: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(rsp.Results))
+
+	// rows of collections are only returned with IncludeCollections
+	require.NoError(t, s.Store.Put(notionapi.TableBlock, map[string]interface{}{
+		"id": "row", "type": "page", "alive": true, "space_id": "space",
+		"parent_id": "coll", "parent_table": "collection",
+		"properties": map[string]interface{}{"title": []interface{}{[]interface{}{"Database row"}}},
+	}))
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "database"})
+	require.NoError(t, err)
+	require.Equal(t, 1, rsp.Total)
+	require.Equal(t, 0, len(rsp.Results))
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "database", IncludeCollections: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(rsp.Results))
+	require.Equal(t, "row", rsp.Results[0].ID)
+
+	rsp, err = client.Search(notionapi.SearchRequest{
+		Query:        "go",
+		AncestorID:   "p1",
+		EditedAfter:  time.Date(2021, 5, 5, 0, 0, 0, 0, time.UTC),
+		EditedBefore: time.Date(2021, 5, 6, 0, 0, 0, 0, time.UTC),
+		Sort:         notionapi.SearchSortLastEdited,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, rsp.Total)
+	require.Equal(t, "g2", rsp.Results[0].ID)
+	require.Equal(t, "g1", rsp.Results[1].ID)
+
+	rsp, err = client.Search(notionapi.SearchRequest{Query: "go", CreatedBy: []string{"user-2"}})
+	require.NoError(t, err)
+	require.Equal(t, 0, rsp.Total)
+
+	it := client.IterateSearch(context.Background(), notionapi.SearchRequest{
+		Query: "go",
+		Sort:  notionapi.SearchSortCreated,
+		Limit: 2,
+	})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Result().ID)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, 7, it.Total())
+	require.Equal(t, []string{"g4", "g3", "g2", "g1", "g0", "p2", "p1"}, ids)
+}
+
+func TestSearchRequestJSON(t *testing.T) {
+	var got map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		got = nil
+		require.NoError(t, json.Unmarshal(d, &got))
+		_, _ = w.Write([]byte(`{"results":[],"total":0}`))
+	}))
+	defer ts.Close()
+	client := &notionapi.Client{BaseURL: ts.URL}
+
+	defFilters := `"isDeletedOnly":false,"excludeTemplates":false,"isNavigableOnly":false,
+		"navigableBlockContentOnly":false,"requireEditPermissions":false,"editedBy":[]`
+	tests := []struct {
+		req notionapi.SearchRequest
+		exp string
+	}{
+		{
+			notionapi.SearchRequest{Query: "go", SpaceID: "space"},
+			`{"type":"BlocksInSpace","query":"go","spaceId":"space","limit":20,"source":"quick_find_input_change",
+				"sort":{"field":"relevance"},"filters":{` + defFilters + `,"ancestors":[],"createdBy":[]}}`,
+		},
+		// OnlyTitles and IncludeCollections are filtered on the client
+		{
+			notionapi.SearchRequest{Query: "go", OnlyTitles: true, IncludeCollections: true},
+			`{"type":"BlocksInSpace","query":"go","spaceId":"","limit":20,"source":"quick_find_input_change",
+				"sort":{"field":"relevance"},"filters":{` + defFilters + `,"ancestors":[],"createdBy":[]}}`,
+		},
+		{
+			notionapi.SearchRequest{
+				Query:        "go",
+				AncestorID:   "94167af6567043279811dc923edd1f04",
+				CreatedBy:    []string{"user-1"},
+				EditedAfter:  time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+				EditedBefore: time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC),
+				Sort:         notionapi.SearchSortCreated,
+				Limit:        5,
+			},
+			`{"type":"BlocksInSpace","query":"go","spaceId":"","limit":5,"source":"quick_find_input_change",
+				"sort":{"field":"created"},"filters":{` + defFilters + `,
+				"ancestors":["94167af6-5670-4327-9811-dc923edd1f04"],"createdBy":["user-1"],
+				"lastEditedTime":{"starting":{"type":"date","start_date":"2021-05-01"},"ending":{"type":"date","start_date":"2021-05-03"}}}}`,
+		},
+		{
+			notionapi.SearchRequest{Query: "go", EditedAfter: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)},
+			`{"type":"BlocksInSpace","query":"go","spaceId":"","limit":20,"source":"quick_find_input_change",
+				"sort":{"field":"relevance"},"filters":{` + defFilters + `,"ancestors":[],"createdBy":[],
+				"lastEditedTime":{"starting":{"type":"date","start_date":"2021-05-01"}}}}`,
+		},
+	}
+	for _, tc := range tests {
+		_, err := client.Search(tc.req)
+		require.NoError(t, err)
+		var exp map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(tc.exp), &exp))
+		require.Equal(t, exp, got)
+	}
+}
@@ -3,6 +3,7 @@ package notionapi
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"errors"
 	"fmt"
@@ -81,6 +82,10 @@ type CachingClient struct {
 	RequestsFromServer     int
 	RequestsWrittenToCache int
 
+	// protects request counters and currPageRequests which are
+	// updated concurrently when Client.MaxConcurrency > 1
+	mu sync.Mutex
+
 	pageIDToEntries map[string][]*RequestCacheEntry
 	// we cache requests on a per-page basis
 	currPageID *NotionID
@@ -139,6 +144,12 @@ func serializeCacheEntry(rr *RequestCacheEntry, prettyPrint bool) ([]byte, error
 	return buf.Bytes(), nil
 }
 
+// ParseCacheEntries parses a file with requests cached by CachingClient
+// (${CacheDir}/${pageID}.txt)
+func ParseCacheEntries(d []byte) ([]*RequestCacheEntry, error) {
+	return deserializeCacheEntry(d)
+}
+
 func deserializeCacheEntry(d []byte) ([]*RequestCacheEntry, error) {
 	br := bufio.NewReader(bytes.NewBuffer(d))
 	r := siser.NewReader(br)
@@ -234,6 +245,8 @@ func (c *CachingClient) getFilesCacheDir() string {
 
 func (c *CachingClient) findCachedRequest(pageRequests []*RequestCacheEntry, method string, uri string, body string) (*RequestCacheEntry, bool) {
 	panicIf(c.Policy == PolicyDownloadAlways)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	bodyPP := ""
 	for _, r := range pageRequests {
 		if r.Method != method || r.URL != uri {
@@ -263,7 +276,7 @@ func (c *CachingClient) findCachedRequest(pageRequests []*RequestCacheEntry, met
 	return nil, false
 }
 
-func (c *CachingClient) doPostCacheOnly(uri string, body []byte) ([]byte, error) {
+func (c *CachingClient) doPostCacheOnly(ctx context.Context, uri string, body []byte) ([]byte, error) {
 	pageID := c.currPageID.NoDashID
 	pageRequests := c.pageIDToEntries[pageID]
 	r, ok := c.findCachedRequest(pageRequests, "POST", uri, string(body))
@@ -274,11 +287,13 @@ func (c *CachingClient) doPostCacheOnly(uri string, body []byte) ([]byte, error)
 	return nil, fmt.Errorf("no cache response for '%s' of size %d", uri, len(body))
 }
 
-func (c *CachingClient) doPostNoCache(uri string, body []byte) ([]byte, error) {
-	d, err := c.Client.doPostInternal(uri, body)
+func (c *CachingClient) doPostNoCache(ctx context.Context, uri string, body []byte) ([]byte, error) {
+	d, err := c.Client.doPostInternal(ctx, uri, body)
 	if err != nil {
 		return nil, err
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.RequestsFromServer++
 
 	if c.currPageID != nil {
@@ -337,12 +352,10 @@ func (c *CachingClient) PreLoadCache() {
 		sem <- true // enter semaphore
 		wg.Add(1)
 		go func(client *Client, cp *CachedPage, nid *NotionID) {
-			client.httpPostOverride = func(uri string, body []byte) ([]byte, error) {
+			client.httpPostOverride = func(ctx context.Context, uri string, body []byte) ([]byte, error) {
 				pageID := nid.NoDashID
 				pageRequests := c.pageIDToEntries[pageID]
-				mu.Lock()
 				r, ok := c.findCachedRequest(pageRequests, "POST", uri, string(body))
-				mu.Unlock()
 				if ok {
 					return r.Response, nil
 				}
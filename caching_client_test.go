@@ -71,6 +71,12 @@ func TestPage94167af6567043279811dc923edd1f04(t *testing.T) {
 	pid := "94167af6567043279811dc923edd1f04"
 	p := testDownloadFromCache(t, pid)
 	require.Equal(t, 2, len(p.TableViews))
+	// the count of rows is computed by Notion
+	for _, tv := range p.TableViews {
+		agg := tv.Aggregations["title"]
+		require.False(t, agg.Local)
+		require.Equal(t, 3.0, agg.Value.Number)
+	}
 	//convertToMdAndHTML(t, p)
 }
 
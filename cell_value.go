@@ -0,0 +1,200 @@
+package notionapi
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellValue is a typed value of a cell in a TableView, parsed from
+// text spans in which Notion stores values of properties.
+// Which fields are set depends on Type.
+type CellValue struct {
+	// ColumnTypeTitle etc.
+	Type string
+	// original content of the cell
+	TextSpans []*TextSpan
+	// cell content as plain text, set for all types
+	Text string
+
+	// for Type == ColumnTypeNumber
+	Number float64
+	// for Type == ColumnTypeCheckbox
+	Checkbox bool
+	// for Type == ColumnTypeDate
+	Date *Date
+	// for Type == ColumnTypeDate (start of the date),
+	// ColumnTypeCreatedTime and ColumnTypeLastEditedTime
+	Time time.Time
+	// for Type == ColumnTypeSelect (at most one) and ColumnTypeMultiSelect
+	Options []*CollectionColumnOption
+	// for Type == ColumnTypePerson, ColumnTypeCreatedBy and ColumnTypeLastEditedBy
+	UserIDs []string
+	// users for UserIDs, only those we have in the Page
+	Users []*NotionUser
+	// for Type == ColumnTypeRelation, ids of related pages
+	BlockIDs []string
+	// for Type == ColumnTypeFile
+	FileURLs []string
+	// for Type == ColumnTypeFormula, evaluated locally if Notion didn't
+	// give us the value. nil if the formula couldn't be evaluated
+	Formula *FormulaValue
+}
+
+// IsEmpty returns true if the cell has no value
+func (v *CellValue) IsEmpty() bool {
+	switch v.Type {
+	case ColumnTypeCreatedTime, ColumnTypeLastEditedTime:
+		return v.Time.IsZero()
+	case ColumnTypeCreatedBy, ColumnTypeLastEditedBy:
+		return len(v.UserIDs) == 0
+	}
+	return len(v.TextSpans) == 0
+}
+
+// findOption finds an option of select or multi select column by value.
+// Returns an option without ID and color if it's not in the schema
+func findOption(schema *ColumnSchema, value string) *CollectionColumnOption {
+	if schema != nil {
+		for _, o := range schema.Options {
+			if o.Value == value {
+				return o
+			}
+		}
+	}
+	return &CollectionColumnOption{Value: value}
+}
+
+// parse Notion's date/time into time.Time, zero time if invalid
+func parseDateTime(d *Date) time.Time {
+	layout := "2006-01-02"
+	s := d.StartDate
+	if d.StartTime != "" {
+		layout += " 15:04"
+		s += " " + d.StartTime
+	}
+	loc := time.UTC
+	if d.TimeZone != nil {
+		if l, err := time.LoadLocation(*d.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation(layout, s, loc)
+	return t
+}
+
+func (v *CellValue) addUser(page *Page, userID string) {
+	v.UserIDs = append(v.UserIDs, userID)
+	if page == nil || page.idToNotionUser == nil {
+		return
+	}
+	if u := page.idToNotionUser[userID]; u != nil {
+		v.Users = append(v.Users, u)
+	}
+}
+
+// NewCellValue parses value of property of a row page, for a column
+// with a given schema. page is used to resolve users and can be nil
+func NewCellValue(row *Block, propID string, schema *ColumnSchema, page *Page) *CellValue {
+	v := &CellValue{
+		TextSpans: row.GetProperty(propID),
+	}
+	if schema != nil {
+		v.Type = schema.Type
+	}
+	v.Text = TextSpansToString(v.TextSpans)
+
+	switch v.Type {
+	case ColumnTypeNumber:
+		v.Number, _ = strconv.ParseFloat(strings.TrimSpace(v.Text), 64)
+	case ColumnTypeCheckbox:
+		v.Checkbox = v.Text == "Yes"
+	case ColumnTypeSelect:
+		if v.Text != "" {
+			v.Options = []*CollectionColumnOption{findOption(schema, v.Text)}
+		}
+	case ColumnTypeMultiSelect:
+		for _, s := range strings.Split(v.Text, ",") {
+			if s != "" {
+				v.Options = append(v.Options, findOption(schema, s))
+			}
+		}
+	case ColumnTypeCreatedTime:
+		v.Time = row.CreatedOn()
+	case ColumnTypeLastEditedTime:
+		v.Time = row.LastEditedOn()
+	case ColumnTypeCreatedBy:
+		if row.CreatedBy != "" {
+			v.addUser(page, row.CreatedBy)
+		}
+	case ColumnTypeLastEditedBy:
+		if row.LastEditedBy != "" {
+			v.addUser(page, row.LastEditedBy)
+		}
+	}
+
+	for _, ts := range v.TextSpans {
+		for _, attr := range ts.Attrs {
+			switch AttrGetType(attr) {
+			case AttrDate:
+				if v.Type == ColumnTypeDate && v.Date == nil {
+					v.Date = AttrGetDate(attr)
+					v.Time = parseDateTime(v.Date)
+				}
+			case AttrUser:
+				if v.Type == ColumnTypePerson {
+					v.addUser(page, AttrGetUserID(attr))
+				}
+			case AttrPage:
+				if v.Type == ColumnTypeRelation {
+					v.BlockIDs = append(v.BlockIDs, AttrGetPageID(attr))
+				}
+			case AttrLink:
+				if v.Type == ColumnTypeFile {
+					v.FileURLs = append(v.FileURLs, AttrGetLink(attr))
+				}
+			}
+		}
+	}
+	return v
+}
+
+// columnSchema returns schema of a column with a given id, looking
+// first in columns of the view and then in the collection
+func (r *TableRow) columnSchema(colID string) *ColumnSchema {
+	tv := r.TableView
+	if tv == nil {
+		return nil
+	}
+	for _, ci := range tv.Columns {
+		if ci.ID() == colID && ci.Schema != nil {
+			return ci.Schema
+		}
+	}
+	if tv.Collection != nil {
+		return tv.Collection.Schema[colID]
+	}
+	return nil
+}
+
+// Value returns a typed value of the cell in column with a given id
+func (r *TableRow) Value(colID string) *CellValue {
+	var page *Page
+	if r.TableView != nil {
+		page = r.TableView.Page
+	}
+	schema := r.columnSchema(colID)
+	v := NewCellValue(r.Page, colID, schema, page)
+	if v.Type == ColumnTypeFormula && len(v.TextSpans) == 0 && schema.Formula != nil {
+		if fv, err := EvalFormula(schema.Formula, r); err == nil {
+			v.Formula = fv
+			v.Text = fv.String()
+		}
+	}
+	return v
+}
+
+// Get returns a typed value of a cell
+func (t *TableView) Get(row, col int) *CellValue {
+	return t.Rows[row].Value(t.Columns[col].ID())
+}
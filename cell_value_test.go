@@ -0,0 +1,93 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestCellValue(t *testing.T) {
+	propsJSON := `{
+	"title": [["My page"]],
+	"num": [["12.5"]],
+	"chk": [["Yes"]],
+	"sel": [["Done"]],
+	"msel": [["go,rust"]],
+	"date": [["‣", [["d", {"type": "date", "start_date": "2021-05-03"}]]]],
+	"person": [["‣", [["u", "user-1"]]], [","], ["‣", [["u", "user-2"]]]],
+	"rel": [["‣", [["p", "page-1"]]]],
+	"file": [["a.png", [["a", "https://example.com/a.png"]]]]
+}`
+	var props map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(propsJSON), &props))
+	row := &Block{
+		ID:             "row",
+		Properties:     props,
+		CreatedTime:    1620000000000,
+		LastEditedBy:   "user-1",
+		LastEditedTime: 1620000000000,
+	}
+	user := &NotionUser{ID: "user-1"}
+	page := &Page{idToNotionUser: map[string]*NotionUser{"user-1": user}}
+	schema := map[string]*ColumnSchema{
+		"title":  {Name: "Name", Type: ColumnTypeTitle},
+		"num":    {Name: "Num", Type: ColumnTypeNumber},
+		"chk":    {Name: "Check", Type: ColumnTypeCheckbox},
+		"sel":    {Name: "Status", Type: ColumnTypeSelect, Options: []*CollectionColumnOption{{ID: "o1", Value: "Done", Color: "green"}}},
+		"msel":   {Name: "Tags", Type: ColumnTypeMultiSelect, Options: []*CollectionColumnOption{{ID: "o2", Value: "go"}}},
+		"date":   {Name: "Due", Type: ColumnTypeDate},
+		"person": {Name: "Owner", Type: ColumnTypePerson},
+		"rel":    {Name: "Related", Type: ColumnTypeRelation},
+		"file":   {Name: "Files", Type: ColumnTypeFile},
+		"ct":     {Name: "Created", Type: ColumnTypeCreatedTime},
+		"leb":    {Name: "Edited by", Type: ColumnTypeLastEditedBy},
+		"empty":  {Name: "Empty", Type: ColumnTypeNumber},
+	}
+	tv := &TableView{
+		Page:       page,
+		Collection: &Collection{Schema: schema},
+	}
+	for _, id := range []string{"title", "num", "chk"} {
+		tv.Columns = append(tv.Columns, &ColumnInfo{
+			TableView: tv,
+			Schema:    schema[id],
+			Property:  &TableProperty{Property: id},
+		})
+	}
+	tr := &TableRow{TableView: tv, Page: row}
+	tv.Rows = append(tv.Rows, tr)
+
+	v := tv.Get(0, 0)
+	assert.Equal(t, ColumnTypeTitle, v.Type)
+	assert.Equal(t, "My page", v.Text)
+	assert.Equal(t, 12.5, tv.Get(0, 1).Number)
+	assert.True(t, tv.Get(0, 2).Checkbox)
+
+	// columns not in the view are taken from Collection.Schema
+	v = tr.Value("sel")
+	assert.Equal(t, 1, len(v.Options))
+	assert.Equal(t, "green", v.Options[0].Color)
+
+	v = tr.Value("msel")
+	assert.Equal(t, 2, len(v.Options))
+	assert.Equal(t, "o2", v.Options[0].ID)
+	assert.Equal(t, "rust", v.Options[1].Value)
+	assert.Equal(t, "", v.Options[1].ID)
+
+	v = tr.Value("date")
+	assert.Equal(t, "2021-05-03", v.Date.StartDate)
+	assert.Equal(t, time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC), v.Time)
+
+	v = tr.Value("person")
+	assert.Equal(t, []string{"user-1", "user-2"}, v.UserIDs)
+	assert.Equal(t, []*NotionUser{user}, v.Users)
+
+	assert.Equal(t, []string{"page-1"}, tr.Value("rel").BlockIDs)
+	assert.Equal(t, []string{"https://example.com/a.png"}, tr.Value("file").FileURLs)
+	assert.Equal(t, row.CreatedOn(), tr.Value("ct").Time)
+	assert.Equal(t, []string{"user-1"}, tr.Value("leb").UserIDs)
+	assert.True(t, tr.Value("empty").IsEmpty())
+	assert.False(t, tr.Value("num").IsEmpty())
+}
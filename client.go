@@ -2,17 +2,21 @@ package notionapi
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	notionHost = "https://www.notion.so"
+	// DefaultBaseURL is where Notion API lives
+	DefaultBaseURL = "https://www.notion.so"
 	// modern Chrome
 	userAgent  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_13_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/69.0.3483.0 Safari/537.36"
 	acceptLang = "en-US,en;q=0.9"
@@ -22,6 +26,9 @@ const (
 type Client struct {
 	// AuthToken allows accessing non-public pages.
 	AuthToken string
+	// BaseURL is the url of Notion server, DefaultBaseURL if not set.
+	// Over-ride it to test against a fake server (see notiontest package)
+	BaseURL string
 	// HTTPClient allows over-riding http.Client
 	HTTPClient *http.Client
 	// Logger is used to log requests and responses for debugging.
@@ -29,19 +36,44 @@ type Client struct {
 	Logger io.Writer
 	// DebugLog enables debug logging
 	DebugLog bool
-	// MinRequestDelay is for controlling rate limiting. it's 333 ms by default
-	// because https://developers.notion.com/reference/errors#rate-limits
-	// says rate limit is, on average, 3 requests per second
+	// Log is a structured logger (e.g. *slog.Logger). If set, it's used
+	// instead of Logger and decides itself which levels to log
+	Log StructuredLogger
+	// OnRequest, if set, is called before each HTTP request
+	OnRequest func(ev *RequestEvent)
+	// OnResponse, if set, is called after each HTTP request finishes,
+	// successfully or not
+	OnResponse func(ev *RequestEvent)
+	// RateLimiter controls how often we send requests. If not set, all
+	// Clients with the same AuthToken share a limiter that allows
+	// DefaultRequestsPerSecond on average
+	RateLimiter RateLimiter
+	// MinRequestDelay is an older way of controlling rate limiting.
+	// If set (and RateLimiter is not) we wait at least MinRequestDelay
+	// between requests
 	MinRequestDelay time.Duration
-	// simplest rate limiting: track last request time and wait at least
-	// MinRequestDelay between requests
-	lastRequestTime time.Time
-
-	httpPostOverride func(uri string, body []byte) ([]byte, error)
+	// RetryPolicy decides which failed requests are retried.
+	// If not set, DefaultRetryPolicy is used
+	RetryPolicy RetryPolicy
+	// MaxConcurrency is the maximum number of requests DownloadPage sends
+	// at the same time when fetching blocks and querying collections.
+	// 0 or 1 means sequential. Requests still go through RateLimiter and
+	// the resulting Page doesn't depend on MaxConcurrency.
+	// When > 1, OnRequest and OnResponse can be called from multiple goroutines
+	MaxConcurrency int
+	// CollectionPageSize is how many rows of a collection we ask for in
+	// a single request. DefaultCollectionPageSize if not set
+	CollectionPageSize int
+
+	httpPostOverride func(ctx context.Context, uri string, body []byte) ([]byte, error)
 }
 
 // vlogf is for verbose logging
 func (c *Client) vlogf(format string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Debug(formatLogMsg(format, args))
+		return
+	}
 	if !c.DebugLog {
 		return
 	}
@@ -49,6 +81,10 @@ func (c *Client) vlogf(format string, args ...interface{}) {
 }
 
 func (c *Client) logf(format string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Info(formatLogMsg(format, args))
+		return
+	}
 	if c.Logger == nil {
 		return
 	}
@@ -59,6 +95,22 @@ func (c *Client) logf(format string, args ...interface{}) {
 	fmt.Fprintf(c.Logger, format, args...)
 }
 
+// formatLogMsg formats printf-style log message for StructuredLogger
+func formatLogMsg(format string, args []interface{}) string {
+	s := format
+	if len(args) > 0 {
+		s = fmt.Sprintf(format, args...)
+	}
+	return strings.TrimSpace(s)
+}
+
+func (c *Client) getBaseURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimSuffix(c.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
 func (c *Client) getHTTPClient() *http.Client {
 	if c.HTTPClient != nil {
 		return c.HTTPClient
@@ -68,82 +120,146 @@ func (c *Client) getHTTPClient() *http.Client {
 	return &httpClient
 }
 
-func (c *Client) rateLimitRequest() {
-	if !c.lastRequestTime.IsZero() {
-		minDelay := c.MinRequestDelay
-		if minDelay == 0 {
-			minDelay = time.Millisecond * 360
+// sleepCtx sleeps for d or until ctx is cancelled, whichever comes first
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// runConcurrently calls fn(ctx, i) for i in [0, n) using up to
+// c.MaxConcurrency goroutines. If some calls fail, the remaining ones are
+// cancelled and we return the error with the lowest i, so that the
+// result doesn't depend on scheduling
+func (c *Client) runConcurrently(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	if c.MaxConcurrency <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, n)
+	sem := make(chan bool, c.MaxConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n && workCtx.Err() == nil; i++ {
+		sem <- true
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(workCtx, i); err != nil {
+				errs[i] = err
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// errors caused by our cancel() are less interesting than the
+	// error that triggered it
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
 		}
-		since := time.Since(c.lastRequestTime)
-		if minDelay > since {
-			time.Sleep(minDelay - since)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
 		}
 	}
-	c.lastRequestTime = time.Now()
+	return firstErr
 }
 
-func (c *Client) doPost(uri string, body []byte) ([]byte, error) {
+func (c *Client) doPost(ctx context.Context, uri string, body []byte) ([]byte, error) {
 	if c.httpPostOverride != nil {
-		return c.httpPostOverride(uri, body)
+		return c.httpPostOverride(ctx, uri, body)
 	}
-	return c.doPostInternal(uri, body)
+	return c.doPostInternal(ctx, uri, body)
 }
 
-func (c *Client) doPostInternal(uri string, body []byte) ([]byte, error) {
-	c.rateLimitRequest()
+func (c *Client) doPostInternal(ctx context.Context, uri string, body []byte) ([]byte, error) {
+	endpoint := endpointFromURL(uri)
+	retryPolicy := c.getRetryPolicy()
+	for attempt := 0; ; attempt++ {
+		if err := c.getRateLimiter().Wait(ctx); err != nil {
+			return nil, err
+		}
+		d, err := c.doPostOnce(ctx, uri, body, attempt)
+		if err == nil {
+			return d, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		delay, retry := retryPolicy.RetryDelay(endpoint, attempt, err)
+		if !retry {
+			return nil, err
+		}
+		c.logWarn("retrying request", "endpoint", endpoint, "delay", delay, "retries", attempt+1, "err", err)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
 
-	// try to back-off exponentially
-	// note: backing off doesn't seem to work i.e. I get 429 from subsequent requests as well
-	nRepeats := 0
-	timeouts := []time.Duration{time.Second * 3, time.Second * 5, time.Second * 10}
-repeatRequest:
+func (c *Client) doPostOnce(ctx context.Context, uri string, body []byte, attempt int) (d []byte, err error) {
 	br := bytes.NewBuffer(body)
-	req, err := http.NewRequest("POST", uri, br)
+	req, err := http.NewRequestWithContext(ctx, "POST", uri, br)
 	if err != nil {
 		return nil, err
 	}
+	ev, timeStart := c.startRequest(ctx, endpointFromURL(uri), "POST", uri, int64(len(body)), attempt)
+	status := 0
+	defer func() {
+		c.finishRequest(ev, timeStart, status, int64(len(d)), err)
+	}()
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Accept-Language", acceptLang)
 	if c.AuthToken != "" {
 		req.Header.Set("cookie", fmt.Sprintf("token_v2=%v", c.AuthToken))
 	}
-	var rsp *http.Response
 
 	httpClient := c.getHTTPClient()
-	rsp, err = httpClient.Do(req)
-
+	rsp, err := httpClient.Do(req)
 	if err != nil {
-		c.logf("httpClient.Do() failed with %s\n", err)
 		return nil, err
 	}
-
-	if rsp.StatusCode == http.StatusTooManyRequests {
-		if nRepeats < 3 {
-			closeNoError(rsp.Body)
-			c.logf("retrying '%s' because httpClient.Do() returned %d (%s)\n", uri, rsp.StatusCode, rsp.Status)
-			time.Sleep(timeouts[nRepeats])
-			nRepeats++
-			goto repeatRequest
-		}
-	}
-
 	defer closeNoError(rsp.Body)
+	status = rsp.StatusCode
 
 	if rsp.StatusCode != 200 {
-		d, _ := ioutil.ReadAll(rsp.Body)
-		c.logf("Error: status code %s\nBody:\n%s\n", rsp.Status, PrettyPrintJS(d))
-		return nil, fmt.Errorf("http.Post('%s') returned non-200 status code of %d", uri, rsp.StatusCode)
+		d, _ = ioutil.ReadAll(rsp.Body)
+		c.vlogf("Body:\n%s\n", PrettyPrintJS(d))
+		return d, newAPIError(uri, rsp, d)
 	}
-	d, err := ioutil.ReadAll(rsp.Body)
+	d, err = ioutil.ReadAll(rsp.Body)
 	if err != nil {
-		c.logf("Error: ioutil.ReadAll() failed with %s\n", err)
 		return nil, err
 	}
 	return d, nil
 }
 
-func (c *Client) doNotionAPI(apiURL string, requestData interface{}, result interface{}, rawJSON *map[string]interface{}) error {
+func (c *Client) doNotionAPI(ctx context.Context, apiURL string, requestData interface{}, result interface{}, rawJSON *map[string]interface{}) error {
 	var body []byte
 	var err error
 	if requestData != nil {
@@ -152,13 +268,13 @@ func (c *Client) doNotionAPI(apiURL string, requestData interface{}, result inte
 			return err
 		}
 	}
-	uri := notionHost + apiURL
-	c.logf("POST %s\n", uri)
+	uri := c.getBaseURL() + apiURL
+	c.logInfo("POST", "url", uri)
 	if len(body) > 0 {
 		logJSON(c, body)
 	}
 
-	d, err := c.doPost(uri, body)
+	d, err := c.doPost(ctx, uri, body)
 	if err != nil {
 		return err
 	}
@@ -166,7 +282,7 @@ func (c *Client) doNotionAPI(apiURL string, requestData interface{}, result inte
 
 	err = jsonit.Unmarshal(d, result)
 	if err != nil {
-		c.logf("Error: json.Unmarshal() failed with %s\n. Body:\n%s\n", err, string(d))
+		c.logError("json.Unmarshal() failed", "url", uri, "err", err, "body", string(d))
 		return err
 	}
 	if rawJSON != nil {
@@ -258,6 +374,12 @@ func (p *Page) findMissingBlocks() []string {
 
 // DownloadPage returns Notion page data given its id
 func (c *Client) DownloadPage(pageID string) (*Page, error) {
+	return c.DownloadPageCtx(context.Background(), pageID)
+}
+
+// DownloadPageCtx is like DownloadPage but the download can be
+// cancelled with ctx
+func (c *Client) DownloadPageCtx(ctx context.Context, pageID string) (*Page, error) {
 	id := ToDashID(pageID)
 	if !IsValidDashID(id) {
 		return nil, fmt.Errorf("%s is not a valid Notion page id", id)
@@ -282,7 +404,7 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 	var root *Block
 	// get page's root block and then recursively download referenced blocks
 	{
-		blocks, err := c.GetBlockRecords([]string{pageID})
+		blocks, err := c.GetBlockRecordsCtx(ctx, []string{pageID})
 		if err != nil {
 			return nil, err
 		}
@@ -298,7 +420,7 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 	chunkNo := 0
 	var cur *cursor
 	for {
-		rsp, err := c.LoadCachedPageChunk(pageID, chunkNo, cur)
+		rsp, err := c.LoadCachedPageChunkCtx(ctx, pageID, chunkNo, cur)
 		chunkNo++
 		if err != nil {
 			return nil, err
@@ -354,6 +476,17 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 		// the API worked even with 6k items, but I'll split it into many
 		// smaller requests anyway
 		maxToGet := 128 * 10
+		if c.MaxConcurrency > 1 {
+			// use smaller chunks so that all workers have something to do
+			perWorker := (len(missing) + c.MaxConcurrency - 1) / c.MaxConcurrency
+			if perWorker < 128 {
+				perWorker = 128
+			}
+			if perWorker < maxToGet {
+				maxToGet = perWorker
+			}
+		}
+		var chunks [][]string
 		for len(missing) > 0 {
 			toGet := missing
 			if len(toGet) > maxToGet {
@@ -362,11 +495,23 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 			} else {
 				missing = nil
 			}
+			chunks = append(chunks, toGet)
+		}
 
-			blocks, err := c.GetBlockRecords(toGet)
-			if err != nil {
-				return nil, err
-			}
+		chunkBlocks := make([][]*Block, len(chunks))
+		err := c.runConcurrently(ctx, len(chunks), func(ctx context.Context, i int) error {
+			blocks, err := c.GetBlockRecordsCtx(ctx, chunks[i])
+			chunkBlocks[i] = blocks
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// merge results in the order of chunks, not in the order
+		// they were downloaded
+		for i, toGet := range chunks {
+			blocks := chunkBlocks[i]
 			for n, block := range blocks {
 				// This can happen e.g. in 157765353f2c4705bd45474e5ba8b46c
 				// Server returns { "role": "none" },
@@ -420,6 +565,15 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 			}
 	*/
 
+	// collect collection queries first so that we can run them concurrently
+	type collectionQuery struct {
+		block          *Block
+		collection     *Collection
+		collectionView *CollectionView
+		req            QueryCollectionRequest
+		res            *QueryCollectionResponse
+	}
+	var queries []*collectionQuery
 	blockIDs := getBlockIDsSorted(p.idToBlock)
 	for _, id := range blockIDs {
 		block := p.idToBlock[id]
@@ -449,27 +603,40 @@ func (c *Client) DownloadPage(pageID string) (*Page, error) {
 				continue
 			}
 			spaceID := block.SpaceID
-			req := QueryCollectionRequest{}
-			req.Collection.ID = collectionID
-			req.Collection.SpaceID = spaceID
-			req.CollectionView.ID = collectionViewID
-			req.CollectionView.SpaceID = spaceID
-			res, err := c.QueryCollection(req, collectionView.Query)
-			if err != nil {
-				return nil, err
+			q := &collectionQuery{
+				block:          block,
+				collection:     collection,
+				collectionView: collectionView,
 			}
+			q.req.Collection.ID = collectionID
+			q.req.Collection.SpaceID = spaceID
+			q.req.CollectionView.ID = collectionViewID
+			q.req.CollectionView.SpaceID = spaceID
+			queries = append(queries, q)
+		}
+	}
 
-			tableView := &TableView{
-				Page:           p,
-				CollectionView: collectionView,
-				Collection:     collection,
-			}
-			if err := c.buildTableView(tableView, res); err != nil {
-				return nil, err
-			}
-			block.TableViews = append(block.TableViews, tableView)
-			p.TableViews = append(p.TableViews, tableView)
+	err = c.runConcurrently(ctx, len(queries), func(ctx context.Context, i int) error {
+		q := queries[i]
+		var err error
+		q.res, err = c.QueryCollectionViewCtx(ctx, q.req, q.collectionView)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range queries {
+		tableView := &TableView{
+			Page:           p,
+			CollectionView: q.collectionView,
+			Collection:     q.collection,
+		}
+		if err := c.buildTableView(tableView, q.res); err != nil {
+			return nil, err
 		}
+		q.block.TableViews = append(q.block.TableViews, tableView)
+		p.TableViews = append(p.TableViews, tableView)
 	}
 
 	for _, b := range p.idToBlock {
@@ -0,0 +1,47 @@
+package notionapi_test
+
+import (
+	"testing"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/notiontest"
+)
+
+func newTestServer(t *testing.T) *notiontest.Server {
+	s, err := notiontest.NewServerFromCacheDir("caching_client_testdata")
+	require.NoError(t, err)
+	return s
+}
+
+func pageSummary(p *notionapi.Page) []string {
+	var res []string
+	p.ForEachBlock(func(b *notionapi.Block) {
+		res = append(res, b.ID)
+	})
+	for _, tv := range p.TableViews {
+		res = append(res, tv.CollectionView.ID)
+		for _, row := range tv.Rows {
+			res = append(res, row.Page.ID)
+		}
+	}
+	return res
+}
+
+func TestDownloadPageConcurrent(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	pageID := "94167af6567043279811dc923edd1f04"
+	p, err := client.DownloadPage(pageID)
+	require.NoError(t, err)
+	exp := pageSummary(p)
+
+	client.MaxConcurrency = 4
+	for i := 0; i < 4; i++ {
+		p, err = client.DownloadPage(pageID)
+		require.NoError(t, err)
+		require.Equal(t, exp, pageSummary(p))
+	}
+}
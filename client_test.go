@@ -1,7 +1,14 @@
 package notionapi
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/kjk/common/assert"
 )
@@ -39,3 +46,98 @@ func TestExtractNoDashIDFromNotionURL(t *testing.T) {
 		assert.Equal(t, exp, got)
 	}
 }
+
+func TestDownloadURLCtxCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	client := &Client{}
+	rsp, err := client.DownloadURLCtx(context.Background(), ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(rsp.Data))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.DownloadURLCtx(ctx, ts.URL)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"errorId":"e1","name":"UnauthorizedError","message":"Token was invalid or expired."}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{}
+	_, err := client.doPostInternal(context.Background(), ts.URL+"/api/v3/syncRecordValues", nil)
+	wrapped := fmt.Errorf("wrapped: %w", err)
+	e, ok := AsAPIError(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, e.StatusCode)
+	assert.Equal(t, "syncRecordValues", e.Endpoint)
+	assert.Equal(t, "e1", e.ErrorID)
+	assert.Equal(t, "UnauthorizedError", e.Name)
+	assert.Equal(t, "Token was invalid or expired.", e.Message)
+	assert.Equal(t, 7*time.Second, e.RetryAfter)
+	assert.True(t, IsUnauthorized(wrapped))
+	assert.False(t, IsRateLimited(wrapped))
+	assert.False(t, IsErrPageNotFound(wrapped))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-3", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter("Sun, 02 Jan 2022 03:04:15 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("garbage", now))
+}
+
+func TestRunConcurrently(t *testing.T) {
+	for _, maxConcurrency := range []int{0, 1, 3} {
+		c := &Client{MaxConcurrency: maxConcurrency}
+		var running, maxRunning int32
+		res := make([]int, 10)
+		err := c.runConcurrently(context.Background(), len(res), func(ctx context.Context, i int) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			res[i] = i * 2
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		assert.NoError(t, err)
+		for i, v := range res {
+			assert.Equal(t, i*2, v)
+		}
+		limit := int32(maxConcurrency)
+		if limit < 1 {
+			limit = 1
+		}
+		assert.True(t, atomic.LoadInt32(&maxRunning) <= limit)
+	}
+
+	// the error with the lowest index wins, not the one that was first
+	c := &Client{MaxConcurrency: 4}
+	err := c.runConcurrently(context.Background(), 4, func(ctx context.Context, i int) error {
+		if i == 1 {
+			time.Sleep(10 * time.Millisecond)
+			return fmt.Errorf("error %d", i)
+		}
+		if i == 3 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "error 1", err.Error())
+}
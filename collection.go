@@ -10,6 +10,14 @@ const (
 	CollectionViewTypeTable = "table"
 	// CollectionViewTypeTable is a lists block
 	CollectionViewTypeList = "list"
+	// CollectionViewTypeBoard is a board, rows are grouped by a property
+	CollectionViewTypeBoard = "board"
+	// CollectionViewTypeGallery is a gallery of cards
+	CollectionViewTypeGallery = "gallery"
+	// CollectionViewTypeCalendar shows rows by a date property
+	CollectionViewTypeCalendar = "calendar"
+	// CollectionViewTypeTimeline shows rows on a timeline by a date property
+	CollectionViewTypeTimeline = "timeline"
 )
 
 // CollectionColumnOption describes options for ColumnTypeMultiSelect
@@ -20,20 +28,42 @@ type CollectionColumnOption struct {
 	Value string `json:"value"`
 }
 
+// FormulaArg is a node in a tree of a formula
 type FormulaArg struct {
 	Name       *string `json:"name,omitempty"`
 	ResultType string  `json:"result_type"`
-	Type       string  `json:"type"`
-	Value      *string `json:"value,omitempty"`
-	ValueType  *string `json:"value_type,omitempty"`
+	// "operator", "function", "property", "constant", "symbol", "conditional"
+	Type      string  `json:"type"`
+	Value     *string `json:"value,omitempty"`
+	ValueType *string `json:"value_type,omitempty"`
+
+	// for Type == "property", id of the column
+	ID string `json:"id,omitempty"`
+	// for Type == "operator", e.g. "+"
+	Operator string `json:"operator,omitempty"`
+	// for Type == "operator" and "function"
+	Args []FormulaArg `json:"args,omitempty"`
+	// for Type == "conditional"
+	Condition *FormulaArg `json:"condition,omitempty"`
+	True      *FormulaArg `json:"true,omitempty"`
+	False     *FormulaArg `json:"false,omitempty"`
 }
 
+// ColumnFormula is the root of a tree of a formula
 type ColumnFormula struct {
 	Args       []FormulaArg `json:"args"`
 	Name       string       `json:"name"`
 	Operator   string       `json:"operator"`
 	ResultType string       `json:"result_type"`
 	Type       string       `json:"type"`
+
+	// when the formula is not a function or an operator
+	ID        string      `json:"id,omitempty"`
+	Value     *string     `json:"value,omitempty"`
+	ValueType *string     `json:"value_type,omitempty"`
+	Condition *FormulaArg `json:"condition,omitempty"`
+	True      *FormulaArg `json:"true,omitempty"`
+	False     *FormulaArg `json:"false,omitempty"`
 }
 
 // ColumnSchema describes a info of a collection column
@@ -85,6 +115,7 @@ type Collection struct {
 	Format      *CollectionFormat        `json:"format"`
 	ParentID    string                   `json:"parent_id"`
 	ParentTable string                   `json:"parent_table"`
+	SpaceID     string                   `json:"space_id"`
 	Alive       bool                     `json:"alive"`
 	CopiedFrom  string                   `json:"copied_from"`
 	Cover       string                   `json:"cover"`
@@ -99,6 +130,9 @@ type Collection struct {
 	// calculated by us
 	name    []*TextSpan
 	RawJSON map[string]interface{} `json:"-"`
+	// ids of columns returned by newColumnID, which might not be in
+	// Schema yet
+	pendingColumnIDs map[string]bool
 }
 
 // GetName parses Name and returns as a string
@@ -136,7 +170,7 @@ type QueryAggregate struct {
 
 type QueryAggregation struct {
 	Property   string `json:"property"`
-	Aggregator string `json:"aggregator"`
+	Aggregator string `json:"aggregator"` // AggregationSum etc.
 }
 
 type Query struct {
@@ -144,13 +178,93 @@ type Query struct {
 	Aggregate    []QueryAggregate       `json:"aggregate"`
 	Aggregations []QueryAggregation     `json:"aggregations"`
 	Filter       map[string]interface{} `json:"filter"`
+
+	// for CollectionViewTypeBoard, id of the property rows are grouped by
+	GroupBy string `json:"group_by"`
+	// for CollectionViewTypeCalendar, id of the date property
+	CalendarBy string `json:"calendar_by"`
+	// for CollectionViewTypeTimeline, ids of date properties for start
+	// and (optional) end of the row
+	TimelineBy    string `json:"timeline_by"`
+	TimelineByEnd string `json:"timeline_by_end"`
+
+	// SearchQuery is not part of a collection view's query. It's only
+	// sent in queryCollection request, see MakeLoaderReducer
+	SearchQuery string `json:"-"`
+}
+
+// aggregations returns aggregations from Aggregations and the older
+// Aggregate, at most one per property
+func (q *Query) aggregations() []QueryAggregation {
+	var res []QueryAggregation
+	seen := map[string]bool{}
+	add := func(property, aggregator string) {
+		if property == "" || aggregator == "" || seen[property] {
+			return
+		}
+		seen[property] = true
+		res = append(res, QueryAggregation{Property: property, Aggregator: aggregator})
+	}
+	for _, a := range q.Aggregations {
+		add(a.Property, a.Aggregator)
+	}
+	for _, a := range q.Aggregate {
+		add(a.Property, a.AggregationType)
+	}
+	return res
 }
 
-// FormatTable describes format for BlockTable
+// FormatTable describes format of a collection view.
+// Each view type has its own list of properties
 type FormatTable struct {
 	PageSort        []string         `json:"page_sort"`
 	TableWrap       bool             `json:"table_wrap"`
 	TableProperties []*TableProperty `json:"table_properties"`
+
+	// for CollectionViewTypeList
+	ListProperties []*TableProperty `json:"list_properties"`
+
+	// for CollectionViewTypeBoard
+	BoardProperties  []*TableProperty `json:"board_properties"`
+	BoardGroups2     []*BoardGroup    `json:"board_groups2"`
+	BoardCover       *ViewCover       `json:"board_cover"`
+	BoardCoverSize   string           `json:"board_cover_size"`   // "small", "medium", "large"
+	BoardCoverAspect string           `json:"board_cover_aspect"` // "contain", "cover"
+
+	// for CollectionViewTypeGallery
+	GalleryProperties  []*TableProperty `json:"gallery_properties"`
+	GalleryCover       *ViewCover       `json:"gallery_cover"`
+	GalleryCoverSize   string           `json:"gallery_cover_size"`
+	GalleryCoverAspect string           `json:"gallery_cover_aspect"`
+
+	// for CollectionViewTypeCalendar
+	CalendarProperties []*TableProperty `json:"calendar_properties"`
+
+	// for CollectionViewTypeTimeline
+	TimelineProperties []*TableProperty `json:"timeline_properties"`
+	TimelineShowTable  bool             `json:"timeline_show_table"`
+}
+
+// Properties returns properties shown by a view of a given type.
+// Falls back to TableProperties if there are none for this type
+func (f *FormatTable) Properties(viewType string) []*TableProperty {
+	var res []*TableProperty
+	switch viewType {
+	case CollectionViewTypeList:
+		res = f.ListProperties
+	case CollectionViewTypeBoard:
+		res = f.BoardProperties
+	case CollectionViewTypeGallery:
+		res = f.GalleryProperties
+	case CollectionViewTypeCalendar:
+		res = f.CalendarProperties
+	case CollectionViewTypeTimeline:
+		res = f.TimelineProperties
+	}
+	if len(res) == 0 {
+		return f.TableProperties
+	}
+	return res
 }
 
 // CollectionView represents a collection view
@@ -219,6 +333,11 @@ type TableView struct {
 	// easier to work representation we calculate
 	Columns []*ColumnInfo
 	Rows    []*TableRow
+	// for CollectionViewTypeBoard, rows grouped by CollectionView.GroupBy(),
+	// in the order of board columns
+	Groups []*TableGroup
+	// aggregations shown under columns, keyed by column id
+	Aggregations map[string]*TableAggregation
 }
 
 func (t *TableView) RowCount() int {
@@ -261,7 +380,7 @@ func (c *Client) buildTableView(tv *TableView, res *QueryCollectionResponse) err
 	}
 
 	idx := 0
-	for _, prop := range cv.Format.TableProperties {
+	for _, prop := range cv.Format.Properties(cv.Type) {
 		if !prop.Visible {
 			continue
 		}
@@ -308,5 +427,10 @@ func (c *Client) buildTableView(tv *TableView, res *QueryCollectionResponse) err
 			tr.Columns = append(tr.Columns, v)
 		}
 	}
+
+	if cv.Type == CollectionViewTypeBoard {
+		tv.Groups = buildGroups(tv, res)
+	}
+	tv.Aggregations = buildAggregations(tv, res)
 	return nil
 }
@@ -0,0 +1,120 @@
+package notionapi
+
+import "context"
+
+// CollectionRowIterator iterates over rows of a collection view, asking
+// the server for more rows as needed. Use it to stop after the first rows
+// without downloading the whole collection:
+//
+//	it := client.IterateCollection(ctx, req, query)
+//	for it.Next() {
+//		row := it.Block()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+//
+// It doesn't stream rows. Notion has no offset for results so each
+// request asks for Client.CollectionPageSize more rows than the previous
+// one and downloads again all the rows returned before. Iterating over n
+// rows transfers O(n^2) rows. Only records of rows not yet returned are
+// kept in memory
+type CollectionRowIterator struct {
+	client *Client
+	ctx    context.Context
+	req    QueryCollectionRequest
+	limit  int
+
+	recordMap *RecordMap
+	ids       []string
+	seen      map[string]bool
+	total     int
+	done      bool
+
+	block *Block
+	err   error
+}
+
+// IterateCollection returns an iterator over rows of a collection view.
+// req.Loader is ignored, query (can be nil) provides sort and filter
+func (c *Client) IterateCollection(ctx context.Context, req QueryCollectionRequest, query *Query) *CollectionRowIterator {
+	req.Loader = MakeLoaderReducer(query)
+	return &CollectionRowIterator{
+		client: c,
+		ctx:    ctx,
+		req:    req,
+		seen:   map[string]bool{},
+		total:  -1,
+	}
+}
+
+func (it *CollectionRowIterator) fetchMore() error {
+	it.limit += it.client.getCollectionPageSize()
+	it.req.Loader = withResultsLimit(it.req.Loader, it.limit)
+	rsp, err := it.client.queryCollectionOnce(it.ctx, it.req)
+	if err != nil {
+		return err
+	}
+	if err = ParseRecordMap(rsp.RecordMap); err != nil {
+		return err
+	}
+	gr := rsp.GroupResults()
+	if gr == nil {
+		it.done = true
+		return nil
+	}
+	it.recordMap = rsp.RecordMap
+	it.total = gr.Total
+	it.ids = it.ids[:0]
+	for _, id := range gr.BlockIds {
+		if it.seen[id] {
+			// we already returned it, don't keep it again
+			delete(it.recordMap.Blocks, id)
+			continue
+		}
+		it.seen[id] = true
+		it.ids = append(it.ids, id)
+	}
+	if len(it.ids) == 0 || len(gr.BlockIds) >= gr.Total || len(gr.BlockIds) < it.limit {
+		it.done = true
+	}
+	return nil
+}
+
+// Next advances to the next row. Returns false when there are no
+// more rows or there was an error
+func (it *CollectionRowIterator) Next() bool {
+	for it.err == nil {
+		for len(it.ids) > 0 {
+			id := it.ids[0]
+			it.ids = it.ids[1:]
+			rec := it.recordMap.Blocks[id]
+			delete(it.recordMap.Blocks, id)
+			if rec != nil && rec.Block != nil {
+				it.block = rec.Block
+				return true
+			}
+		}
+		if it.done {
+			break
+		}
+		it.err = it.fetchMore()
+	}
+	it.block = nil
+	return false
+}
+
+// Block returns the page block of the current row
+func (it *CollectionRowIterator) Block() *Block {
+	return it.block
+}
+
+// Total returns total number of rows reported by the server,
+// -1 before the first request
+func (it *CollectionRowIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *CollectionRowIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,58 @@
+package notionapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+)
+
+func TestQueryCollectionPagination(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	pageID := "94167af6567043279811dc923edd1f04"
+	p, err := client.DownloadPage(pageID)
+	require.NoError(t, err)
+	tv := p.TableViews[0]
+	nRows := tv.RowCount()
+	require.True(t, nRows > 2)
+
+	client.CollectionPageSize = 2
+	p2, err := client.DownloadPage(pageID)
+	require.NoError(t, err)
+	require.Equal(t, pageSummary(p), pageSummary(p2))
+
+	req := notionapi.QueryCollectionRequest{}
+	req.Collection.ID = tv.Collection.ID
+	req.Collection.SpaceID = tv.CollectionView.SpaceID
+	req.CollectionView.ID = tv.CollectionView.ID
+	req.CollectionView.SpaceID = tv.CollectionView.SpaceID
+
+	// every request asks for one more page of rows
+	nRequests := 0
+	client.OnRequest = func(ev *notionapi.RequestEvent) {
+		if ev.Endpoint == "queryCollection" {
+			nRequests++
+		}
+	}
+	rsp, err := client.QueryCollection(req, tv.CollectionView.Query)
+	require.NoError(t, err)
+	require.Equal(t, nRows, len(rsp.GroupResults().BlockIds))
+	require.Equal(t, (nRows+1)/2, nRequests)
+	client.OnRequest = nil
+
+	it := client.IterateCollection(context.Background(), req, tv.CollectionView.Query)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Block().ID)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, nRows, it.Total())
+	require.Equal(t, nRows, len(ids))
+	for i, row := range tv.Rows {
+		require.Equal(t, row.Page.ID, ids[i])
+	}
+}
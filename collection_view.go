@@ -0,0 +1,191 @@
+package notionapi
+
+import (
+	"fmt"
+)
+
+// GroupValue is a value by which rows are grouped in a board view.
+// Value is nil for a group of rows without a value ("No Status")
+type GroupValue struct {
+	// ColumnTypeSelect etc.
+	Type  string      `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// String returns the value as a string, "" for a group without a value
+func (v *GroupValue) String() string {
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	if s, ok := v.Value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v.Value)
+}
+
+// BoardGroup describes a group (column) of a board view
+type BoardGroup struct {
+	Property string      `json:"property"`
+	Value    *GroupValue `json:"value"`
+	Hidden   bool        `json:"hidden"`
+}
+
+// ViewCover describes what is shown as a cover of cards in board and
+// gallery views
+type ViewCover struct {
+	// "page_cover", "page_content" or "property"
+	Type string `json:"type"`
+	// for Type == "property", id of a file property
+	Property string `json:"property"`
+}
+
+// TableGroup is a group (column) of rows in a board view
+type TableGroup struct {
+	Property string
+	Value    *GroupValue
+	// hidden groups are not shown in Notion but we still have their rows
+	Hidden bool
+	Rows   []*TableRow
+}
+
+// GroupBy returns id of the property by which a board view groups rows
+func (cv *CollectionView) GroupBy() string {
+	if cv.Query != nil && cv.Query.GroupBy != "" {
+		return cv.Query.GroupBy
+	}
+	if cv.Format != nil {
+		for _, g := range cv.Format.BoardGroups2 {
+			if g.Property != "" {
+				return g.Property
+			}
+		}
+	}
+	return ""
+}
+
+// DateProperty returns id of the date property of calendar and
+// timeline views, "" for other views
+func (cv *CollectionView) DateProperty() string {
+	if cv.Query == nil {
+		return ""
+	}
+	switch cv.Type {
+	case CollectionViewTypeCalendar:
+		return cv.Query.CalendarBy
+	case CollectionViewTypeTimeline:
+		return cv.Query.TimelineBy
+	}
+	return ""
+}
+
+// Cover returns what is shown as a cover of cards in board and gallery
+// views, nil if there's no cover
+func (cv *CollectionView) Cover() *ViewCover {
+	if cv.Format == nil {
+		return nil
+	}
+	switch cv.Type {
+	case CollectionViewTypeBoard:
+		return cv.Format.BoardCover
+	case CollectionViewTypeGallery:
+		return cv.Format.GalleryCover
+	}
+	return nil
+}
+
+// boardGroups returns groups of a board view that group by its
+// GroupBy() property
+func (cv *CollectionView) boardGroups() []*BoardGroup {
+	groupBy := cv.GroupBy()
+	if groupBy == "" || cv.Format == nil {
+		return nil
+	}
+	var res []*BoardGroup
+	for _, g := range cv.Format.BoardGroups2 {
+		if g.Property == groupBy && g.Value != nil {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// groupReducerName returns the name of a reducer that returns rows in
+// a group e.g. "results:select:Done"
+func groupReducerName(v *GroupValue) string {
+	if v.Value == nil {
+		return "results:" + v.Type + ":uncategorized"
+	}
+	return "results:" + v.Type + ":" + v.String()
+}
+
+// groupFilter returns a filter for rows in a group
+func groupFilter(property string, v *GroupValue) *QueryFilter {
+	p := Filter.Property(property)
+	if v.Value == nil {
+		return p.IsEmpty()
+	}
+	switch v.Type {
+	case ColumnTypeMultiSelect:
+		return p.MultiSelect().Contains(v.String())
+	case ColumnTypeCheckbox:
+		return p.Checkbox().Is(v.String() == "true")
+	}
+	return p.Select().Is(v.String())
+}
+
+// groupContains returns true if a row with a cell value v belongs to a group
+func groupContains(v *CellValue, gv *GroupValue) bool {
+	if v.Type == ColumnTypeCheckbox {
+		return gv.Value != nil && fmt.Sprintf("%v", v.Checkbox) == gv.String()
+	}
+	vals := cellValues(v)
+	if gv.Value == nil {
+		return len(vals) == 0
+	}
+	s := gv.String()
+	for _, val := range vals {
+		if val == s {
+			return true
+		}
+	}
+	return false
+}
+
+// buildGroups groups rows of a board view. Uses results of per-group
+// reducers if Notion returned them (they have the order of cards in a
+// board) and groups rows locally if it didn't
+func buildGroups(tv *TableView, res *QueryCollectionResponse) []*TableGroup {
+	cv := tv.CollectionView
+	idToRow := map[string]*TableRow{}
+	for _, r := range tv.Rows {
+		idToRow[r.Page.ID] = r
+	}
+	var groupResults map[string]*CollectionGroupResults
+	if res.Result.ReducerResults != nil {
+		groupResults = res.Result.ReducerResults.GroupResults
+	}
+
+	var groups []*TableGroup
+	for _, bg := range cv.boardGroups() {
+		g := &TableGroup{
+			Property: bg.Property,
+			Value:    bg.Value,
+			Hidden:   bg.Hidden,
+		}
+		if gr := groupResults[groupReducerName(bg.Value)]; gr != nil {
+			for _, id := range gr.BlockIds {
+				if r := idToRow[id]; r != nil {
+					g.Rows = append(g.Rows, r)
+				}
+			}
+		} else {
+			for _, r := range tv.Rows {
+				if groupContains(r.Value(bg.Property), bg.Value) {
+					g.Rows = append(g.Rows, r)
+				}
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
@@ -0,0 +1,94 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestBoardView(t *testing.T) {
+	cvJSON := `{
+	"id": "cv",
+	"type": "board",
+	"format": {
+		"table_properties": [{"property": "title", "visible": true}, {"property": "st", "visible": true}],
+		"board_properties": [{"property": "title", "visible": true}],
+		"board_cover": {"type": "property", "property": "img"},
+		"board_groups2": [
+			{"property": "st", "value": {"type": "select"}, "hidden": false},
+			{"property": "st", "value": {"type": "select", "value": "Todo"}, "hidden": false},
+			{"property": "st", "value": {"type": "select", "value": "Done"}, "hidden": true}
+		]
+	},
+	"query2": {"group_by": "st"}
+}`
+	var cv CollectionView
+	assert.NoError(t, json.Unmarshal([]byte(cvJSON), &cv))
+	assert.Equal(t, "st", cv.GroupBy())
+	assert.Equal(t, "img", cv.Cover().Property)
+	assert.Equal(t, "", cv.DateProperty())
+	assert.Equal(t, 1, len(cv.Format.Properties(cv.Type)))
+	assert.Equal(t, 2, len(cv.Format.Properties(CollectionViewTypeTable)))
+
+	lr := MakeViewLoaderReducer(&cv)
+	assert.Equal(t, 4, len(lr.Reducers))
+	r := lr.Reducers["results:select:Todo"].(*ReducerCollectionGroupResults)
+	assert.Equal(t, "st", r.Filter["property"])
+	r = withResultsLimit(lr, 7).Reducers["results:select:uncategorized"].(*ReducerCollectionGroupResults)
+	assert.Equal(t, 7, r.Limit)
+	assert.Equal(t, FilterIsEmpty, r.Filter["filter"].(map[string]interface{})["operator"])
+
+	// Notion returned rows only for "Todo" group, in board order
+	rspJSON := `{
+	"recordMap": {"block": {
+		"r1": {"value": {"id": "r1", "properties": {"title": [["one"]], "st": [["Todo"]]}}},
+		"r2": {"value": {"id": "r2", "properties": {"title": [["two"]], "st": [["Done"]]}}},
+		"r3": {"value": {"id": "r3", "properties": {"title": [["three"]]}}},
+		"r4": {"value": {"id": "r4", "properties": {"title": [["four"]], "st": [["Todo"]]}}}
+	}},
+	"result": {"type": "reducer", "reducerResults": {
+		"collection_group_results": {"type": "results", "blockIds": ["r1", "r2", "r3", "r4"], "total": 4},
+		"results:select:Todo": {"type": "results", "blockIds": ["r4", "r1"], "total": 2}
+	}}
+}`
+	var rsp QueryCollectionResponse
+	assert.NoError(t, json.Unmarshal([]byte(rspJSON), &rsp))
+	assert.NoError(t, ParseRecordMap(rsp.RecordMap))
+	assert.Equal(t, 4, len(rsp.GroupResults().BlockIds))
+	assert.Equal(t, 1, len(rsp.Result.ReducerResults.GroupResults))
+	n, total, more := rsp.resultsProgress()
+	assert.Equal(t, 6, n)
+	assert.Equal(t, 4, total)
+	assert.False(t, more)
+
+	tv := &TableView{
+		Page:           &Page{ID: "page"},
+		CollectionView: &cv,
+		Collection: &Collection{
+			Schema: map[string]*ColumnSchema{
+				"title": {Name: "Name", Type: ColumnTypeTitle},
+				"st":    {Name: "Status", Type: ColumnTypeSelect},
+			},
+		},
+	}
+	c := &Client{}
+	assert.NoError(t, c.buildTableView(tv, &rsp))
+	assert.Equal(t, 1, tv.ColumnCount())
+	assert.Equal(t, 4, tv.RowCount())
+	assert.Equal(t, 3, len(tv.Groups))
+
+	rowIDs := func(g *TableGroup) []string {
+		var res []string
+		for _, r := range g.Rows {
+			res = append(res, r.Page.ID)
+		}
+		return res
+	}
+	assert.Equal(t, "", tv.Groups[0].Value.String())
+	assert.Equal(t, []string{"r3"}, rowIDs(tv.Groups[0]))
+	assert.Equal(t, "Todo", tv.Groups[1].Value.String())
+	assert.Equal(t, []string{"r4", "r1"}, rowIDs(tv.Groups[1]))
+	assert.True(t, tv.Groups[2].Hidden)
+	assert.Equal(t, []string{"r2"}, rowIDs(tv.Groups[2]))
+}
@@ -2,6 +2,7 @@ package notionapi
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,11 +21,22 @@ type DownloadFileResponse struct {
 
 // DownloadURL downloads a given url with possibly authenticated client
 func (c *Client) DownloadURL(uri string) (*DownloadFileResponse, error) {
-	req, err := http.NewRequest("GET", uri, nil)
+	return c.DownloadURLCtx(context.Background(), uri)
+}
+
+// DownloadURLCtx is like DownloadURL but takes a context
+func (c *Client) DownloadURLCtx(ctx context.Context, uri string) (rsp *DownloadFileResponse, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
 	if err != nil {
 		//fmt.Printf("DownloadURL: NewRequest() for '%s' failed with '%s'\n", uri, err)
 		return nil, err
 	}
+	ev, timeStart := c.startRequest(ctx, endpointDownload, "GET", uri, 0, 0)
+	status := 0
+	var nDownloaded int64
+	defer func() {
+		c.finishRequest(ev, timeStart, status, nDownloaded, err)
+	}()
 	if c.AuthToken != "" {
 		req.Header.Set("cookie", fmt.Sprintf("token_v2=%v", c.AuthToken))
 	}
@@ -35,16 +47,17 @@ func (c *Client) DownloadURL(uri string) (*DownloadFileResponse, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	status = resp.StatusCode
 	if resp.StatusCode >= 400 {
 		//fmt.Printf("DownloadFile: httpClient.Do() for '%s' failed with '%s'\n", uri, resp.Status)
 		return nil, fmt.Errorf("http GET '%s' failed with status %s", uri, resp.Status)
 	}
 	var buf bytes.Buffer
-	_, err = io.Copy(&buf, resp.Body)
+	nDownloaded, err = io.Copy(&buf, resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	rsp := &DownloadFileResponse{
+	rsp = &DownloadFileResponse{
 		Data:   buf.Bytes(),
 		Header: resp.Header,
 	}
@@ -104,15 +117,20 @@ func maybeProxyImageURL(uri string, block *Block) string {
 // by a block with a given id and of a given block with a given
 // parent table (data present in Block)
 func (c *Client) DownloadFile(uri string, block *Block) (*DownloadFileResponse, error) {
+	return c.DownloadFileCtx(context.Background(), uri, block)
+}
+
+// DownloadFileCtx is like DownloadFile but takes a context
+func (c *Client) DownloadFileCtx(ctx context.Context, uri string, block *Block) (*DownloadFileResponse, error) {
 	// first try downloading proxied url
 	uri2 := maybeProxyImageURL(uri, block)
-	res, err := c.DownloadURL(uri2)
+	res, err := c.DownloadURLCtx(ctx, uri2)
 	if err != nil && uri2 != uri {
 		// otherwise just try your luck with original URL
-		res, err = c.DownloadURL(uri)
+		res, err = c.DownloadURLCtx(ctx, uri)
 	}
-	if err != nil {
-		rsp, err2 := c.GetSignedURLs([]string{uri}, block)
+	if err != nil && ctx.Err() == nil {
+		rsp, err2 := c.GetSignedURLsCtx(ctx, []string{uri}, block)
 		if err2 != nil {
 			return nil, err
 		}
@@ -120,7 +138,7 @@ func (c *Client) DownloadFile(uri string, block *Block) (*DownloadFileResponse,
 			return nil, err
 		}
 		uri3 := rsp.SignedURLS[0]
-		res, err = c.DownloadURL(uri3)
+		res, err = c.DownloadURLCtx(ctx, uri3)
 	}
 	return res, err
 }
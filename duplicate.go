@@ -0,0 +1,564 @@
+package notionapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DuplicateOptions describes how Client.DuplicateBlock copies blocks
+type DuplicateOptions struct {
+	// id of the user making the copy, for created_by and last_edited_by
+	UserID string
+	// if true, sub-pages are not copied and rows of collections are
+	// copied without their content. By default they are copied too
+	Shallow bool
+	// if true, discussions and comments of blocks are copied
+	KeepDiscussions bool
+	// maximum number of operations in a single request,
+	// DefaultMaxTransactionOps if 0
+	MaxOps int
+}
+
+// duplicator creates operations that copy blocks and records they own
+type duplicator struct {
+	opts    *DuplicateOptions
+	spaceID string
+	now     int64
+	ops     []*Operation
+	// ids of copies of records of all pages we copy, so that references
+	// between pages point to copies
+	ids map[string]string
+	// ids of blocks downloaded as pages, to guard against cycles
+	seen map[string]bool
+
+	// downloads a page to copy
+	downloadPage func(ctx context.Context, id string) (*Page, error)
+	// copies file uri of block b, returns id and url of the copy
+	copyFile func(ctx context.Context, b *Block, uri string) (string, string, error)
+}
+
+// pageCopy is a page to copy as block newID of parentID
+type pageCopy struct {
+	page        *Page
+	srcID       string
+	parentID    string
+	parentTable string
+	// blocks of the page, parents before children
+	blocks []*Block
+}
+
+// subPage is a page to duplicate after blocks of the page containing it
+type subPage struct {
+	srcID       string
+	parentID    string
+	parentTable string
+}
+
+// rewriteID returns id of a copy of a record with a given id, id if we
+// don't copy it
+func rewriteID(id string, ids map[string]string) string {
+	if newID, ok := ids[id]; ok {
+		return newID
+	}
+	return id
+}
+
+// rewriteIDList rewrites ids in a list of ids
+func rewriteIDList(v interface{}, ids map[string]string) interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+	for i, el := range list {
+		if s, ok := el.(string); ok {
+			list[i] = rewriteID(s, ids)
+		}
+	}
+	return list
+}
+
+// rewriteMentions rewrites ids of pages in ["p", id] attributes of text
+// spans
+func rewriteMentions(v interface{}, ids map[string]string) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	if len(list) == 2 && list[0] == AttrPage {
+		if id, ok := list[1].(string); ok {
+			list[1] = rewriteID(id, ids)
+			return
+		}
+	}
+	for _, el := range list {
+		rewriteMentions(el, ids)
+	}
+}
+
+// rewriteIDs updates fields of a record that refer to other records
+// to point to copies: parent_id, collection_id, content, view_ids,
+// discussion, comments, page_sort, format.collection_pointer and
+// mentions of pages (["p", id]) in properties and texts
+func rewriteIDs(rec map[string]interface{}, ids map[string]string) map[string]interface{} {
+	for _, k := range []string{"parent_id", "collection_id"} {
+		if id, ok := rec[k].(string); ok {
+			rec[k] = rewriteID(id, ids)
+		}
+	}
+	for _, k := range []string{"content", "view_ids", "discussion", "comments", "page_sort"} {
+		if v, ok := rec[k]; ok {
+			rec[k] = rewriteIDList(v, ids)
+		}
+	}
+	if format, ok := rec["format"].(map[string]interface{}); ok {
+		if v, ok := format["page_sort"]; ok {
+			format["page_sort"] = rewriteIDList(v, ids)
+		}
+		if ptr, ok := format["collection_pointer"].(map[string]interface{}); ok {
+			if id, ok := ptr["id"].(string); ok {
+				ptr["id"] = rewriteID(id, ids)
+			}
+		}
+	}
+	if props, ok := rec["properties"].(map[string]interface{}); ok {
+		for _, v := range props {
+			rewriteMentions(v, ids)
+		}
+	}
+	for _, k := range []string{"text", "name", "description"} {
+		rewriteMentions(rec[k], ids)
+	}
+	return rec
+}
+
+// copyRecord returns a copy of raw json of a record with ids rewritten
+func (d *duplicator) copyRecord(raw map[string]interface{}, id string) (map[string]interface{}, error) {
+	v, err := toJSONValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("record '%s' is not an object", id)
+	}
+	// content can refer to blocks we don't copy, like sub-pages
+	// when making a shallow copy
+	if content, ok := rec["content"].([]interface{}); ok {
+		var res []interface{}
+		for _, childID := range content {
+			if s, ok := childID.(string); ok && d.ids[s] != "" {
+				res = append(res, s)
+			}
+		}
+		if len(res) > 0 {
+			rec["content"] = res
+		} else {
+			delete(rec, "content")
+		}
+	}
+	rec = rewriteIDs(rec, d.ids)
+	rec["id"] = d.ids[id]
+	rec["version"] = 1
+	rec["alive"] = true
+	if _, ok := rec["space_id"]; ok {
+		rec["space_id"] = d.spaceID
+	}
+	return rec, nil
+}
+
+// copyBlockRecord is like copyRecord but also marks the block as created
+// now by the user and copies its files
+func (d *duplicator) copyBlockRecord(ctx context.Context, b *Block) (map[string]interface{}, error) {
+	rec, err := d.copyRecord(b.RawJSON, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	rec["copied_from"] = b.ID
+	rec["space_id"] = d.spaceID
+	rec["created_by"] = d.opts.UserID
+	rec["created_time"] = d.now
+	rec["last_edited_by"] = d.opts.UserID
+	rec["last_edited_time"] = d.now
+	for _, k := range []string{"created_by_id", "last_edited_by_id"} {
+		if _, ok := rec[k]; ok {
+			rec[k] = d.opts.UserID
+		}
+	}
+	// permissions are not inherited by copies
+	delete(rec, "permissions")
+	if !d.opts.KeepDiscussions {
+		delete(rec, "discussion")
+	}
+	if err = d.copyFiles(ctx, b, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// fileIDFromURL returns id of a file uploaded to Notion from its url,
+// "" if it's not a url of an uploaded file
+func fileIDFromURL(uri string) string {
+	if !strings.HasPrefix(uri, s3FileURLPrefix) {
+		return ""
+	}
+	return strings.Split(uri[len(s3FileURLPrefix):], "/")[0]
+}
+
+// copyFiles copies files of block b so that the copy doesn't share them
+// with the original. Updates file_ids of the copy rec and urls of files
+// in its source property, display_source, page_cover and page_icon
+func (d *duplicator) copyFiles(ctx context.Context, b *Block, rec map[string]interface{}) error {
+	if len(b.FileIDs) == 0 {
+		return nil
+	}
+	props, _ := rec["properties"].(map[string]interface{})
+	format, _ := rec["format"].(map[string]interface{})
+	var urls []string
+	if props != nil {
+		if spans, err := ParseTextSpans(props["source"]); err == nil {
+			urls = append(urls, TextSpansToString(spans))
+		}
+	}
+	for _, k := range []string{"display_source", "page_cover", "page_icon"} {
+		if s, ok := format[k].(string); ok {
+			urls = append(urls, s)
+		}
+	}
+
+	var fileIDs []string
+	newURLs := map[string]string{}
+	for _, fileID := range b.FileIDs {
+		uri := ""
+		for _, s := range urls {
+			if fileIDFromURL(s) == fileID {
+				uri = s
+				break
+			}
+		}
+		if uri == "" {
+			return fmt.Errorf("can't find url of file '%s' of block '%s'", fileID, b.ID)
+		}
+		newID, newURL, err := d.copyFile(ctx, b, uri)
+		if err != nil {
+			return fmt.Errorf("copying file '%s' of block '%s' failed: %w", fileID, b.ID, err)
+		}
+		fileIDs = append(fileIDs, newID)
+		newURLs[uri] = newURL
+	}
+	rec["file_ids"] = fileIDs
+	if props != nil {
+		if spans, err := ParseTextSpans(props["source"]); err == nil {
+			if newURL, ok := newURLs[TextSpansToString(spans)]; ok {
+				props["source"] = textProperty(newURL)
+			}
+		}
+	}
+	for _, k := range []string{"display_source", "page_cover", "page_icon"} {
+		if s, ok := format[k].(string); ok && newURLs[s] != "" {
+			format[k] = newURLs[s]
+		}
+	}
+	return nil
+}
+
+// copyFile downloads file uri of block b and uploads it again. Returns id
+// and url of the new file
+func (c *Client) copyFile(ctx context.Context, b *Block, uri string) (string, string, error) {
+	rsp, err := c.DownloadFileCtx(ctx, uri, b)
+	if err != nil {
+		return "", "", err
+	}
+	contentType := rsp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(rsp.Data)
+	}
+	name := path.Base(uri)
+	if u, err := url.Parse(uri); err == nil {
+		name = path.Base(u.Path)
+	}
+	return c.uploadData(ctx, name, contentType, bytes.NewReader(rsp.Data), int64(len(rsp.Data)))
+}
+
+func (d *duplicator) set(table string, rec map[string]interface{}) {
+	d.ops = append(d.ops, &Operation{
+		ID:      rec["id"].(string),
+		Table:   table,
+		Path:    []string{},
+		Command: CommandSet,
+		Args:    rec,
+	})
+}
+
+// collectionOf returns a collection owned by collection view block b,
+// nil for linked databases
+func collectionOf(p *Page, b *Block) *Collection {
+	id := b.CollectionID
+	if id == "" {
+		id = b.Format.CollectionPointer.ID
+	}
+	coll := p.idToCollection[id]
+	if coll == nil || ToDashID(coll.ParentID) != b.ID {
+		return nil
+	}
+	return coll
+}
+
+// rowsOf returns rows of a collection from all views of block b
+func rowsOf(b *Block) []*Block {
+	var res []*Block
+	seen := map[string]bool{}
+	for _, tv := range b.TableViews {
+		for _, row := range tv.Rows {
+			if row.Page != nil && !seen[row.Page.ID] {
+				seen[row.Page.ID] = true
+				res = append(res, row.Page)
+			}
+		}
+	}
+	return res
+}
+
+// copiedAsPage returns true if a row is copied as a page, with its
+// content, instead of with the blocks of the page containing it
+func (d *duplicator) copiedAsPage(row *Block) bool {
+	return len(row.ContentIDs) > 0 && !d.opts.Shallow
+}
+
+// assign sets id of a copy of a record with a given id
+func (d *duplicator) assign(id string) {
+	if d.ids[id] == "" {
+		d.ids[id] = uuid.New().String()
+	}
+}
+
+// collect downloads page srcID and, recursively, its sub-pages. It
+// assigns ids of copies of all records we copy, so that records can
+// refer to copies from other pages
+func (d *duplicator) collect(ctx context.Context, sp *subPage) ([]*pageCopy, error) {
+	srcID := sp.srcID
+	if d.seen[srcID] {
+		return nil, fmt.Errorf("block '%s' contains itself", srcID)
+	}
+	d.seen[srcID] = true
+	p, err := d.downloadPage(ctx, srcID)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &pageCopy{
+		page:        p,
+		srcID:       srcID,
+		parentID:    sp.parentID,
+		parentTable: sp.parentTable,
+	}
+	var subPages []*subPage
+	p.ForEachBlock(func(b *Block) {
+		d.assign(b.ID)
+		pc.blocks = append(pc.blocks, b)
+		for _, child := range b.Content {
+			// ForEachBlock doesn't visit sub-pages
+			if child == nil || !isPageBlock(child) || child.ParentID != b.ID || d.opts.Shallow {
+				continue
+			}
+			d.assign(child.ID)
+			subPages = append(subPages, &subPage{child.ID, b.ID, TableBlock})
+		}
+		for _, id := range b.ViewIDs {
+			if p.idToCollectionView[id] != nil {
+				d.assign(id)
+			}
+		}
+		if coll := collectionOf(p, b); coll != nil {
+			d.assign(coll.ID)
+			for _, row := range rowsOf(b) {
+				d.assign(row.ID)
+				if d.copiedAsPage(row) {
+					subPages = append(subPages, &subPage{row.ID, coll.ID, TableCollection})
+				}
+			}
+		}
+		if !d.opts.KeepDiscussions {
+			return
+		}
+		for _, id := range b.DiscussionIDs {
+			if disc := p.idToDiscussion[id]; disc != nil {
+				d.assign(id)
+				for _, commentID := range disc.Comments {
+					if p.idToComment[commentID] != nil {
+						d.assign(commentID)
+					}
+				}
+			}
+		}
+	})
+
+	res := []*pageCopy{pc}
+	for _, sp := range subPages {
+		pages, err := d.collect(ctx, sp)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, pages...)
+	}
+	return res, nil
+}
+
+// copyPage creates operations that copy blocks of a page, collections
+// they own and their discussions. Parents are created before their
+// children
+func (d *duplicator) copyPage(ctx context.Context, pc *pageCopy) error {
+	p := pc.page
+	for _, b := range pc.blocks {
+		rec, err := d.copyBlockRecord(ctx, b)
+		if err != nil {
+			return err
+		}
+		if b.ID == pc.srcID {
+			rec["parent_id"] = pc.parentID
+			rec["parent_table"] = pc.parentTable
+		}
+		d.set(TableBlock, rec)
+
+		coll := collectionOf(p, b)
+		if coll != nil {
+			rec, err := d.copyRecord(coll.RawJSON, coll.ID)
+			if err != nil {
+				return err
+			}
+			rec["copied_from"] = coll.ID
+			d.set(TableCollection, rec)
+		}
+		for _, id := range b.ViewIDs {
+			if cv := p.idToCollectionView[id]; cv != nil {
+				rec, err := d.copyRecord(cv.RawJSON, id)
+				if err != nil {
+					return err
+				}
+				d.set(TableCollectionView, rec)
+			}
+		}
+		if coll != nil {
+			for _, row := range rowsOf(b) {
+				if d.copiedAsPage(row) {
+					continue
+				}
+				rec, err := d.copyBlockRecord(ctx, row)
+				if err != nil {
+					return err
+				}
+				d.set(TableBlock, rec)
+			}
+		}
+
+		if !d.opts.KeepDiscussions {
+			continue
+		}
+		for _, id := range b.DiscussionIDs {
+			disc := p.idToDiscussion[id]
+			if disc == nil {
+				continue
+			}
+			rec, err := d.copyRecord(disc.RawJSON, id)
+			if err != nil {
+				return err
+			}
+			d.set(TableDiscussion, rec)
+			for _, commentID := range disc.Comments {
+				if comment := p.idToComment[commentID]; comment != nil {
+					rec, err := d.copyRecord(comment.RawJSON, commentID)
+					if err != nil {
+						return err
+					}
+					d.set(TableComment, rec)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// duplicate creates operations that copy block srcID with its content
+// as block newID in parentID
+func (d *duplicator) duplicate(ctx context.Context, srcID string, newID string, parentID string, parentTable string) error {
+	// parent ids of sub-pages are rewritten when they are copied
+	d.ids[srcID] = newID
+	pages, err := d.collect(ctx, &subPage{srcID, parentID, parentTable})
+	if err != nil {
+		return err
+	}
+	for _, pc := range pages {
+		if pc.srcID != srcID {
+			pc.parentID = d.ids[pc.parentID]
+		}
+		if err = d.copyPage(ctx, pc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DuplicateBlock copies block srcID, with its content, to the end of
+// block destParentID and returns id of the copy.
+//
+// Copies get new ids and references between copied blocks (content,
+// parent_id, mentions of pages) are updated to point to copies.
+// Collections owned by collection views are copied with their views
+// and rows. Files of blocks are downloaded and uploaded again, so that
+// copies have their own files.
+//
+// Operations are submitted in as many requests as needed, with parents
+// created before children. The copy is added to destParentID last so
+// that it's not visible if one of the requests fails. opts can be nil
+func (c *Client) DuplicateBlock(srcID string, destParentID string, opts *DuplicateOptions) (string, error) {
+	return c.DuplicateBlockCtx(context.Background(), srcID, destParentID, opts)
+}
+
+// DuplicateBlockCtx is like DuplicateBlock but takes a context
+func (c *Client) DuplicateBlockCtx(ctx context.Context, srcID string, destParentID string, opts *DuplicateOptions) (string, error) {
+	if opts == nil {
+		opts = &DuplicateOptions{}
+	}
+	destParentID = ToDashID(destParentID)
+	blocks, err := c.GetBlockRecordsCtx(ctx, []string{destParentID})
+	if err != nil {
+		return "", err
+	}
+	parent := blocks[0]
+	if parent == nil {
+		return "", fmt.Errorf("block '%s' doesn't exist", destParentID)
+	}
+
+	d := &duplicator{
+		opts:         opts,
+		spaceID:      parent.SpaceID,
+		now:          Now(),
+		ids:          map[string]string{},
+		seen:         map[string]bool{},
+		downloadPage: c.DownloadPageCtx,
+		copyFile:     c.copyFile,
+	}
+	newID := uuid.New().String()
+	if err = d.duplicate(ctx, ToDashID(srcID), newID, parent.ID, TableBlock); err != nil {
+		return "", err
+	}
+
+	tx := NewTransaction(opts.UserID, nil)
+	tx.MaxOps = opts.MaxOps
+	if err = tx.Add(d.ops...); err != nil {
+		return "", err
+	}
+	if err = tx.Add(parent.ListAfterContentOp(newID, "")); err != nil {
+		return "", err
+	}
+	if err = c.CommitTransactionCtx(ctx, tx); err != nil {
+		return "", err
+	}
+	return newID, nil
+}
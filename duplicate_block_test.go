@@ -0,0 +1,98 @@
+package notionapi_test
+
+import (
+	"testing"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+)
+
+func blockTypes(p *notionapi.Page) ([]string, map[string]bool) {
+	var types []string
+	ids := map[string]bool{}
+	p.ForEachBlock(func(b *notionapi.Block) {
+		types = append(types, b.Type)
+		ids[b.ID] = true
+	})
+	return types, ids
+}
+
+func TestDuplicateBlock(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	srcID := "94167af6567043279811dc923edd1f04"
+	destID := "6682351e44bb4f9ca0e149b703265bdb"
+	src, err := client.DownloadPage(srcID)
+	require.NoError(t, err)
+
+	// a sub-page and a mention of it
+	sub := notionapi.NewTextSpec(notionapi.BlockPage, []*notionapi.TextSpan{{Text: "Sub page"}})
+	sub.Content = []*notionapi.BlockSpec{
+		notionapi.NewTextSpec(notionapi.BlockText, []*notionapi.TextSpan{{Text: "inside"}}),
+	}
+	ops := notionapi.NewBlocksOps("user", src.Root(), []*notionapi.BlockSpec{sub})
+	mention := notionapi.NewTextSpec(notionapi.BlockText, []*notionapi.TextSpan{
+		{Text: notionapi.TextSpanSpecial, Attrs: []notionapi.TextAttr{{notionapi.AttrPage, sub.ID}}},
+	})
+	ops = append(ops, notionapi.NewBlocksOps("user", src.Root(), []*notionapi.BlockSpec{mention})...)
+	require.NoError(t, client.SubmitTransaction(ops))
+	src, err = client.DownloadPage(srcID)
+	require.NoError(t, err)
+	srcTypes, srcIDs := blockTypes(src)
+
+	nTx := len(s.Store.Transactions())
+	copyID, err := client.DuplicateBlock(srcID, destID, &notionapi.DuplicateOptions{
+		UserID: "user",
+		MaxOps: 8,
+	})
+	require.NoError(t, err)
+	require.True(t, len(s.Store.Transactions())-nTx > 1)
+
+	dest, err := client.DownloadPage(destID)
+	require.NoError(t, err)
+	contentIDs := dest.Root().ContentIDs
+	require.Equal(t, copyID, contentIDs[len(contentIDs)-1])
+
+	cp, err := client.DownloadPage(copyID)
+	require.NoError(t, err)
+	require.Equal(t, src.Root().Title, cp.Root().Title)
+	require.Equal(t, destID, notionapi.ToNoDashID(cp.Root().ParentID))
+	types, ids := blockTypes(cp)
+	require.Equal(t, srcTypes, types)
+	for id := range ids {
+		require.False(t, srcIDs[id])
+	}
+	require.Equal(t, len(src.TableViews), len(cp.TableViews))
+	for i, tv := range cp.TableViews {
+		require.Equal(t, src.TableViews[i].RowCount(), tv.RowCount())
+		require.True(t, tv.Collection.ID != src.TableViews[i].Collection.ID)
+		require.True(t, tv.CollectionView.ID != src.TableViews[i].CollectionView.ID)
+	}
+
+	// the sub-page is copied and the mention points to the copy
+	root := cp.Root()
+	subCopy := root.Content[len(root.Content)-2]
+	require.Equal(t, notionapi.BlockPage, subCopy.Type)
+	require.True(t, subCopy.ID != sub.ID)
+	span := root.Content[len(root.Content)-1].InlineContent[0]
+	require.Equal(t, subCopy.ID, notionapi.AttrGetPageID(span.Attrs[0]))
+	subPage, err := client.DownloadPage(subCopy.ID)
+	require.NoError(t, err)
+	require.Equal(t, "inside", notionapi.TextSpansToString(subPage.Root().Content[0].InlineContent))
+
+	// shallow copy doesn't copy the sub-page
+	copyID, err = client.DuplicateBlock(srcID, destID, &notionapi.DuplicateOptions{Shallow: true})
+	require.NoError(t, err)
+	cp, err = client.DownloadPage(copyID)
+	require.NoError(t, err)
+	root = cp.Root()
+	require.Equal(t, len(src.Root().ContentIDs)-1, len(root.ContentIDs))
+	span = root.Content[len(root.Content)-1].InlineContent[0]
+	require.Equal(t, sub.ID, notionapi.AttrGetPageID(span.Attrs[0]))
+	require.Equal(t, len(src.TableViews), len(cp.TableViews))
+
+	_, err = client.DuplicateBlock(srcID, "00000000000000000000000000000000", nil)
+	require.True(t, err != nil)
+}
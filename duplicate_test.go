@@ -0,0 +1,259 @@
+package notionapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+const (
+	dupPageID    = "10000000-0000-0000-0000-000000000000"
+	dupTextID    = "20000000-0000-0000-0000-000000000000"
+	dupSubID     = "30000000-0000-0000-0000-000000000000"
+	dupImageID   = "40000000-0000-0000-0000-000000000000"
+	dupInsideID  = "50000000-0000-0000-0000-000000000000"
+	dupDiscID    = "60000000-0000-0000-0000-000000000000"
+	dupCommentID = "70000000-0000-0000-0000-000000000000"
+	dupFileID    = "80000000-0000-0000-0000-000000000000"
+)
+
+func mention(id string) []interface{} {
+	return []interface{}{"‣", []interface{}{[]interface{}{AttrPage, id}}}
+}
+
+// dupTestPage returns a page with a given root from records of tables
+// block, discussion and comment
+func dupTestPage(t *testing.T, rootID string, records map[string][]map[string]interface{}) *Page {
+	p := &Page{
+		ID:                 rootID,
+		idToBlock:          map[string]*Block{},
+		idToCollection:     map[string]*Collection{},
+		idToCollectionView: map[string]*CollectionView{},
+		idToDiscussion:     map[string]*Discussion{},
+		idToComment:        map[string]*Comment{},
+		blocksToSkip:       map[string]struct{}{},
+	}
+	for table, recs := range records {
+		for _, v := range recs {
+			d, err := jsonit.Marshal(v)
+			assert.NoError(t, err)
+			r := &Record{Value: d}
+			assert.NoError(t, parseRecord(table, r))
+			switch table {
+			case TableBlock:
+				r.Block.Page = p
+				p.idToBlock[r.ID] = r.Block
+			case TableDiscussion:
+				p.idToDiscussion[r.ID] = r.Discussion
+			case TableComment:
+				p.idToComment[r.ID] = r.Comment
+			}
+		}
+	}
+	assert.NoError(t, p.resolveBlocks())
+	return p
+}
+
+// dupTestPages returns a page with a text with a discussion, a sub-page
+// and an image, and the sub-page
+func dupTestPages(t *testing.T) map[string]func() *Page {
+	fileURL := s3FileURLPrefix + dupFileID + "/a.png"
+	page := map[string]interface{}{
+		"id": dupPageID, "type": "page", "alive": true, "space_id": "space",
+		"properties": map[string]interface{}{"title": [][]string{{"Template"}}},
+		"content":    []string{dupTextID, dupSubID, dupImageID},
+	}
+	text := map[string]interface{}{
+		"id": dupTextID, "type": "text", "alive": true, "parent_id": dupPageID, "parent_table": "block",
+		"discussion": []string{dupDiscID},
+		"properties": map[string]interface{}{
+			// an id in plain text is not a reference
+			"title": []interface{}{[]interface{}{"see " + dupSubID + " and "}, mention(dupSubID), mention(dupInsideID)},
+		},
+	}
+	sub := map[string]interface{}{
+		"id": dupSubID, "type": "page", "alive": true, "parent_id": dupPageID, "parent_table": "block",
+		"properties": map[string]interface{}{"title": [][]string{{"Sub"}}},
+		"content":    []string{dupInsideID},
+	}
+	image := map[string]interface{}{
+		"id": dupImageID, "type": "image", "alive": true, "parent_id": dupPageID, "parent_table": "block",
+		"file_ids":   []string{dupFileID},
+		"properties": map[string]interface{}{"source": [][]string{{fileURL}}},
+		"format":     map[string]interface{}{"display_source": fileURL},
+	}
+	inside := map[string]interface{}{
+		"id": dupInsideID, "type": "text", "alive": true, "parent_id": dupSubID, "parent_table": "block",
+		"properties": map[string]interface{}{"title": []interface{}{mention(dupPageID)}},
+	}
+	disc := map[string]interface{}{
+		"id": dupDiscID, "parent_id": dupTextID, "parent_table": "block", "comments": []string{dupCommentID},
+	}
+	comment := map[string]interface{}{
+		"id": dupCommentID, "alive": true, "parent_id": dupDiscID, "parent_table": "discussion",
+		"text": []interface{}{[]interface{}{"about "}, mention(dupSubID)},
+	}
+	return map[string]func() *Page{
+		dupPageID: func() *Page {
+			return dupTestPage(t, dupPageID, map[string][]map[string]interface{}{
+				TableBlock:      {page, text, sub, image},
+				TableDiscussion: {disc},
+				TableComment:    {comment},
+			})
+		},
+		dupSubID: func() *Page {
+			return dupTestPage(t, dupSubID, map[string][]map[string]interface{}{
+				TableBlock: {sub, inside},
+			})
+		},
+	}
+}
+
+// duplicateTestPage duplicates the test page and returns created records
+// by table and id of the original
+func duplicateTestPage(t *testing.T, opts *DuplicateOptions) (map[string]map[string]interface{}, error) {
+	pages := dupTestPages(t)
+	d := &duplicator{
+		opts:    opts,
+		spaceID: "space2",
+		now:     1,
+		ids:     map[string]string{},
+		seen:    map[string]bool{},
+		downloadPage: func(ctx context.Context, id string) (*Page, error) {
+			if f := pages[id]; f != nil {
+				return f(), nil
+			}
+			return nil, errors.New("no page")
+		},
+		copyFile: func(ctx context.Context, b *Block, uri string) (string, string, error) {
+			if uri != s3FileURLPrefix+dupFileID+"/a.png" {
+				return "", "", errors.New("unexpected url")
+			}
+			return "new-file", s3FileURLPrefix + "new-file/a.png", nil
+		},
+	}
+	if err := d.duplicate(context.Background(), dupPageID, "copy", "dest", TableBlock); err != nil {
+		return nil, err
+	}
+	res := map[string]map[string]interface{}{}
+	created := map[string]bool{"dest": true}
+	for _, op := range d.ops {
+		assert.NoError(t, ValidateOperation(op))
+		rec := op.Args.(map[string]interface{})
+		// parents are created before their children
+		assert.True(t, created[rec["parent_id"].(string)])
+		created[op.ID] = true
+		for src, id := range d.ids {
+			if id == op.ID {
+				res[op.Table+":"+src] = rec
+			}
+		}
+	}
+	return res, nil
+}
+
+func TestDuplicate(t *testing.T) {
+	recs, err := duplicateTestPage(t, &DuplicateOptions{UserID: "user"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(recs))
+	idOf := func(table string, id string) string {
+		return recs[table+":"+id]["id"].(string)
+	}
+
+	page := recs["block:"+dupPageID]
+	assert.Equal(t, "copy", page["id"])
+	assert.Equal(t, "dest", page["parent_id"])
+	assert.Equal(t, "space2", page["space_id"])
+	assert.Equal(t, "user", page["created_by"])
+	assert.Equal(t, []interface{}{idOf("block", dupTextID), idOf("block", dupSubID), idOf("block", dupImageID)}, page["content"])
+
+	// mentions point to copies, even of blocks in sub-pages, text doesn't change
+	text := recs["block:"+dupTextID]
+	spans, err := ParseTextSpans(text["properties"].(map[string]interface{})["title"])
+	assert.NoError(t, err)
+	assert.Equal(t, "see "+dupSubID+" and ", spans[0].Text)
+	assert.Equal(t, idOf("block", dupSubID), AttrGetPageID(spans[1].Attrs[0]))
+	assert.Equal(t, idOf("block", dupInsideID), AttrGetPageID(spans[2].Attrs[0]))
+	assert.Nil(t, text["discussion"])
+
+	// the sub-page and its content are copied, the mention in the
+	// sub-page points to the copy of the page
+	sub := recs["block:"+dupSubID]
+	assert.Equal(t, "copy", sub["parent_id"])
+	inside := recs["block:"+dupInsideID]
+	assert.Equal(t, sub["id"], inside["parent_id"])
+	spans, err = ParseTextSpans(inside["properties"].(map[string]interface{})["title"])
+	assert.NoError(t, err)
+	assert.Equal(t, "copy", AttrGetPageID(spans[0].Attrs[0]))
+
+	// the image has its own file
+	image := recs["block:"+dupImageID]
+	newURL := s3FileURLPrefix + "new-file/a.png"
+	assert.Equal(t, []string{"new-file"}, image["file_ids"])
+	assert.Equal(t, textProperty(newURL), image["properties"].(map[string]interface{})["source"])
+	assert.Equal(t, newURL, image["format"].(map[string]interface{})["display_source"])
+}
+
+func TestDuplicateShallow(t *testing.T) {
+	recs, err := duplicateTestPage(t, &DuplicateOptions{UserID: "user", Shallow: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(recs))
+	assert.Nil(t, recs["block:"+dupSubID])
+	page := recs["block:"+dupPageID]
+	assert.Equal(t, 2, len(page["content"].([]interface{})))
+
+	// mentions of blocks we don't copy point to the originals
+	text := recs["block:"+dupTextID]
+	spans, err := ParseTextSpans(text["properties"].(map[string]interface{})["title"])
+	assert.NoError(t, err)
+	assert.Equal(t, dupSubID, AttrGetPageID(spans[1].Attrs[0]))
+}
+
+func TestDuplicateKeepDiscussions(t *testing.T) {
+	recs, err := duplicateTestPage(t, &DuplicateOptions{UserID: "user", KeepDiscussions: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(recs))
+	text := recs["block:"+dupTextID]
+	disc := recs["discussion:"+dupDiscID]
+	comment := recs["comment:"+dupCommentID]
+	assert.Equal(t, []interface{}{disc["id"]}, text["discussion"])
+	assert.Equal(t, text["id"], disc["parent_id"])
+	assert.Equal(t, []interface{}{comment["id"]}, disc["comments"])
+	assert.Equal(t, disc["id"], comment["parent_id"])
+	spans, err := ParseTextSpans(comment["text"])
+	assert.NoError(t, err)
+	assert.Equal(t, recs["block:"+dupSubID]["id"], AttrGetPageID(spans[1].Attrs[0]))
+}
+
+func TestRewriteIDs(t *testing.T) {
+	ids := map[string]string{"a": "A", "b": "B", "c": "C"}
+	v := map[string]interface{}{
+		"parent_id":     "a",
+		"collection_id": "c",
+		"content":       []interface{}{"b", "d"},
+		"format": map[string]interface{}{
+			"collection_pointer": map[string]interface{}{"id": "c", "table": "collection"},
+		},
+		"properties": map[string]interface{}{
+			"title": []interface{}{[]interface{}{"b"}, []interface{}{"‣", []interface{}{[]interface{}{"p", "b"}}}},
+		},
+		"type":    "a",
+		"version": 3,
+	}
+	exp := map[string]interface{}{
+		"parent_id":     "A",
+		"collection_id": "C",
+		"content":       []interface{}{"B", "d"},
+		"format": map[string]interface{}{
+			"collection_pointer": map[string]interface{}{"id": "C", "table": "collection"},
+		},
+		"properties": map[string]interface{}{
+			"title": []interface{}{[]interface{}{"b"}, []interface{}{"‣", []interface{}{[]interface{}{"p", "B"}}}},
+		},
+		"type":    "a",
+		"version": 3,
+	}
+	assert.Equal(t, exp, rewriteIDs(v, ids))
+}
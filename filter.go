@@ -0,0 +1,658 @@
+package notionapi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// operators of filter groups
+const (
+	FilterAnd = "and"
+	FilterOr  = "or"
+)
+
+// operators of filter conditions
+const (
+	FilterIsEmpty    = "is_empty"
+	FilterIsNotEmpty = "is_not_empty"
+
+	FilterStringIs             = "string_is"
+	FilterStringIsNot          = "string_is_not"
+	FilterStringContains       = "string_contains"
+	FilterStringDoesNotContain = "string_does_not_contain"
+	FilterStringStartsWith     = "string_starts_with"
+	FilterStringEndsWith       = "string_ends_with"
+
+	FilterNumberEquals             = "number_equals"
+	FilterNumberDoesNotEqual       = "number_does_not_equal"
+	FilterNumberGreaterThan        = "number_greater_than"
+	FilterNumberLessThan           = "number_less_than"
+	FilterNumberGreaterThanOrEqual = "number_greater_than_or_equal_to"
+	FilterNumberLessThanOrEqual    = "number_less_than_or_equal_to"
+
+	FilterEnumIs             = "enum_is"
+	FilterEnumIsNot          = "enum_is_not"
+	FilterEnumContains       = "enum_contains"
+	FilterEnumDoesNotContain = "enum_does_not_contain"
+
+	FilterCheckboxIs    = "checkbox_is"
+	FilterCheckboxIsNot = "checkbox_is_not"
+
+	FilterDateIs           = "date_is"
+	FilterDateIsBefore     = "date_is_before"
+	FilterDateIsAfter      = "date_is_after"
+	FilterDateIsOnOrBefore = "date_is_on_or_before"
+	FilterDateIsOnOrAfter  = "date_is_on_or_after"
+	FilterDateIsWithin     = "date_is_within"
+
+	FilterPersonContains       = "person_contains"
+	FilterPersonDoesNotContain = "person_does_not_contain"
+
+	FilterRelationContains       = "relation_contains"
+	FilterRelationDoesNotContain = "relation_does_not_contain"
+)
+
+// FilterValue.Type
+const (
+	FilterValueExact    = "exact"
+	FilterValueRelative = "relative"
+)
+
+// relative dates for date filters
+const (
+	DateToday           = "today"
+	DateTomorrow        = "tomorrow"
+	DateYesterday       = "yesterday"
+	DateOneWeekAgo      = "one_week_ago"
+	DateOneWeekFromNow  = "one_week_from_now"
+	DateOneMonthAgo     = "one_month_ago"
+	DateOneMonthFromNow = "one_month_from_now"
+
+	// for DateFilter.IsWithin
+	DateThePastWeek  = "the_past_week"
+	DateThePastMonth = "the_past_month"
+	DateThePastYear  = "the_past_year"
+	DateTheNextWeek  = "the_next_week"
+	DateTheNextMonth = "the_next_month"
+	DateTheNextYear  = "the_next_year"
+)
+
+// kinds of filters, determine which column types a filter applies to
+const (
+	filterKindAny         = ""
+	filterKindText        = "text"
+	filterKindNumber      = "number"
+	filterKindSelect      = "select"
+	filterKindMultiSelect = "multi_select"
+	filterKindCheckbox    = "checkbox"
+	filterKindDate        = "date"
+	filterKindPerson      = "person"
+	filterKindRelation    = "relation"
+)
+
+// column types that can be filtered with a given kind of filter.
+// formula and rollup columns can be filtered with any kind
+var filterKindColumnTypes = map[string][]string{
+	filterKindText:        {ColumnTypeTitle, ColumnTypeText, ColumnTypeURL, ColumnTypeEmail, ColumnTypePhoneNumber},
+	filterKindNumber:      {ColumnTypeNumber},
+	filterKindSelect:      {ColumnTypeSelect},
+	filterKindMultiSelect: {ColumnTypeMultiSelect},
+	filterKindCheckbox:    {ColumnTypeCheckbox},
+	filterKindDate:        {ColumnTypeDate, ColumnTypeCreatedTime, ColumnTypeLastEditedTime},
+	filterKindPerson:      {ColumnTypePerson, ColumnTypeCreatedBy, ColumnTypeLastEditedBy},
+	filterKindRelation:    {ColumnTypeRelation},
+}
+
+// FilterValue is a value a property is compared with
+type FilterValue struct {
+	// FilterValueExact or FilterValueRelative
+	Type  string
+	Value interface{}
+}
+
+// FilterCondition is a condition for a single property
+type FilterCondition struct {
+	// e.g. FilterEnumIs
+	Operator string
+	// nil for FilterIsEmpty and FilterIsNotEmpty
+	Value *FilterValue
+}
+
+// QueryFilter is a typed version of Query.Filter. It's either a group
+// of filters (Operator is FilterAnd or FilterOr) or a Condition for
+// a Property. Build it with Filter e.g.:
+//
+//	f := Filter.Property("Status").Select().Is("Done").And(
+//		Filter.Property("Due").Date().IsBefore(RelativeDate(DateToday)),
+//	)
+type QueryFilter struct {
+	// for groups
+	Operator string
+	Filters  []*QueryFilter
+
+	// for conditions. Property is a name or id of a column
+	Property  string
+	Condition *FilterCondition
+
+	kind string
+}
+
+// IsGroup returns true if this is a group of filters
+func (f *QueryFilter) IsGroup() bool {
+	return f.Condition == nil
+}
+
+func newFilterGroup(op string, filters []*QueryFilter) *QueryFilter {
+	return &QueryFilter{
+		Operator: op,
+		Filters:  filters,
+	}
+}
+
+func (f *QueryFilter) group(op string, filters []*QueryFilter) *QueryFilter {
+	if f.IsGroup() && f.Operator == op {
+		res := append([]*QueryFilter{}, f.Filters...)
+		return newFilterGroup(op, append(res, filters...))
+	}
+	return newFilterGroup(op, append([]*QueryFilter{f}, filters...))
+}
+
+// And returns a filter that matches if f and all filters match
+func (f *QueryFilter) And(filters ...*QueryFilter) *QueryFilter {
+	return f.group(FilterAnd, filters)
+}
+
+// Or returns a filter that matches if f or any of filters match
+func (f *QueryFilter) Or(filters ...*QueryFilter) *QueryFilter {
+	return f.group(FilterOr, filters)
+}
+
+// FilterBuilder is the starting point for building a QueryFilter.
+// Use Filter variable
+type FilterBuilder struct{}
+
+// Filter is for building QueryFilter
+var Filter FilterBuilder
+
+// And returns a group of filters that matches if all filters match
+func (FilterBuilder) And(filters ...*QueryFilter) *QueryFilter {
+	return newFilterGroup(FilterAnd, filters)
+}
+
+// Or returns a group of filters that matches if any filter matches
+func (FilterBuilder) Or(filters ...*QueryFilter) *QueryFilter {
+	return newFilterGroup(FilterOr, filters)
+}
+
+// Property starts building a filter for a property with a given
+// name or id
+func (FilterBuilder) Property(name string) PropertyFilter {
+	return PropertyFilter{property: name}
+}
+
+// PropertyFilter selects a type of filter for a property
+type PropertyFilter struct {
+	property string
+}
+
+func (p PropertyFilter) cond(kind string, op string, v *FilterValue) *QueryFilter {
+	return &QueryFilter{
+		Property: p.property,
+		Condition: &FilterCondition{
+			Operator: op,
+			Value:    v,
+		},
+		kind: kind,
+	}
+}
+
+func exactValue(v interface{}) *FilterValue {
+	return &FilterValue{Type: FilterValueExact, Value: v}
+}
+
+// IsEmpty matches rows where property is empty
+func (p PropertyFilter) IsEmpty() *QueryFilter {
+	return p.cond(filterKindAny, FilterIsEmpty, nil)
+}
+
+// IsNotEmpty matches rows where property is not empty
+func (p PropertyFilter) IsNotEmpty() *QueryFilter {
+	return p.cond(filterKindAny, FilterIsNotEmpty, nil)
+}
+
+// Text is for ColumnTypeTitle, ColumnTypeText, ColumnTypeURL etc.
+func (p PropertyFilter) Text() TextFilter {
+	return TextFilter{p}
+}
+
+// Number is for ColumnTypeNumber
+func (p PropertyFilter) Number() NumberFilter {
+	return NumberFilter{p}
+}
+
+// Select is for ColumnTypeSelect
+func (p PropertyFilter) Select() SelectFilter {
+	return SelectFilter{p}
+}
+
+// MultiSelect is for ColumnTypeMultiSelect
+func (p PropertyFilter) MultiSelect() MultiSelectFilter {
+	return MultiSelectFilter{p}
+}
+
+// Checkbox is for ColumnTypeCheckbox
+func (p PropertyFilter) Checkbox() CheckboxFilter {
+	return CheckboxFilter{p}
+}
+
+// Date is for ColumnTypeDate, ColumnTypeCreatedTime and ColumnTypeLastEditedTime
+func (p PropertyFilter) Date() DateFilter {
+	return DateFilter{p}
+}
+
+// Person is for ColumnTypePerson, ColumnTypeCreatedBy and ColumnTypeLastEditedBy
+func (p PropertyFilter) Person() PersonFilter {
+	return PersonFilter{p}
+}
+
+// Relation is for ColumnTypeRelation
+func (p PropertyFilter) Relation() RelationFilter {
+	return RelationFilter{p}
+}
+
+// TextFilter builds filters for text properties
+type TextFilter struct{ p PropertyFilter }
+
+func (f TextFilter) cond(op string, s string) *QueryFilter {
+	return f.p.cond(filterKindText, op, exactValue(s))
+}
+
+// Is matches if property is s
+func (f TextFilter) Is(s string) *QueryFilter { return f.cond(FilterStringIs, s) }
+
+// IsNot matches if property is not s
+func (f TextFilter) IsNot(s string) *QueryFilter { return f.cond(FilterStringIsNot, s) }
+
+// Contains matches if property contains s
+func (f TextFilter) Contains(s string) *QueryFilter { return f.cond(FilterStringContains, s) }
+
+// DoesNotContain matches if property doesn't contain s
+func (f TextFilter) DoesNotContain(s string) *QueryFilter {
+	return f.cond(FilterStringDoesNotContain, s)
+}
+
+// StartsWith matches if property starts with s
+func (f TextFilter) StartsWith(s string) *QueryFilter { return f.cond(FilterStringStartsWith, s) }
+
+// EndsWith matches if property ends with s
+func (f TextFilter) EndsWith(s string) *QueryFilter { return f.cond(FilterStringEndsWith, s) }
+
+// NumberFilter builds filters for number properties
+type NumberFilter struct{ p PropertyFilter }
+
+func (f NumberFilter) cond(op string, n float64) *QueryFilter {
+	return f.p.cond(filterKindNumber, op, exactValue(n))
+}
+
+// Equals matches if property == n
+func (f NumberFilter) Equals(n float64) *QueryFilter { return f.cond(FilterNumberEquals, n) }
+
+// DoesNotEqual matches if property != n
+func (f NumberFilter) DoesNotEqual(n float64) *QueryFilter {
+	return f.cond(FilterNumberDoesNotEqual, n)
+}
+
+// GreaterThan matches if property > n
+func (f NumberFilter) GreaterThan(n float64) *QueryFilter {
+	return f.cond(FilterNumberGreaterThan, n)
+}
+
+// LessThan matches if property < n
+func (f NumberFilter) LessThan(n float64) *QueryFilter { return f.cond(FilterNumberLessThan, n) }
+
+// GreaterThanOrEqual matches if property >= n
+func (f NumberFilter) GreaterThanOrEqual(n float64) *QueryFilter {
+	return f.cond(FilterNumberGreaterThanOrEqual, n)
+}
+
+// LessThanOrEqual matches if property <= n
+func (f NumberFilter) LessThanOrEqual(n float64) *QueryFilter {
+	return f.cond(FilterNumberLessThanOrEqual, n)
+}
+
+// SelectFilter builds filters for select properties
+type SelectFilter struct{ p PropertyFilter }
+
+// Is matches if selected option is v
+func (f SelectFilter) Is(v string) *QueryFilter {
+	return f.p.cond(filterKindSelect, FilterEnumIs, exactValue(v))
+}
+
+// IsNot matches if selected option is not v
+func (f SelectFilter) IsNot(v string) *QueryFilter {
+	return f.p.cond(filterKindSelect, FilterEnumIsNot, exactValue(v))
+}
+
+// MultiSelectFilter builds filters for multi select properties
+type MultiSelectFilter struct{ p PropertyFilter }
+
+// Contains matches if v is one of selected options
+func (f MultiSelectFilter) Contains(v string) *QueryFilter {
+	return f.p.cond(filterKindMultiSelect, FilterEnumContains, exactValue(v))
+}
+
+// DoesNotContain matches if v is not one of selected options
+func (f MultiSelectFilter) DoesNotContain(v string) *QueryFilter {
+	return f.p.cond(filterKindMultiSelect, FilterEnumDoesNotContain, exactValue(v))
+}
+
+// CheckboxFilter builds filters for checkbox properties
+type CheckboxFilter struct{ p PropertyFilter }
+
+// Is matches if checkbox is v
+func (f CheckboxFilter) Is(v bool) *QueryFilter {
+	return f.p.cond(filterKindCheckbox, FilterCheckboxIs, exactValue(v))
+}
+
+// IsNot matches if checkbox is not v
+func (f CheckboxFilter) IsNot(v bool) *QueryFilter {
+	return f.p.cond(filterKindCheckbox, FilterCheckboxIsNot, exactValue(v))
+}
+
+// ExactDate returns a value for DateFilter for a given day
+func ExactDate(t time.Time) *FilterValue {
+	return exactValue(map[string]interface{}{
+		"type":       "date",
+		"start_date": t.Format("2006-01-02"),
+	})
+}
+
+// RelativeDate returns a value for DateFilter relative to now,
+// e.g. DateToday
+func RelativeDate(rel string) *FilterValue {
+	return &FilterValue{Type: FilterValueRelative, Value: rel}
+}
+
+// DateFilter builds filters for date properties. Values are
+// created with ExactDate or RelativeDate
+type DateFilter struct{ p PropertyFilter }
+
+func (f DateFilter) cond(op string, v *FilterValue) *QueryFilter {
+	return f.p.cond(filterKindDate, op, v)
+}
+
+// Is matches if date is v
+func (f DateFilter) Is(v *FilterValue) *QueryFilter { return f.cond(FilterDateIs, v) }
+
+// IsBefore matches if date is before v
+func (f DateFilter) IsBefore(v *FilterValue) *QueryFilter { return f.cond(FilterDateIsBefore, v) }
+
+// IsAfter matches if date is after v
+func (f DateFilter) IsAfter(v *FilterValue) *QueryFilter { return f.cond(FilterDateIsAfter, v) }
+
+// IsOnOrBefore matches if date is v or before v
+func (f DateFilter) IsOnOrBefore(v *FilterValue) *QueryFilter {
+	return f.cond(FilterDateIsOnOrBefore, v)
+}
+
+// IsOnOrAfter matches if date is v or after v
+func (f DateFilter) IsOnOrAfter(v *FilterValue) *QueryFilter {
+	return f.cond(FilterDateIsOnOrAfter, v)
+}
+
+// IsWithin matches if date is within a relative range e.g. DateThePastWeek
+func (f DateFilter) IsWithin(rel string) *QueryFilter {
+	return f.cond(FilterDateIsWithin, RelativeDate(rel))
+}
+
+// PersonFilter builds filters for person properties
+type PersonFilter struct{ p PropertyFilter }
+
+func userValue(userID string) *FilterValue {
+	return exactValue(map[string]interface{}{
+		"table": TableNotionUser,
+		"id":    userID,
+	})
+}
+
+// Contains matches if user with userID is one of people
+func (f PersonFilter) Contains(userID string) *QueryFilter {
+	return f.p.cond(filterKindPerson, FilterPersonContains, userValue(userID))
+}
+
+// DoesNotContain matches if user with userID is not one of people
+func (f PersonFilter) DoesNotContain(userID string) *QueryFilter {
+	return f.p.cond(filterKindPerson, FilterPersonDoesNotContain, userValue(userID))
+}
+
+// ContainsMe matches if the current user is one of people
+func (f PersonFilter) ContainsMe() *QueryFilter {
+	v := &FilterValue{Type: FilterValueRelative, Value: "me"}
+	return f.p.cond(filterKindPerson, FilterPersonContains, v)
+}
+
+// RelationFilter builds filters for relation properties
+type RelationFilter struct{ p PropertyFilter }
+
+func blockValue(blockID string) *FilterValue {
+	return exactValue(map[string]interface{}{
+		"table": TableBlock,
+		"id":    blockID,
+	})
+}
+
+// Contains matches if page with blockID is one of related pages
+func (f RelationFilter) Contains(blockID string) *QueryFilter {
+	return f.p.cond(filterKindRelation, FilterRelationContains, blockValue(blockID))
+}
+
+// DoesNotContain matches if page with blockID is not one of related pages
+func (f RelationFilter) DoesNotContain(blockID string) *QueryFilter {
+	return f.p.cond(filterKindRelation, FilterRelationDoesNotContain, blockValue(blockID))
+}
+
+// findColumn finds a column by id or name
+func findColumn(c *Collection, nameOrID string) (string, *ColumnSchema) {
+	if schema, ok := c.Schema[nameOrID]; ok {
+		return nameOrID, schema
+	}
+	for id, schema := range c.Schema {
+		if schema.Name == nameOrID {
+			return id, schema
+		}
+	}
+	return "", nil
+}
+
+func isFilterKindValidForColumn(kind string, columnType string) bool {
+	if kind == filterKindAny || columnType == ColumnTypeFormula || columnType == ColumnTypeRollup {
+		return true
+	}
+	for _, t := range filterKindColumnTypes[kind] {
+		if t == columnType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToMap converts the filter to Notion's format used in Query.Filter.
+// If c is not nil, property names are resolved to ids and we check
+// that the filters match types of columns in c.Schema
+func (f *QueryFilter) ToMap(c *Collection) (map[string]interface{}, error) {
+	if f.IsGroup() {
+		if f.Operator != FilterAnd && f.Operator != FilterOr {
+			return nil, fmt.Errorf("invalid filter group operator '%s'", f.Operator)
+		}
+		filters := []interface{}{}
+		for _, child := range f.Filters {
+			m, err := child.ToMap(c)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, m)
+		}
+		return map[string]interface{}{
+			"operator": f.Operator,
+			"filters":  filters,
+		}, nil
+	}
+
+	property := f.Property
+	if c != nil {
+		id, schema := findColumn(c, f.Property)
+		if schema == nil {
+			return nil, fmt.Errorf("collection '%s' has no property '%s'", c.ID, f.Property)
+		}
+		if !isFilterKindValidForColumn(f.kind, schema.Type) {
+			return nil, fmt.Errorf("filter '%s' can't be used with property '%s' of type '%s'", f.Condition.Operator, f.Property, schema.Type)
+		}
+		property = id
+	}
+	cond := map[string]interface{}{
+		"operator": f.Condition.Operator,
+	}
+	if v := f.Condition.Value; v != nil {
+		cond["value"] = map[string]interface{}{
+			"type":  v.Type,
+			"value": v.Value,
+		}
+	}
+	return map[string]interface{}{
+		"property": property,
+		"filter":   cond,
+	}, nil
+}
+
+// Validate checks that the filter can be used with collection c
+func (f *QueryFilter) Validate(c *Collection) error {
+	_, err := f.ToMap(c)
+	return err
+}
+
+// filterKindFromOperator returns a kind of filter based on its operator
+func filterKindFromOperator(op string) string {
+	switch op {
+	case FilterEnumIs, FilterEnumIsNot:
+		return filterKindSelect
+	case FilterEnumContains, FilterEnumDoesNotContain:
+		return filterKindMultiSelect
+	}
+	prefixes := map[string]string{
+		"string_":   filterKindText,
+		"number_":   filterKindNumber,
+		"checkbox_": filterKindCheckbox,
+		"date_":     filterKindDate,
+		"person_":   filterKindPerson,
+		"relation_": filterKindRelation,
+	}
+	for prefix, kind := range prefixes {
+		if strings.HasPrefix(op, prefix) {
+			return kind
+		}
+	}
+	return filterKindAny
+}
+
+// ParseQueryFilter parses filter in Notion's format (e.g. Query.Filter
+// of a CollectionView) into QueryFilter. Property of conditions is
+// the id of a column
+func ParseQueryFilter(m map[string]interface{}) (*QueryFilter, error) {
+	if m == nil {
+		return nil, nil
+	}
+	if filters, ok := m["filters"]; ok {
+		op, _ := m["operator"].(string)
+		if op != FilterAnd && op != FilterOr {
+			return nil, fmt.Errorf("invalid filter group operator '%v'", m["operator"])
+		}
+		res := newFilterGroup(op, nil)
+		a, ok := filters.([]interface{})
+		if !ok && filters != nil {
+			return nil, fmt.Errorf("'filters' is %T, not an array", filters)
+		}
+		for _, v := range a {
+			child, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("filter is %T, not an object", v)
+			}
+			f, err := ParseQueryFilter(child)
+			if err != nil {
+				return nil, err
+			}
+			res.Filters = append(res.Filters, f)
+		}
+		return res, nil
+	}
+
+	property, _ := m["property"].(string)
+	cond, ok := m["filter"].(map[string]interface{})
+	if !ok || property == "" {
+		return nil, fmt.Errorf("filter must have 'property' and 'filter'")
+	}
+	op, _ := cond["operator"].(string)
+	if op == "" {
+		return nil, fmt.Errorf("filter for property '%s' has no operator", property)
+	}
+	res := &QueryFilter{
+		Property: property,
+		Condition: &FilterCondition{
+			Operator: op,
+		},
+		kind: filterKindFromOperator(op),
+	}
+	if v, ok := cond["value"].(map[string]interface{}); ok {
+		typ, _ := v["type"].(string)
+		res.Condition.Value = &FilterValue{
+			Type:  typ,
+			Value: v["value"],
+		}
+	}
+	return res, nil
+}
+
+// TypedFilter returns Filter parsed as QueryFilter, nil if there's no filter
+func (q *Query) TypedFilter() (*QueryFilter, error) {
+	return ParseQueryFilter(q.Filter)
+}
+
+// SortAscending returns a sort by a property (name or id) in ascending order
+func SortAscending(property string) QuerySort {
+	return QuerySort{Property: property, Direction: "ascending"}
+}
+
+// SortDescending returns a sort by a property (name or id) in descending order
+func SortDescending(property string) QuerySort {
+	return QuerySort{Property: property, Direction: "descending"}
+}
+
+// NewQuery builds a Query for QueryCollection. Property names in filter
+// and sorts are resolved to ids and validated against c.Schema.
+// filter can be nil
+func NewQuery(c *Collection, filter *QueryFilter, searchQuery string, sorts ...QuerySort) (*Query, error) {
+	q := &Query{
+		SearchQuery: searchQuery,
+	}
+	if filter != nil {
+		m, err := filter.ToMap(c)
+		if err != nil {
+			return nil, err
+		}
+		q.Filter = m
+	}
+	for _, s := range sorts {
+		if c != nil {
+			id, schema := findColumn(c, s.Property)
+			if schema == nil {
+				return nil, fmt.Errorf("collection '%s' has no property '%s'", c.ID, s.Property)
+			}
+			s.Property = id
+		}
+		if s.Direction != "ascending" && s.Direction != "descending" {
+			return nil, fmt.Errorf("invalid sort direction '%s'", s.Direction)
+		}
+		q.Sort = append(q.Sort, s)
+	}
+	return q, nil
+}
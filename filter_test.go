@@ -0,0 +1,116 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func newFilterTestCollection() *Collection {
+	return &Collection{
+		ID: "c1",
+		Schema: map[string]*ColumnSchema{
+			"title": {Name: "Name", Type: ColumnTypeTitle},
+			"8#gA":  {Name: "Count", Type: ColumnTypeNumber},
+			"a:bc":  {Name: "Status", Type: ColumnTypeSelect},
+			"d;ef":  {Name: "Due", Type: ColumnTypeDate},
+			"g=hi":  {Name: "Tags", Type: ColumnTypeMultiSelect},
+		},
+	}
+}
+
+func toJSONString(t *testing.T, v interface{}) string {
+	d, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(d)
+}
+
+func TestFilterBuilder(t *testing.T) {
+	c := newFilterTestCollection()
+	f := Filter.Property("Status").Select().Is("Done").And(
+		Filter.Or(
+			Filter.Property("Due").Date().IsBefore(RelativeDate(DateToday)),
+			Filter.Property("Due").Date().IsOnOrAfter(ExactDate(time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC))),
+		),
+		Filter.Property("8#gA").Number().GreaterThan(3),
+	).And(Filter.Property("Tags").IsEmpty())
+	assert.Equal(t, FilterAnd, f.Operator)
+	assert.Equal(t, 4, len(f.Filters))
+
+	m, err := f.ToMap(c)
+	assert.NoError(t, err)
+	exp := `{"filters":[` +
+		`{"filter":{"operator":"enum_is","value":{"type":"exact","value":"Done"}},"property":"a:bc"},` +
+		`{"filters":[` +
+		`{"filter":{"operator":"date_is_before","value":{"type":"relative","value":"today"}},"property":"d;ef"},` +
+		`{"filter":{"operator":"date_is_on_or_after","value":{"type":"exact","value":{"start_date":"2021-05-03","type":"date"}}},"property":"d;ef"}` +
+		`],"operator":"or"},` +
+		`{"filter":{"operator":"number_greater_than","value":{"type":"exact","value":3}},"property":"8#gA"},` +
+		`{"filter":{"operator":"is_empty"},"property":"g=hi"}` +
+		`],"operator":"and"}`
+	assert.Equal(t, exp, toJSONString(t, m))
+
+	// round-trip
+	f2, err := ParseQueryFilter(m)
+	assert.NoError(t, err)
+	assert.NoError(t, f2.Validate(c))
+	m2, err := f2.ToMap(c)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, toJSONString(t, m2))
+
+	err = Filter.Property("Status").Number().Equals(1).Validate(c)
+	assert.Error(t, err)
+	err = Filter.Property("Missing").Text().Is("x").Validate(c)
+	assert.Error(t, err)
+	// text filters work for title
+	assert.NoError(t, Filter.Property("Name").Text().Contains("x").Validate(c))
+}
+
+func TestParseQueryFilter(t *testing.T) {
+	s := `{
+  "filters": [
+    {
+      "filter": {
+        "operator": "number_equals",
+        "value": { "type": "exact" }
+      },
+      "property": "8#gA"
+    }
+  ],
+  "operator": "and"
+}`
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(s), &m))
+	q := &Query{Filter: m}
+	f, err := q.TypedFilter()
+	assert.NoError(t, err)
+	assert.True(t, f.IsGroup())
+	assert.Equal(t, 1, len(f.Filters))
+	cond := f.Filters[0]
+	assert.Equal(t, "8#gA", cond.Property)
+	assert.Equal(t, FilterNumberEquals, cond.Condition.Operator)
+	assert.Equal(t, FilterValueExact, cond.Condition.Value.Type)
+	assert.NoError(t, cond.Validate(newFilterTestCollection()))
+
+	_, err = ParseQueryFilter(map[string]interface{}{"operator": "xor", "filters": []interface{}{}})
+	assert.Error(t, err)
+}
+
+func TestNewQuery(t *testing.T) {
+	c := newFilterTestCollection()
+	f := Filter.Property("Tags").MultiSelect().Contains("go")
+	q, err := NewQuery(c, f, "notion", SortDescending("Due"), SortAscending("title"))
+	assert.NoError(t, err)
+	assert.Equal(t, "d;ef", q.Sort[0].Property)
+	assert.Equal(t, "descending", q.Sort[0].Direction)
+	assert.Equal(t, "title", q.Sort[1].Property)
+
+	lr := MakeLoaderReducer(q)
+	assert.Equal(t, "notion", lr.SearchQuery)
+	assert.Equal(t, q.Filter, lr.Filter)
+
+	_, err = NewQuery(c, nil, "", SortAscending("Missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,828 @@
+package notionapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FormulaValue.Type
+const (
+	FormulaNumber   = "number"
+	FormulaText     = "text"
+	FormulaCheckbox = "checkbox"
+	FormulaDate     = "date"
+)
+
+// FormulaValue is a result of evaluating a formula. It's also used
+// for results of rollups and aggregations
+type FormulaValue struct {
+	// FormulaNumber, FormulaText, FormulaCheckbox or FormulaDate
+	Type     string
+	Number   float64
+	Text     string
+	Checkbox bool
+	// for Type == FormulaDate
+	Date time.Time
+	// for Type == FormulaDate, if it's a date range
+	DateEnd time.Time
+}
+
+// String formats the value the way Notion shows it
+func (v *FormulaValue) String() string {
+	switch v.Type {
+	case FormulaNumber:
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	case FormulaCheckbox:
+		return strconv.FormatBool(v.Checkbox)
+	case FormulaDate:
+		if v.Date.IsZero() {
+			return ""
+		}
+		layout := "January 2, 2006"
+		if v.Date.Hour() != 0 || v.Date.Minute() != 0 {
+			layout += " 3:04 PM"
+		}
+		s := v.Date.Format(layout)
+		if !v.DateEnd.IsZero() {
+			s += " → " + v.DateEnd.Format(layout)
+		}
+		return s
+	}
+	return v.Text
+}
+
+func numberValue(n float64) *FormulaValue {
+	return &FormulaValue{Type: FormulaNumber, Number: n}
+}
+
+func textValue(s string) *FormulaValue {
+	return &FormulaValue{Type: FormulaText, Text: s}
+}
+
+func boolValue(b bool) *FormulaValue {
+	return &FormulaValue{Type: FormulaCheckbox, Checkbox: b}
+}
+
+func dateValue(t time.Time) *FormulaValue {
+	return &FormulaValue{Type: FormulaDate, Date: t}
+}
+
+// UnsupportedFormulaError is returned when a formula uses a function
+// or operator we don't know how to evaluate
+type UnsupportedFormulaError struct {
+	Name string
+}
+
+func (e *UnsupportedFormulaError) Error() string {
+	return fmt.Sprintf("formula function '%s' is not supported", e.Name)
+}
+
+// names of functions for operators
+var formulaOperators = map[string]string{
+	"+":  "add",
+	"-":  "subtract",
+	"*":  "multiply",
+	"/":  "divide",
+	"^":  "pow",
+	"%":  "mod",
+	"==": "equal",
+	"!=": "unequal",
+	">":  "larger",
+	">=": "largerEq",
+	"<":  "smaller",
+	"<=": "smallerEq",
+	"!":  "not",
+	"&&": "and",
+	"||": "or",
+	"?":  "if",
+}
+
+// formula functions that take numbers and return a number
+var formulaMathFuncs = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"cbrt":  math.Cbrt,
+	"ceil":  math.Ceil,
+	"exp":   math.Exp,
+	"floor": math.Floor,
+	"ln":    math.Log,
+	"log10": math.Log10,
+	"log2":  math.Log2,
+	"round": func(n float64) float64 { return math.Floor(n + 0.5) },
+	"sqrt":  math.Sqrt,
+	"sign": func(n float64) float64 {
+		if n > 0 {
+			return 1
+		}
+		if n < 0 {
+			return -1
+		}
+		return 0
+	},
+	"unaryMinus": func(n float64) float64 { return -n },
+	"unaryPlus":  func(n float64) float64 { return n },
+}
+
+// over-written in tests
+var formulaNow = time.Now
+
+// maximum depth of formulas referencing other formulas
+const maxFormulaDepth = 32
+
+type formulaEvaluator struct {
+	row   *TableRow
+	depth int
+}
+
+// EvalFormula evaluates formula f for a given row of a table
+func EvalFormula(f *ColumnFormula, row *TableRow) (*FormulaValue, error) {
+	e := &formulaEvaluator{row: row}
+	return e.eval(f.root())
+}
+
+// FormulaValue evaluates formula in a column with a given id
+func (r *TableRow) FormulaValue(colID string) (*FormulaValue, error) {
+	schema := r.columnSchema(colID)
+	if schema == nil || schema.Type != ColumnTypeFormula || schema.Formula == nil {
+		return nil, fmt.Errorf("column '%s' is not a formula", colID)
+	}
+	return EvalFormula(schema.Formula, r)
+}
+
+func (f *ColumnFormula) root() *FormulaArg {
+	name := f.Name
+	return &FormulaArg{
+		Name:       &name,
+		ResultType: f.ResultType,
+		Type:       f.Type,
+		Value:      f.Value,
+		ValueType:  f.ValueType,
+		ID:         f.ID,
+		Operator:   f.Operator,
+		Args:       f.Args,
+		Condition:  f.Condition,
+		True:       f.True,
+		False:      f.False,
+	}
+}
+
+func argName(a *FormulaArg) string {
+	if a.Name != nil && *a.Name != "" {
+		return *a.Name
+	}
+	return formulaOperators[a.Operator]
+}
+
+func (e *formulaEvaluator) eval(a *FormulaArg) (*FormulaValue, error) {
+	if a == nil {
+		return nil, fmt.Errorf("missing formula argument")
+	}
+	switch a.Type {
+	case "constant":
+		s := ""
+		if a.Value != nil {
+			s = *a.Value
+		}
+		if a.ResultType == FormulaNumber {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number constant '%s'", s)
+			}
+			return numberValue(n), nil
+		}
+		return textValue(s), nil
+	case "symbol":
+		switch argName(a) {
+		case "true":
+			return boolValue(true), nil
+		case "false":
+			return boolValue(false), nil
+		case "e":
+			return numberValue(math.E), nil
+		case "pi":
+			return numberValue(math.Pi), nil
+		}
+		return nil, &UnsupportedFormulaError{Name: argName(a)}
+	case "property":
+		return e.propValue(a.ID)
+	case "conditional":
+		return e.evalIf([]FormulaArg{derefArg(a.Condition), derefArg(a.True), derefArg(a.False)})
+	case "operator", "function":
+		return e.call(argName(a), a.Args)
+	}
+	return nil, &UnsupportedFormulaError{Name: a.Type}
+}
+
+func derefArg(a *FormulaArg) FormulaArg {
+	if a == nil {
+		return FormulaArg{}
+	}
+	return *a
+}
+
+func (e *formulaEvaluator) propValue(colID string) (*FormulaValue, error) {
+	schema := e.row.columnSchema(colID)
+	if schema != nil && schema.Type == ColumnTypeFormula && schema.Formula != nil {
+		if e.depth >= maxFormulaDepth {
+			return nil, fmt.Errorf("formula in column '%s' references itself", colID)
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+		return e.eval(schema.Formula.root())
+	}
+	v := e.row.Value(colID)
+	switch v.Type {
+	case ColumnTypeNumber:
+		return numberValue(v.Number), nil
+	case ColumnTypeCheckbox:
+		return boolValue(v.Checkbox), nil
+	case ColumnTypeDate, ColumnTypeCreatedTime, ColumnTypeLastEditedTime:
+		res := dateValue(v.Time)
+		if v.Date != nil && v.Date.EndDate != "" {
+			end := *v.Date
+			end.StartDate, end.StartTime = v.Date.EndDate, v.Date.EndTime
+			res.DateEnd = parseDateTime(&end)
+		}
+		return res, nil
+	}
+	return textValue(v.Text), nil
+}
+
+func (e *formulaEvaluator) evalArgs(name string, args []FormulaArg, minArgs int, maxArgs int) ([]*FormulaValue, error) {
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return nil, fmt.Errorf("%s: wrong number of arguments: %d", name, len(args))
+	}
+	var res []*FormulaValue
+	for i := range args {
+		v, err := e.eval(&args[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func expectType(name string, v *FormulaValue, typ string) error {
+	if v.Type != typ {
+		return fmt.Errorf("%s: expected %s, got %s", name, typ, v.Type)
+	}
+	return nil
+}
+
+func expectTypes(name string, vals []*FormulaValue, types ...string) error {
+	for i, v := range vals {
+		if i < len(types) {
+			if err := expectType(name, v, types[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyFormulaValue(v *FormulaValue) bool {
+	switch v.Type {
+	case FormulaNumber:
+		return v.Number == 0
+	case FormulaCheckbox:
+		return !v.Checkbox
+	case FormulaDate:
+		return v.Date.IsZero()
+	}
+	return v.Text == ""
+}
+
+func formulaValuesEqual(a *FormulaValue, b *FormulaValue) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case FormulaNumber:
+		return a.Number == b.Number
+	case FormulaCheckbox:
+		return a.Checkbox == b.Checkbox
+	case FormulaDate:
+		return a.Date.Equal(b.Date)
+	}
+	return a.Text == b.Text
+}
+
+// compareFormulaValues returns -1, 0, 1
+func compareFormulaValues(name string, a *FormulaValue, b *FormulaValue) (int, error) {
+	if err := expectType(name, b, a.Type); err != nil {
+		return 0, err
+	}
+	switch a.Type {
+	case FormulaNumber:
+		if a.Number < b.Number {
+			return -1, nil
+		}
+		if a.Number > b.Number {
+			return 1, nil
+		}
+		return 0, nil
+	case FormulaDate:
+		if a.Date.Before(b.Date) {
+			return -1, nil
+		}
+		if a.Date.After(b.Date) {
+			return 1, nil
+		}
+		return 0, nil
+	case FormulaText:
+		return strings.Compare(a.Text, b.Text), nil
+	}
+	return 0, fmt.Errorf("%s: can't compare values of type %s", name, a.Type)
+}
+
+func (e *formulaEvaluator) evalIf(args []FormulaArg) (*FormulaValue, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("if: wrong number of arguments: %d", len(args))
+	}
+	cond, err := e.eval(&args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err = expectType("if", cond, FormulaCheckbox); err != nil {
+		return nil, err
+	}
+	// only evaluate the branch that is taken
+	if cond.Checkbox {
+		return e.eval(&args[1])
+	}
+	return e.eval(&args[2])
+}
+
+func (e *formulaEvaluator) call(name string, args []FormulaArg) (*FormulaValue, error) {
+	if name == "if" {
+		return e.evalIf(args)
+	}
+	if fn, ok := formulaMathFuncs[name]; ok {
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaNumber); err != nil {
+			return nil, err
+		}
+		return numberValue(fn(vals[0].Number)), nil
+	}
+
+	switch name {
+	case "add":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		if vals[0].Type == FormulaText || vals[1].Type == FormulaText {
+			return textValue(vals[0].String() + vals[1].String()), nil
+		}
+		if err = expectTypes(name, vals, FormulaNumber, FormulaNumber); err != nil {
+			return nil, err
+		}
+		return numberValue(vals[0].Number + vals[1].Number), nil
+	case "subtract", "multiply", "divide", "pow", "mod":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaNumber, FormulaNumber); err != nil {
+			return nil, err
+		}
+		a, b := vals[0].Number, vals[1].Number
+		switch name {
+		case "subtract":
+			return numberValue(a - b), nil
+		case "multiply":
+			return numberValue(a * b), nil
+		case "divide":
+			return numberValue(a / b), nil
+		case "pow":
+			return numberValue(math.Pow(a, b)), nil
+		}
+		return numberValue(math.Mod(a, b)), nil
+	case "equal", "unequal":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		eq := formulaValuesEqual(vals[0], vals[1])
+		return boolValue(eq == (name == "equal")), nil
+	case "larger", "largerEq", "smaller", "smallerEq":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := compareFormulaValues(name, vals[0], vals[1])
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "larger":
+			return boolValue(cmp > 0), nil
+		case "largerEq":
+			return boolValue(cmp >= 0), nil
+		case "smaller":
+			return boolValue(cmp < 0), nil
+		}
+		return boolValue(cmp <= 0), nil
+	case "not":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaCheckbox); err != nil {
+			return nil, err
+		}
+		return boolValue(!vals[0].Checkbox), nil
+	case "and", "or":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaCheckbox, FormulaCheckbox); err != nil {
+			return nil, err
+		}
+		if name == "and" {
+			return boolValue(vals[0].Checkbox && vals[1].Checkbox), nil
+		}
+		return boolValue(vals[0].Checkbox || vals[1].Checkbox), nil
+	case "max", "min":
+		vals, err := e.evalArgs(name, args, 1, -1)
+		if err != nil {
+			return nil, err
+		}
+		res := vals[0]
+		for _, v := range vals {
+			if err = expectType(name, v, FormulaNumber); err != nil {
+				return nil, err
+			}
+			if (name == "max" && v.Number > res.Number) || (name == "min" && v.Number < res.Number) {
+				res = v
+			}
+		}
+		return res, nil
+	case "concat":
+		vals, err := e.evalArgs(name, args, 1, -1)
+		if err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		for _, v := range vals {
+			if err = expectType(name, v, FormulaText); err != nil {
+				return nil, err
+			}
+			sb.WriteString(v.Text)
+		}
+		return textValue(sb.String()), nil
+	case "join":
+		vals, err := e.evalArgs(name, args, 1, -1)
+		if err != nil {
+			return nil, err
+		}
+		var parts []string
+		for _, v := range vals {
+			if err = expectType(name, v, FormulaText); err != nil {
+				return nil, err
+			}
+			parts = append(parts, v.Text)
+		}
+		return textValue(strings.Join(parts[1:], parts[0])), nil
+	case "slice":
+		vals, err := e.evalArgs(name, args, 2, 3)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaText, FormulaNumber, FormulaNumber); err != nil {
+			return nil, err
+		}
+		runes := []rune(vals[0].Text)
+		clamp := func(n float64) int {
+			i := int(n)
+			if i < 0 {
+				i = 0
+			}
+			if i > len(runes) {
+				i = len(runes)
+			}
+			return i
+		}
+		start, end := clamp(vals[1].Number), len(runes)
+		if len(vals) == 3 {
+			end = clamp(vals[2].Number)
+		}
+		if end < start {
+			end = start
+		}
+		return textValue(string(runes[start:end])), nil
+	case "length":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaText); err != nil {
+			return nil, err
+		}
+		return numberValue(float64(utf8.RuneCountInString(vals[0].Text))), nil
+	case "format":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return textValue(vals[0].String()), nil
+	case "toNumber":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		v := vals[0]
+		switch v.Type {
+		case FormulaNumber:
+			return v, nil
+		case FormulaCheckbox:
+			if v.Checkbox {
+				return numberValue(1), nil
+			}
+			return numberValue(0), nil
+		case FormulaDate:
+			return numberValue(float64(v.Date.UnixNano() / int64(time.Millisecond))), nil
+		}
+		n, _ := strconv.ParseFloat(strings.TrimSpace(v.Text), 64)
+		return numberValue(n), nil
+	case "contains":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaText, FormulaText); err != nil {
+			return nil, err
+		}
+		return boolValue(strings.Contains(vals[0].Text, vals[1].Text)), nil
+	case "test", "replace", "replaceAll":
+		nArgs := 3
+		if name == "test" {
+			nArgs = 2
+		}
+		vals, err := e.evalArgs(name, args, nArgs, nArgs)
+		if err != nil {
+			return nil, err
+		}
+		s := vals[0].String()
+		if err = expectTypes(name, vals[1:], FormulaText, FormulaText); err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(vals[1].Text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid regular expression '%s'", name, vals[1].Text)
+		}
+		switch name {
+		case "test":
+			return boolValue(re.MatchString(s)), nil
+		case "replaceAll":
+			return textValue(re.ReplaceAllString(s, vals[2].Text)), nil
+		}
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return textValue(s), nil
+		}
+		res := re.ExpandString([]byte(s[:loc[0]]), vals[2].Text, s, loc)
+		return textValue(string(res) + s[loc[1]:]), nil
+	case "empty":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue(isEmptyFormulaValue(vals[0])), nil
+	case "now":
+		if _, err := e.evalArgs(name, args, 0, 0); err != nil {
+			return nil, err
+		}
+		return dateValue(formulaNow()), nil
+	case "start", "end":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		v := vals[0]
+		if err = expectType(name, v, FormulaDate); err != nil {
+			return nil, err
+		}
+		if name == "end" && !v.DateEnd.IsZero() {
+			return dateValue(v.DateEnd), nil
+		}
+		return dateValue(v.Date), nil
+	case "timestamp":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaDate); err != nil {
+			return nil, err
+		}
+		return numberValue(float64(vals[0].Date.UnixNano() / int64(time.Millisecond))), nil
+	case "fromTimestamp":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaNumber); err != nil {
+			return nil, err
+		}
+		ms := int64(vals[0].Number)
+		return dateValue(time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC()), nil
+	case "dateAdd", "dateSubtract":
+		vals, err := e.evalArgs(name, args, 3, 3)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaDate, FormulaNumber, FormulaText); err != nil {
+			return nil, err
+		}
+		n := vals[1].Number
+		if name == "dateSubtract" {
+			n = -n
+		}
+		t, err := dateAdd(vals[0].Date, n, vals[2].Text)
+		if err != nil {
+			return nil, err
+		}
+		return dateValue(t), nil
+	case "dateBetween":
+		vals, err := e.evalArgs(name, args, 3, 3)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaDate, FormulaDate, FormulaText); err != nil {
+			return nil, err
+		}
+		n, err := dateBetween(vals[0].Date, vals[1].Date, vals[2].Text)
+		if err != nil {
+			return nil, err
+		}
+		return numberValue(n), nil
+	case "formatDate":
+		vals, err := e.evalArgs(name, args, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectTypes(name, vals, FormulaDate, FormulaText); err != nil {
+			return nil, err
+		}
+		return textValue(formatMomentDate(vals[0].Date, vals[1].Text)), nil
+	case "minute", "hour", "day", "date", "month", "year":
+		vals, err := e.evalArgs(name, args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = expectType(name, vals[0], FormulaDate); err != nil {
+			return nil, err
+		}
+		t := vals[0].Date
+		var n int
+		switch name {
+		case "minute":
+			n = t.Minute()
+		case "hour":
+			n = t.Hour()
+		case "day":
+			// day of the week, 0 is Sunday
+			n = int(t.Weekday())
+		case "date":
+			n = t.Day()
+		case "month":
+			// 0 is January
+			n = int(t.Month()) - 1
+		case "year":
+			n = t.Year()
+		}
+		return numberValue(float64(n)), nil
+	}
+	return nil, &UnsupportedFormulaError{Name: name}
+}
+
+func dateAdd(t time.Time, n float64, unit string) (time.Time, error) {
+	i := int(n)
+	switch unit {
+	case "years":
+		return t.AddDate(i, 0, 0), nil
+	case "quarters":
+		return t.AddDate(0, 3*i, 0), nil
+	case "months":
+		return t.AddDate(0, i, 0), nil
+	case "weeks":
+		return t.AddDate(0, 0, 7*i), nil
+	case "days":
+		return t.AddDate(0, 0, i), nil
+	case "hours":
+		return t.Add(time.Duration(n * float64(time.Hour))), nil
+	case "minutes":
+		return t.Add(time.Duration(n * float64(time.Minute))), nil
+	case "seconds":
+		return t.Add(time.Duration(n * float64(time.Second))), nil
+	case "milliseconds":
+		return t.Add(time.Duration(n * float64(time.Millisecond))), nil
+	}
+	return t, fmt.Errorf("invalid date unit '%s'", unit)
+}
+
+// monthsBetween returns number of whole months between t1 and t2
+func monthsBetween(t1 time.Time, t2 time.Time) int {
+	n := (t1.Year()-t2.Year())*12 + int(t1.Month()) - int(t2.Month())
+	// don't count the last month if it's not complete
+	if n > 0 && t2.AddDate(0, n, 0).After(t1) {
+		n--
+	} else if n < 0 && t2.AddDate(0, n, 0).Before(t1) {
+		n++
+	}
+	return n
+}
+
+// dateBetween returns t1 - t2 in whole units
+func dateBetween(t1 time.Time, t2 time.Time, unit string) (float64, error) {
+	d := t1.Sub(t2)
+	var n float64
+	switch unit {
+	case "years":
+		n = float64(monthsBetween(t1, t2) / 12)
+	case "quarters":
+		n = float64(monthsBetween(t1, t2) / 3)
+	case "months":
+		n = float64(monthsBetween(t1, t2))
+	case "weeks":
+		n = float64(d / (7 * 24 * time.Hour))
+	case "days":
+		n = float64(d / (24 * time.Hour))
+	case "hours":
+		n = float64(d / time.Hour)
+	case "minutes":
+		n = float64(d / time.Minute)
+	case "seconds":
+		n = float64(d / time.Second)
+	case "milliseconds":
+		n = float64(d / time.Millisecond)
+	default:
+		return 0, fmt.Errorf("invalid date unit '%s'", unit)
+	}
+	return n, nil
+}
+
+// moment.js format tokens, longest first, and their Go equivalents
+var momentTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"dddd", "Monday"},
+	{"ddd", "Mon"},
+	{"DD", "02"},
+	{"D", "2"},
+	{"HH", "15"},
+	{"hh", "03"},
+	{"h", "3"},
+	{"mm", "04"},
+	{"m", "4"},
+	{"ss", "05"},
+	{"s", "5"},
+	{"A", "PM"},
+	{"a", "pm"},
+}
+
+// formatMomentDate formats t using moment.js format used by
+// Notion's formatDate(), e.g. "MMMM D, YYYY"
+func formatMomentDate(t time.Time, format string) string {
+	var sb strings.Builder
+	for len(format) > 0 {
+		matched := false
+		for _, tok := range momentTokens {
+			if strings.HasPrefix(format, tok.token) {
+				sb.WriteString(t.Format(tok.layout))
+				format = format[len(tok.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if format[0] == 'H' {
+			// "H" has no equivalent in Go
+			sb.WriteString(strconv.Itoa(t.Hour()))
+			format = format[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(format)
+		sb.WriteRune(r)
+		format = format[size:]
+	}
+	return sb.String()
+}
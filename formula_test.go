@@ -0,0 +1,186 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func newFormulaTestRow(t *testing.T) *TableRow {
+	propsJSON := `{
+	"title": [["Task"]],
+	"price": [["12.5"]],
+	"qty": [["4"]],
+	"done": [["Yes"]],
+	"due": [["‣", [["d", {"type": "date", "start_date": "2021-05-03"}]]]]
+}`
+	var props map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(propsJSON), &props))
+	total := mustParseFormula(t, `{
+	"type": "operator", "operator": "*", "name": "multiply", "result_type": "number",
+	"args": [
+		{"type": "property", "id": "price", "name": "Price", "result_type": "number"},
+		{"type": "property", "id": "qty", "name": "Qty", "result_type": "number"}
+	]
+}`)
+	schema := map[string]*ColumnSchema{
+		"title": {Name: "Name", Type: ColumnTypeTitle},
+		"price": {Name: "Price", Type: ColumnTypeNumber},
+		"qty":   {Name: "Qty", Type: ColumnTypeNumber},
+		"done":  {Name: "Done", Type: ColumnTypeCheckbox},
+		"due":   {Name: "Due", Type: ColumnTypeDate},
+		"total": {Name: "Total", Type: ColumnTypeFormula, Formula: total},
+	}
+	tv := &TableView{Collection: &Collection{Schema: schema}}
+	return &TableRow{TableView: tv, Page: &Block{ID: "row", Properties: props}}
+}
+
+func mustParseFormula(t *testing.T, s string) *ColumnFormula {
+	var f ColumnFormula
+	assert.NoError(t, json.Unmarshal([]byte(s), &f))
+	return &f
+}
+
+func evalFormulaString(t *testing.T, row *TableRow, s string) (*FormulaValue, error) {
+	return EvalFormula(mustParseFormula(t, s), row)
+}
+
+func TestEvalFormula(t *testing.T) {
+	formulaNow = func() time.Time {
+		return time.Date(2021, 5, 10, 14, 30, 0, 0, time.UTC)
+	}
+	defer func() {
+		formulaNow = time.Now
+	}()
+	row := newFormulaTestRow(t)
+
+	v, err := row.FormulaValue("total")
+	assert.NoError(t, err)
+	assert.Equal(t, 50.0, v.Number)
+
+	tests := []struct {
+		formula string
+		exp     string
+	}{
+		{
+			// prop("Total") + 1, formula referencing another formula
+			`{"type": "operator", "operator": "+", "name": "add", "args": [
+				{"type": "property", "id": "total"},
+				{"type": "constant", "value": "1", "value_type": "number", "result_type": "number"}]}`,
+			"51",
+		},
+		{
+			// concat(prop("Name"), " costs ", format(prop("Total")))
+			`{"type": "function", "name": "concat", "args": [
+				{"type": "property", "id": "title"},
+				{"type": "constant", "value": " costs ", "result_type": "text"},
+				{"type": "function", "name": "format", "args": [{"type": "property", "id": "total"}]}]}`,
+			"Task costs 50",
+		},
+		{
+			// if(prop("Done"), "yes", "no")
+			`{"type": "function", "name": "if", "args": [
+				{"type": "property", "id": "done"},
+				{"type": "constant", "value": "yes", "result_type": "text"},
+				{"type": "constant", "value": "no", "result_type": "text"}]}`,
+			"yes",
+		},
+		{
+			// prop("Price") > 20 ? "expensive" : "cheap"
+			`{"type": "conditional",
+				"condition": {"type": "operator", "operator": ">", "args": [
+					{"type": "property", "id": "price"},
+					{"type": "constant", "value": "20", "result_type": "number"}]},
+				"true": {"type": "constant", "value": "expensive", "result_type": "text"},
+				"false": {"type": "constant", "value": "cheap", "result_type": "text"}}`,
+			"cheap",
+		},
+		{
+			// formatDate(dateAdd(prop("Due"), 1, "months"), "MMM D, YYYY")
+			`{"type": "function", "name": "formatDate", "args": [
+				{"type": "function", "name": "dateAdd", "args": [
+					{"type": "property", "id": "due"},
+					{"type": "constant", "value": "1", "result_type": "number"},
+					{"type": "constant", "value": "months", "result_type": "text"}]},
+				{"type": "constant", "value": "MMM D, YYYY", "result_type": "text"}]}`,
+			"Jun 3, 2021",
+		},
+		{
+			// dateBetween(now(), prop("Due"), "days")
+			`{"type": "function", "name": "dateBetween", "args": [
+				{"type": "function", "name": "now", "args": []},
+				{"type": "property", "id": "due"},
+				{"type": "constant", "value": "days", "result_type": "text"}]}`,
+			"7",
+		},
+		{
+			// round(sqrt(prop("Qty")) * pi)
+			`{"type": "function", "name": "round", "args": [
+				{"type": "operator", "name": "multiply", "args": [
+					{"type": "function", "name": "sqrt", "args": [{"type": "property", "id": "qty"}]},
+					{"type": "symbol", "name": "pi"}]}]}`,
+			"6",
+		},
+		{
+			// replaceAll("a-b-c", "-", "+")
+			`{"type": "function", "name": "replaceAll", "args": [
+				{"type": "constant", "value": "a-b-c", "result_type": "text"},
+				{"type": "constant", "value": "-", "result_type": "text"},
+				{"type": "constant", "value": "+", "result_type": "text"}]}`,
+			"a+b+c",
+		},
+		{
+			// replace("a-b-c", "-", "+")
+			`{"type": "function", "name": "replace", "args": [
+				{"type": "constant", "value": "a-b-c", "result_type": "text"},
+				{"type": "constant", "value": "-", "result_type": "text"},
+				{"type": "constant", "value": "+", "result_type": "text"}]}`,
+			"a+b-c",
+		},
+		{
+			// slice(prop("Name"), 1, 3)
+			`{"type": "function", "name": "slice", "args": [
+				{"type": "property", "id": "title"},
+				{"type": "constant", "value": "1", "result_type": "number"},
+				{"type": "constant", "value": "3", "result_type": "number"}]}`,
+			"as",
+		},
+		{
+			// not empty(prop("Name")) and true
+			`{"type": "operator", "name": "and", "args": [
+				{"type": "operator", "name": "not", "args": [
+					{"type": "function", "name": "empty", "args": [{"type": "property", "id": "title"}]}]},
+				{"type": "symbol", "name": "true"}]}`,
+			"true",
+		},
+		{
+			// month(prop("Due")) is 0-based
+			`{"type": "function", "name": "month", "args": [{"type": "property", "id": "due"}]}`,
+			"4",
+		},
+	}
+	for _, test := range tests {
+		v, err := evalFormulaString(t, row, test.formula)
+		assert.NoError(t, err)
+		assert.Equal(t, test.exp, v.String())
+	}
+
+	_, err = evalFormulaString(t, row, `{"type": "function", "name": "id", "args": []}`)
+	assert.Error(t, err)
+	_, ok := err.(*UnsupportedFormulaError)
+	assert.True(t, ok)
+
+	// type errors are reported
+	_, err = evalFormulaString(t, row, `{"type": "operator", "name": "subtract", "args": [
+		{"type": "property", "id": "title"}, {"type": "property", "id": "qty"}]}`)
+	assert.Error(t, err)
+
+	cv := row.Value("total")
+	assert.Equal(t, "50", cv.Text)
+	assert.Equal(t, 50.0, cv.Formula.Number)
+
+	_, err = row.FormulaValue("price")
+	assert.Error(t, err)
+}
@@ -0,0 +1,567 @@
+// Package fromhtml converts HTML into operations that create Notion blocks.
+// It understands both common HTML and HTML generated by tohtml package
+package fromhtml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/frommarkdown"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hasClass(n *html.Node, cls string) bool {
+	for _, s := range strings.Fields(getAttr(n, "class")) {
+		if s == cls {
+			return true
+		}
+	}
+	return false
+}
+
+func isElement(n *html.Node, a atom.Atom) bool {
+	return n.Type == html.ElementNode && n.DataAtom == a
+}
+
+// findElement returns the first element of type a in n and its
+// descendants, nil if there is none
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if isElement(n, a) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if res := findElement(c, a); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// findClass returns the first element with class cls in n and its
+// descendants, nil if there is none
+func findClass(n *html.Node, cls string) *html.Node {
+	if n.Type == html.ElementNode && hasClass(n, cls) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if res := findClass(c, cls); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// textContent returns text of n and its descendants, as is
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+var inlineElements = map[atom.Atom]bool{
+	atom.A:      true,
+	atom.Abbr:   true,
+	atom.B:      true,
+	atom.Br:     true,
+	atom.Cite:   true,
+	atom.Code:   true,
+	atom.Del:    true,
+	atom.Em:     true,
+	atom.I:      true,
+	atom.Img:    true,
+	atom.Ins:    true,
+	atom.Kbd:    true,
+	atom.Label:  true,
+	atom.Mark:   true,
+	atom.Q:      true,
+	atom.S:      true,
+	atom.Samp:   true,
+	atom.Small:  true,
+	atom.Span:   true,
+	atom.Strike: true,
+	atom.Strong: true,
+	atom.Sub:    true,
+	atom.Sup:    true,
+	atom.Time:   true,
+	atom.U:      true,
+	atom.Var:    true,
+}
+
+// isInline returns true if n is a part of text
+func isInline(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode:
+		return true
+	case html.ElementNode:
+		return inlineElements[n.DataAtom]
+	}
+	return false
+}
+
+// textBlock returns BlockText for inline nodes, nil if they have no text
+func textBlock(nodes ...*html.Node) *notionapi.BlockSpec {
+	spans := inlineSpans(nodes...)
+	if len(spans) == 0 {
+		return nil
+	}
+	return notionapi.NewTextSpec(notionapi.BlockText, spans)
+}
+
+// onlyImages returns <img> elements of inline nodes if they have no
+// text other than white space, nil otherwise
+func onlyImages(nodes []*html.Node) []*html.Node {
+	var imgs []*html.Node
+	var find func(n *html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.TextNode {
+			return strings.TrimSpace(n.Data) == ""
+		}
+		if isElement(n, atom.Img) {
+			imgs = append(imgs, n)
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !find(c) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, n := range nodes {
+		if !find(n) {
+			return nil
+		}
+	}
+	return imgs
+}
+
+// inlineBlocks converts a run of inline nodes to BlockText. Images
+// without text around them, e.g. <p><img></p>, become BlockImage
+func inlineBlocks(nodes []*html.Node) ([]*notionapi.BlockSpec, error) {
+	imgs := onlyImages(nodes)
+	if len(imgs) == 0 {
+		return one(textBlock(nodes...)), nil
+	}
+	var res []*notionapi.BlockSpec
+	for _, img := range imgs {
+		spec, err := image(img, nil)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, spec)
+	}
+	return res, nil
+}
+
+// children converts children of n to blocks. Runs of inline content
+// become BlockText
+func children(n *html.Node) ([]*notionapi.BlockSpec, error) {
+	return childrenExcept(n, nil)
+}
+
+// childrenExcept is like children but skips child node skip
+func childrenExcept(n *html.Node, skip *html.Node) ([]*notionapi.BlockSpec, error) {
+	var res []*notionapi.BlockSpec
+	var run []*html.Node
+	flush := func() error {
+		specs, err := inlineBlocks(run)
+		res = append(res, specs...)
+		run = nil
+		return err
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c == skip {
+			continue
+		}
+		if isInline(c) {
+			run = append(run, c)
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		specs, err := blocks(c)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, specs...)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// childrenWithTitle is withTitle of children of n
+func childrenWithTitle(blockType string, n *html.Node) (*notionapi.BlockSpec, error) {
+	specs, err := children(n)
+	if err != nil {
+		return nil, err
+	}
+	return withTitle(blockType, specs), nil
+}
+
+// withTitle makes the first block of blocks, if it's text, a title of
+// a block of type blockType and the rest its children
+func withTitle(blockType string, blocks []*notionapi.BlockSpec) *notionapi.BlockSpec {
+	spec := &notionapi.BlockSpec{Type: blockType}
+	if len(blocks) > 0 && blocks[0].Type == notionapi.BlockText && len(blocks[0].Content) == 0 {
+		spec.Properties = blocks[0].Properties
+		blocks = blocks[1:]
+	}
+	spec.Content = blocks
+	return spec
+}
+
+func one(spec *notionapi.BlockSpec) []*notionapi.BlockSpec {
+	if spec == nil {
+		return nil
+	}
+	return []*notionapi.BlockSpec{spec}
+}
+
+func oneOrErr(spec *notionapi.BlockSpec, err error) ([]*notionapi.BlockSpec, error) {
+	if err != nil {
+		return nil, err
+	}
+	return one(spec), nil
+}
+
+// blocks converts a non-inline node to blocks
+func blocks(n *html.Node) ([]*notionapi.BlockSpec, error) {
+	if n.Type == html.DocumentNode {
+		return children(n)
+	}
+	if n.Type != html.ElementNode {
+		return nil, nil
+	}
+	// classes used by tohtml
+	switch {
+	case hasClass(n, "page-title"), hasClass(n, "page-header-icon"), hasClass(n, "page-cover-image"):
+		return nil, nil
+	case hasClass(n, "collection-content") && n.DataAtom != atom.Table:
+		return one(collectionView(n)), nil
+	case hasClass(n, "equation"):
+		return one(equation(n)), nil
+	case hasClass(n, "notion-callout"):
+		return oneOrErr(callout(n))
+	case hasClass(n, "table_of_contents"):
+		return one(&notionapi.BlockSpec{Type: notionapi.BlockTableOfContents}), nil
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Noscript, atom.Svg, atom.Input:
+		return nil, nil
+	case atom.H1:
+		return one(heading(notionapi.BlockHeader, n)), nil
+	case atom.H2:
+		return one(heading(notionapi.BlockSubHeader, n)), nil
+	case atom.H3, atom.H4, atom.H5, atom.H6:
+		return one(heading(notionapi.BlockSubSubHeader, n)), nil
+	case atom.Ul, atom.Ol:
+		return list(n)
+	case atom.Pre:
+		return one(code(n)), nil
+	case atom.Blockquote:
+		return oneOrErr(childrenWithTitle(notionapi.BlockQuote, n))
+	case atom.Details:
+		return oneOrErr(toggle(n))
+	case atom.Aside:
+		return oneOrErr(callout(n))
+	case atom.Figure:
+		if img := findElement(n, atom.Img); img != nil {
+			var caption []*notionapi.TextSpan
+			if fc := findElement(n, atom.Figcaption); fc != nil {
+				caption = childSpans(fc)
+			}
+			return oneOrErr(image(img, caption))
+		}
+	case atom.Hr:
+		return one(&notionapi.BlockSpec{Type: notionapi.BlockDivider}), nil
+	case atom.Table:
+		return one(table(n, "")), nil
+	}
+	return children(n)
+}
+
+func heading(blockType string, n *html.Node) *notionapi.BlockSpec {
+	return notionapi.NewTextSpec(blockType, childSpans(n))
+}
+
+// checkbox returns a checkbox of a list item: <input type="checkbox"> or
+// <div class="checkbox"> generated by tohtml
+func checkbox(li *html.Node) (*html.Node, bool) {
+	for c := li.FirstChild; c != nil; c = c.NextSibling {
+		if isElement(c, atom.Input) && strings.EqualFold(getAttr(c, "type"), "checkbox") {
+			return c, hasAttr(c, "checked")
+		}
+		if c.Type == html.ElementNode && hasClass(c, "checkbox") {
+			return c, hasClass(c, "checkbox-on")
+		}
+	}
+	return nil, false
+}
+
+func list(n *html.Node) ([]*notionapi.BlockSpec, error) {
+	blockType := notionapi.BlockBulletedList
+	if n.DataAtom == atom.Ol {
+		blockType = notionapi.BlockNumberedList
+	}
+	var res []*notionapi.BlockSpec
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !isElement(c, atom.Li) {
+			if !isInline(c) {
+				specs, err := blocks(c)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, specs...)
+			}
+			continue
+		}
+		box, checked := checkbox(c)
+		if box == nil {
+			spec, err := childrenWithTitle(blockType, c)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, spec)
+			continue
+		}
+		spec, err := childrenWithTitle(notionapi.BlockTodo, c)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Properties == nil {
+			spec.Properties = map[string]interface{}{}
+		}
+		spec.Properties["checked"] = [][]string{{"No"}}
+		if checked {
+			spec.Properties["checked"] = [][]string{{"Yes"}}
+		}
+		res = append(res, spec)
+	}
+	return res, nil
+}
+
+// codeLanguage returns language from language-${lang} (used by
+// markdown renderers) or lang-${lang} (used by tohtml) class
+func codeLanguage(nodes ...*html.Node) string {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		for _, cls := range strings.Fields(getAttr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(cls, prefix) {
+					return strings.TrimPrefix(cls, prefix)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func code(n *html.Node) *notionapi.BlockSpec {
+	codeNode := findElement(n, atom.Code)
+	lang := codeLanguage(codeNode, n)
+	// whitespace around <code> is formatting of html, not code
+	text := textContent(n)
+	if codeNode != nil {
+		text = textContent(codeNode)
+	}
+	return &notionapi.BlockSpec{
+		Type: notionapi.BlockCode,
+		Properties: map[string]interface{}{
+			"title":    [][]string{{strings.TrimSuffix(text, "\n")}},
+			"language": [][]string{{frommarkdown.CodeLanguage(lang)}},
+		},
+	}
+}
+
+func equation(n *html.Node) *notionapi.BlockSpec {
+	s := notionapi.TextSpansToString(childSpans(n))
+	return &notionapi.BlockSpec{
+		Type: notionapi.BlockEquation,
+		Properties: map[string]interface{}{
+			"title": [][]string{{s}},
+		},
+	}
+}
+
+func toggle(n *html.Node) (*notionapi.BlockSpec, error) {
+	spec := &notionapi.BlockSpec{Type: notionapi.BlockToggle}
+	var summary *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isElement(c, atom.Summary) {
+			summary = c
+			spec.Properties = map[string]interface{}{
+				"title": notionapi.EncodeTextSpans(childSpans(c)),
+			}
+			break
+		}
+	}
+	content, err := childrenExcept(n, summary)
+	if err != nil {
+		return nil, err
+	}
+	spec.Content = content
+	return spec, nil
+}
+
+// callout converts <aside> or <figure class="notion-callout"> generated
+// by tohtml, whose icon is in <span class="notion-figure-icon">
+func callout(n *html.Node) (*notionapi.BlockSpec, error) {
+	var icon string
+	wrap := findClass(n, "notion-figure-icon-wrap")
+	if wrap != nil {
+		icon = strings.TrimSpace(textContent(wrap))
+	}
+	content, err := childrenExcept(n, wrap)
+	if err != nil {
+		return nil, err
+	}
+	spec := withTitle(notionapi.BlockCallout, content)
+	if icon != "" {
+		spec.Format = map[string]interface{}{
+			"page_icon": icon,
+		}
+	}
+	return spec, nil
+}
+
+func image(img *html.Node, caption []*notionapi.TextSpan) (*notionapi.BlockSpec, error) {
+	src := getAttr(img, "src")
+	if src == "" {
+		return nil, fmt.Errorf("<img> without src")
+	}
+	spec := &notionapi.BlockSpec{
+		Type: notionapi.BlockImage,
+		Properties: map[string]interface{}{
+			"source": [][]string{{src}},
+		},
+		Format: map[string]interface{}{
+			"display_source": src,
+		},
+	}
+	if len(caption) > 0 {
+		spec.Properties["caption"] = notionapi.EncodeTextSpans(caption)
+	}
+	return spec, nil
+}
+
+// collectionView converts <div class="collection-content"> generated by
+// tohtml, with the name of the collection in <h4 class="collection-title">
+func collectionView(n *html.Node) *notionapi.BlockSpec {
+	t := findElement(n, atom.Table)
+	if t == nil {
+		return nil
+	}
+	name := ""
+	if h := findClass(n, "collection-title"); h != nil {
+		name = strings.TrimSpace(textContent(h))
+	}
+	return table(t, name)
+}
+
+// rows returns <tr> elements of a table, without rows of nested tables
+func rows(n *html.Node) []*html.Node {
+	var res []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case isElement(c, atom.Tr):
+			res = append(res, c)
+		case isElement(c, atom.Thead), isElement(c, atom.Tbody), isElement(c, atom.Tfoot):
+			res = append(res, rows(c)...)
+		}
+	}
+	return res
+}
+
+// table converts a table to a collection. The first row is the header
+func table(n *html.Node, name string) *notionapi.BlockSpec {
+	trs := rows(n)
+	if len(trs) == 0 {
+		return nil
+	}
+	coll := &notionapi.CollectionSpec{Name: name}
+	for i, tr := range trs {
+		var row [][]*notionapi.TextSpan
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if isElement(c, atom.Th) || isElement(c, atom.Td) {
+				spans := childSpans(c)
+				// tohtml writes &nbsp; in empty cells
+				if len(spans) == 1 && len(spans[0].Attrs) == 0 && spans[0].Text == "\u00a0" {
+					spans = nil
+				}
+				row = append(row, spans)
+			}
+		}
+		if i > 0 {
+			coll.Rows = append(coll.Rows, row)
+			continue
+		}
+		for _, spans := range row {
+			coll.Columns = append(coll.Columns, notionapi.TextSpansToString(spans))
+		}
+	}
+	return &notionapi.BlockSpec{
+		Type:       notionapi.BlockCollectionView,
+		Collection: coll,
+	}
+}
+
+// FromNode converts html node, usually a document returned by html.Parse,
+// to blocks
+func FromNode(n *html.Node) ([]*notionapi.BlockSpec, error) {
+	if isInline(n) {
+		return inlineBlocks([]*html.Node{n})
+	}
+	return blocks(n)
+}
+
+// Parse parses html into blocks
+func Parse(r io.Reader) ([]*notionapi.BlockSpec, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromNode(doc)
+}
+
+// ToOps converts html into operations that add blocks at the end of
+// parent, as user userID. Submit them with Client.SubmitTransaction or
+// add them to a notionapi.Transaction
+func ToOps(userID string, parent *notionapi.Block, r io.Reader) ([]*notionapi.Operation, error) {
+	blocks, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return notionapi.NewBlocksOps(userID, parent, blocks), nil
+}
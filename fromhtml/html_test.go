@@ -0,0 +1,211 @@
+package fromhtml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/notiontest"
+	"github.com/kjk/notionapi/tohtml"
+)
+
+const testHTML = `<html><body>
+<h1>Title</h1>
+<h2>Sub <i>title</i></h2>
+<p>Some <b>bold</b>, <i>italic</i>, <s>struck</s>, <code>code</code>,
+	<a href="https://example.com">a <strong>link</strong></a> and <mark>marked</mark> text.</p>
+<ul>
+	<li>one</li>
+	<li>two
+		<ul><li>nested</li></ul>
+	</li>
+</ul>
+<ol><li>first</li><li>second</li></ol>
+<ul>
+	<li><input type="checkbox" checked> done</li>
+	<li><input type="checkbox"> todo</li>
+</ul>
+<pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+<blockquote>quoted</blockquote>
+<hr>
+<details><summary>more</summary><p>hidden</p></details>
+<aside>note</aside>
+<figure><img src="https://example.com/a.png"><figcaption>caption</figcaption></figure>
+<table>
+	<thead><tr><th>Name</th><th>Age</th></tr></thead>
+	<tbody><tr><td>Bob</td><td>42</td></tr></tbody>
+</table>
+</body></html>`
+
+func formatSpans(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	// normalize [][]string etc. to []interface{}
+	d, _ := json.Marshal(v)
+	var raw interface{}
+	_ = json.Unmarshal(d, &raw)
+	spans, _ := notionapi.ParseTextSpans(raw)
+	var parts []string
+	for _, ts := range spans {
+		var attrs []string
+		for _, attr := range ts.Attrs {
+			attrs = append(attrs, strings.Join(attr, ":"))
+		}
+		// tohtml nests tags in reverse order of attributes
+		sort.Strings(attrs)
+		s := fmt.Sprintf("%q", ts.Text)
+		if len(attrs) > 0 {
+			s += "[" + strings.Join(attrs, ",") + "]"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// summary returns a description of blocks independent of their ids
+func summary(blocks []*notionapi.BlockSpec, indent string) string {
+	var sb strings.Builder
+	for _, b := range blocks {
+		sb.WriteString(indent + b.Type)
+		keys := make([]string, 0, len(b.Properties))
+		for k := range b.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sb.WriteString(fmt.Sprintf(" %s=%s", k, formatSpans(b.Properties[k])))
+		}
+		if c := b.Collection; c != nil {
+			sb.WriteString(fmt.Sprintf(" columns=%q", c.Columns))
+			for _, row := range c.Rows {
+				var cells []string
+				for _, spans := range row {
+					cells = append(cells, notionapi.TextSpansToString(spans))
+				}
+				sb.WriteString(fmt.Sprintf(" row=%q", cells))
+			}
+		}
+		sb.WriteString("\n")
+		sb.WriteString(summary(b.Content, indent+"  "))
+	}
+	return sb.String()
+}
+
+func TestParse(t *testing.T) {
+	blocks, err := Parse(strings.NewReader(testHTML))
+	assert.NoError(t, err)
+	exp := `header title="Title"
+sub_header title="Sub " "title"[i]
+text title="Some " "bold"[b] ", " "italic"[i] ", " "struck"[s] ", " "code"[c] ", " "a "[a:https://example.com] "link"[a:https://example.com,b] " and " "marked"[h:yellow_background] " text."
+bulleted_list title="one"
+bulleted_list title="two"
+  bulleted_list title="nested"
+numbered_list title="first"
+numbered_list title="second"
+to_do checked="Yes" title="done"
+to_do checked="No" title="todo"
+code language="Go" title="func main() {\n\tfmt.Println(\"hi\")\n}"
+quote title="quoted"
+divider
+toggle title="more"
+  text title="hidden"
+callout title="note"
+image caption="caption" source="https://example.com/a.png"
+collection_view columns=["Name" "Age"] row=["Bob" "42"]
+`
+	assert.Equal(t, exp, summary(blocks, ""))
+}
+
+func TestWhitespace(t *testing.T) {
+	blocks, err := Parse(strings.NewReader("<p>  a\n  <b> b </b>  c<br>\n d  </p>"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(blocks))
+	assert.Equal(t, `text title="a " "b "[b] "c\nd"`+"\n", summary(blocks, ""))
+}
+
+func TestInlineImages(t *testing.T) {
+	html := `<p>A <img src="https://example.com/logo.png" alt="logo"> inline and <a href="https://example.com"><img src="https://example.com/b.png"></a> linked</p>
+<p><img src="https://example.com/c.png"></p>
+<a href="https://example.com/d.png"><img src="https://example.com/d.png"></a>`
+	blocks, err := Parse(strings.NewReader(html))
+	assert.NoError(t, err)
+	exp := `text title="A " "logo"[a:https://example.com/logo.png] " inline and " "https://example.com/b.png"[a:https://example.com] " linked"
+image source="https://example.com/c.png"
+image source="https://example.com/d.png"
+`
+	assert.Equal(t, exp, summary(blocks, ""))
+}
+
+func TestImageWithoutSrc(t *testing.T) {
+	_, err := Parse(strings.NewReader(`<p><img alt="missing"></p>`))
+	assert.Error(t, err)
+	_, err = Parse(strings.NewReader(`<figure><img><figcaption>caption</figcaption></figure>`))
+	assert.Error(t, err)
+
+	// there's nothing to link to but the text is kept
+	blocks, err := Parse(strings.NewReader(`<p>an <img alt="image"> in text</p>`))
+	assert.NoError(t, err)
+	assert.Equal(t, `text title="an image in text"`+"\n", summary(blocks, ""))
+}
+
+// roundTrip converts blocks to a notion page, renders it with tohtml and
+// returns blocks parsed from that html
+func roundTrip(t *testing.T, blocks []*notionapi.BlockSpec) []*notionapi.BlockSpec {
+	s, err := notiontest.NewServerFromCacheDir("../caching_client_testdata")
+	assert.NoError(t, err)
+	defer s.Close()
+	client := s.NewClient()
+
+	root, err := client.DownloadPage("6682351e44bb4f9ca0e149b703265bdb")
+	assert.NoError(t, err)
+
+	page := notionapi.NewTextSpec(notionapi.BlockPage, []*notionapi.TextSpan{{Text: "Imported"}})
+	page.Content = blocks
+	ops := notionapi.NewBlocksOps("user", root.Root(), []*notionapi.BlockSpec{page})
+	assert.NoError(t, client.SubmitTransaction(ops))
+
+	p, err := client.DownloadPage(page.ID)
+	assert.NoError(t, err)
+	got, err := Parse(bytes.NewReader(tohtml.ToHTML(p)))
+	assert.NoError(t, err)
+	return got
+}
+
+// html -> blocks -> notion page -> tohtml -> blocks must give the same blocks
+func TestRoundTrip(t *testing.T) {
+	blocks, err := Parse(strings.NewReader(testHTML))
+	assert.NoError(t, err)
+	got := roundTrip(t, blocks)
+	assert.Equal(t, summary(blocks, ""), summary(got, ""))
+}
+
+func TestRoundTripImagesCalloutsCollections(t *testing.T) {
+	html := `<p>Text with <img src="https://example.com/logo.png" alt="logo"> inside</p>
+<p><img src="https://example.com/a.png"></p>
+<figure class="notion-callout"><div class="notion-figure-icon-wrap"><span class="notion-figure-icon">💡</span></div><div>a <b>tip</b> with <img src="https://example.com/tip.png" alt="icon"></div></figure>
+<div class="collection-content"><h4 class="collection-title">People</h4><table>
+	<thead><tr><th>Name</th><th>Site</th></tr></thead>
+	<tbody><tr><td>Bob</td><td><a href="https://bob.com">bob.com</a></td></tr><tr><td>Alice</td><td></td></tr></tbody>
+</table></div>`
+	blocks, err := Parse(strings.NewReader(html))
+	assert.NoError(t, err)
+	exp := `text title="Text with " "logo"[a:https://example.com/logo.png] " inside"
+image source="https://example.com/a.png"
+callout title="a " "tip"[b] " with " "icon"[a:https://example.com/tip.png]
+collection_view columns=["Name" "Site"] row=["Bob" "bob.com"] row=["Alice" ""]
+`
+	assert.Equal(t, exp, summary(blocks, ""))
+	assert.Equal(t, "💡", blocks[2].Format["page_icon"])
+	assert.Equal(t, "People", blocks[3].Collection.Name)
+
+	got := roundTrip(t, blocks)
+	assert.Equal(t, exp, summary(got, ""))
+}
@@ -0,0 +1,184 @@
+package fromhtml
+
+import (
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// highlight used for <mark> without highlight-${color} class
+const defaultHighlight = "yellow_background"
+
+// inlineBuilder converts inline html (<b>, <a> etc.) to text spans
+type inlineBuilder struct {
+	spans []*notionapi.TextSpan
+}
+
+func sameAttrs(a, b []notionapi.TextAttr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.Join(a[i], "\x00") != strings.Join(b[i], "\x00") {
+			return false
+		}
+	}
+	return true
+}
+
+func withAttr(attrs []notionapi.TextAttr, attr ...string) []notionapi.TextAttr {
+	res := append([]notionapi.TextAttr(nil), attrs...)
+	return append(res, notionapi.TextAttr(attr))
+}
+
+func hasLink(attrs []notionapi.TextAttr) bool {
+	for _, attr := range attrs {
+		if len(attr) > 0 && attr[0] == notionapi.AttrLink {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseSpace replaces runs of whitespace with a single space, like
+// browsers do outside of <pre>
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	wasSpace := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !wasSpace {
+				sb.WriteByte(' ')
+			}
+			wasSpace = true
+			continue
+		}
+		sb.WriteRune(r)
+		wasSpace = false
+	}
+	return sb.String()
+}
+
+// atLineStart returns true if a space emitted now would be at the start
+// of a line or follow another space
+func (b *inlineBuilder) atLineStart() bool {
+	n := len(b.spans)
+	if n == 0 {
+		return true
+	}
+	s := b.spans[n-1].Text
+	return strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n")
+}
+
+func (b *inlineBuilder) emit(text string, attrs []notionapi.TextAttr) {
+	if strings.HasPrefix(text, " ") && b.atLineStart() {
+		text = text[1:]
+	}
+	if text == "" {
+		return
+	}
+	if n := len(b.spans); n > 0 && sameAttrs(b.spans[n-1].Attrs, attrs) {
+		b.spans[n-1].Text += text
+		return
+	}
+	ts := &notionapi.TextSpan{Text: text}
+	if len(attrs) > 0 {
+		ts.Attrs = append([]notionapi.TextAttr(nil), attrs...)
+	}
+	b.spans = append(b.spans, ts)
+}
+
+// highlightColor returns color of <mark class="highlight-${color}">
+func highlightColor(n *html.Node) string {
+	for _, cls := range strings.Fields(getAttr(n, "class")) {
+		if strings.HasPrefix(cls, "highlight-") {
+			return strings.TrimPrefix(cls, "highlight-")
+		}
+	}
+	return defaultHighlight
+}
+
+func (b *inlineBuilder) add(n *html.Node, attrs []notionapi.TextAttr) {
+	switch n.Type {
+	case html.TextNode:
+		b.emit(collapseSpace(n.Data), attrs)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		// a line break replaces a preceding space
+		if len(b.spans) > 0 {
+			last := b.spans[len(b.spans)-1]
+			last.Text = strings.TrimSuffix(last.Text, " ")
+		}
+		b.emit("\n", attrs)
+		return
+	case atom.Script, atom.Style, atom.Input:
+		return
+	case atom.Img:
+		// Notion text can't have images, we link to it
+		src := getAttr(n, "src")
+		text := collapseSpace(getAttr(n, "alt"))
+		if text == "" {
+			text = src
+		}
+		if src != "" && !hasLink(attrs) {
+			attrs = withAttr(attrs, notionapi.AttrLink, src)
+		}
+		b.emit(text, attrs)
+		return
+	case atom.B, atom.Strong:
+		attrs = withAttr(attrs, notionapi.AttrBold)
+	case atom.I, atom.Em:
+		attrs = withAttr(attrs, notionapi.AttrItalic)
+	case atom.S, atom.Del, atom.Strike:
+		attrs = withAttr(attrs, notionapi.AttrStrikeThrought)
+	case atom.Code, atom.Kbd, atom.Samp:
+		attrs = withAttr(attrs, notionapi.AttrCode)
+	case atom.Mark:
+		attrs = withAttr(attrs, notionapi.AttrHighlight, highlightColor(n))
+	case atom.A:
+		if href := getAttr(n, "href"); href != "" {
+			attrs = withAttr(attrs, notionapi.AttrLink, href)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.add(c, attrs)
+	}
+}
+
+// trim removes trailing whitespace and spans that became empty
+func (b *inlineBuilder) trim() {
+	for len(b.spans) > 0 {
+		last := b.spans[len(b.spans)-1]
+		last.Text = strings.TrimRight(last.Text, " \n")
+		if last.Text != "" {
+			return
+		}
+		b.spans = b.spans[:len(b.spans)-1]
+	}
+}
+
+// inlineSpans converts inline nodes to text spans
+func inlineSpans(nodes ...*html.Node) []*notionapi.TextSpan {
+	b := &inlineBuilder{}
+	for _, n := range nodes {
+		b.add(n, nil)
+	}
+	b.trim()
+	return b.spans
+}
+
+// childSpans converts inline content of n to text spans
+func childSpans(n *html.Node) []*notionapi.TextSpan {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return inlineSpans(nodes...)
+}
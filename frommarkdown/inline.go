@@ -0,0 +1,167 @@
+package frommarkdown
+
+import (
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// inlineParser converts inline nodes (emphasis, code, links) to text spans
+type inlineParser struct {
+	source []byte
+	spans  []*notionapi.TextSpan
+}
+
+func sameAttrs(a, b []notionapi.TextAttr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.Join(a[i], "\x00") != strings.Join(b[i], "\x00") {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *inlineParser) emit(text string, attrs []notionapi.TextAttr) {
+	if text == "" {
+		return
+	}
+	if n := len(p.spans); n > 0 && sameAttrs(p.spans[n-1].Attrs, attrs) {
+		p.spans[n-1].Text += text
+		return
+	}
+	ts := &notionapi.TextSpan{Text: text}
+	if len(attrs) > 0 {
+		ts.Attrs = append([]notionapi.TextAttr(nil), attrs...)
+	}
+	p.spans = append(p.spans, ts)
+}
+
+func withAttr(attrs []notionapi.TextAttr, attr ...string) []notionapi.TextAttr {
+	res := append([]notionapi.TextAttr(nil), attrs...)
+	return append(res, notionapi.TextAttr(attr))
+}
+
+// unescape resolves backslash escapes and entities, like the text
+// rendered by goldmark
+func unescape(b []byte) string {
+	b = util.UnescapePunctuations(b)
+	b = util.ResolveNumericReferences(b)
+	return string(util.ResolveEntityNames(b))
+}
+
+// codeText returns text of a code span, line breaks become spaces
+func (p *inlineParser) codeText(n *ast.CodeSpan) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			v := t.Segment.Value(p.source)
+			if len(v) > 0 && v[len(v)-1] == '\n' {
+				sb.Write(v[:len(v)-1])
+				sb.WriteByte(' ')
+				continue
+			}
+			sb.Write(v)
+		}
+	}
+	return sb.String()
+}
+
+func (p *inlineParser) walk(n ast.Node, attrs []notionapi.TextAttr) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			v := c.Segment.Value(p.source)
+			if c.IsRaw() {
+				p.emit(string(v), attrs)
+			} else {
+				p.emit(unescape(v), attrs)
+			}
+			if c.HardLineBreak() {
+				p.emit("\n", attrs)
+			} else if c.SoftLineBreak() {
+				p.emit(" ", attrs)
+			}
+		case *ast.String:
+			p.emit(string(c.Value), attrs)
+		case *ast.CodeSpan:
+			p.emit(p.codeText(c), withAttr(attrs, notionapi.AttrCode))
+		case *ast.Emphasis:
+			attr := notionapi.AttrItalic
+			if c.Level > 1 {
+				attr = notionapi.AttrBold
+			}
+			p.walk(c, withAttr(attrs, attr))
+		case *extast.Strikethrough:
+			p.walk(c, withAttr(attrs, notionapi.AttrStrikeThrought))
+		case *ast.Link:
+			p.walk(c, withAttr(attrs, notionapi.AttrLink, unescape(c.Destination)))
+		case *ast.Image:
+			// Notion text can't have images, we link to it
+			p.walk(c, withAttr(attrs, notionapi.AttrLink, unescape(c.Destination)))
+		case *ast.AutoLink:
+			uri := string(c.URL(p.source))
+			label := strings.TrimPrefix(string(c.Label(p.source)), "mailto:")
+			if c.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(uri, "mailto:") {
+				uri = "mailto:" + uri
+			}
+			p.emit(label, withAttr(attrs, notionapi.AttrLink, uri))
+		case *ast.RawHTML:
+			for i := 0; i < c.Segments.Len(); i++ {
+				seg := c.Segments.At(i)
+				p.emit(string(seg.Value(p.source)), attrs)
+			}
+		case *extast.TaskCheckBox:
+			// handled by the list item
+		default:
+			p.walk(c, attrs)
+		}
+	}
+}
+
+// trimSpans removes white space at the start and end of text
+func trimSpans(spans []*notionapi.TextSpan) []*notionapi.TextSpan {
+	for len(spans) > 0 {
+		spans[0].Text = strings.TrimLeft(spans[0].Text, " \t\n")
+		if spans[0].Text != "" {
+			break
+		}
+		spans = spans[1:]
+	}
+	for len(spans) > 0 {
+		last := spans[len(spans)-1]
+		last.Text = strings.TrimRight(last.Text, " \t\n")
+		if last.Text != "" {
+			break
+		}
+		spans = spans[:len(spans)-1]
+	}
+	return spans
+}
+
+// inlineSpans returns text spans of inline children of n
+func inlineSpans(source []byte, n ast.Node) []*notionapi.TextSpan {
+	p := &inlineParser{source: source}
+	p.walk(n, nil)
+	return trimSpans(p.spans)
+}
+
+// ParseInline converts inline markdown to text spans
+func ParseInline(s string) []*notionapi.TextSpan {
+	source := []byte(s)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	p := &inlineParser{source: source}
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if c != doc.FirstChild() {
+			p.emit("\n", nil)
+		}
+		p.walk(c, nil)
+	}
+	return trimSpans(p.spans)
+}
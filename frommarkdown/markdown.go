@@ -0,0 +1,351 @@
+// Package frommarkdown converts Markdown (CommonMark with GitHub's tables,
+// task lists, strikethrough and autolinks, and $$ math blocks) into
+// operations that create Notion blocks. Markdown is parsed with goldmark
+package frommarkdown
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kjk/notionapi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathParser{}, 750)),
+	),
+)
+
+// codeLanguages maps names of languages in fenced code blocks to
+// languages known by Notion
+var codeLanguages = map[string]string{
+	"bash":       "Bash",
+	"c":          "C",
+	"c++":        "C++",
+	"cpp":        "C++",
+	"cs":         "C#",
+	"csharp":     "C#",
+	"css":        "CSS",
+	"diff":       "Diff",
+	"go":         "Go",
+	"golang":     "Go",
+	"html":       "HTML",
+	"java":       "Java",
+	"javascript": "JavaScript",
+	"js":         "JavaScript",
+	"json":       "JSON",
+	"kotlin":     "Kotlin",
+	"latex":      "LaTeX",
+	"makefile":   "Makefile",
+	"markdown":   "Markdown",
+	"md":         "Markdown",
+	"php":        "PHP",
+	"plain":      notionapi.CodeLanguagePlainText,
+	"plaintext":  notionapi.CodeLanguagePlainText,
+	"py":         "Python",
+	"python":     "Python",
+	"rb":         "Ruby",
+	"ruby":       "Ruby",
+	"rust":       "Rust",
+	"scss":       "Sass",
+	"sh":         "Shell",
+	"shell":      "Shell",
+	"sql":        "SQL",
+	"swift":      "Swift",
+	"text":       notionapi.CodeLanguagePlainText,
+	"ts":         "TypeScript",
+	"typescript": "TypeScript",
+	"xml":        "XML",
+	"yaml":       "YAML",
+	"yml":        "YAML",
+}
+
+// CodeLanguage returns Notion's name of a language of a fenced code block
+func CodeLanguage(lang string) string {
+	if lang == "" {
+		return notionapi.CodeLanguagePlainText
+	}
+	if s, ok := codeLanguages[strings.ToLower(lang)]; ok {
+		return s
+	}
+	return lang
+}
+
+// Converter converts markdown to Notion blocks
+type Converter struct {
+	// id of the user creating blocks
+	UserID string
+	// used to upload local images with UploadFile. If nil, local
+	// images are an error
+	Client *notionapi.Client
+	// directory that relative paths of local images are relative to
+	BaseDir string
+}
+
+// NewConverter returns a converter that creates blocks as user userID
+func NewConverter(userID string) *Converter {
+	return &Converter{
+		UserID: userID,
+	}
+}
+
+// blockConverter converts goldmark's ast to blocks
+type blockConverter struct {
+	c      *Converter
+	source []byte
+}
+
+func heading(level int, spans []*notionapi.TextSpan) *notionapi.BlockSpec {
+	types := []string{notionapi.BlockHeader, notionapi.BlockSubHeader, notionapi.BlockSubSubHeader}
+	if level > len(types) {
+		level = len(types)
+	}
+	return notionapi.NewTextSpec(types[level-1], spans)
+}
+
+func codeSpec(lang string, code string) *notionapi.BlockSpec {
+	return &notionapi.BlockSpec{
+		Type: notionapi.BlockCode,
+		Properties: map[string]interface{}{
+			"title":    [][]string{{code}},
+			"language": [][]string{{CodeLanguage(lang)}},
+		},
+	}
+}
+
+// lines returns text of a code block without the last new line
+func (p *blockConverter) lines(n ast.Node) string {
+	var sb strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		sb.Write(seg.Value(p.source))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// withTitle makes the first block of blocks, if it's text, a title of
+// a block of type blockType and the rest its children
+func withTitle(blockType string, blocks []*notionapi.BlockSpec) *notionapi.BlockSpec {
+	spec := &notionapi.BlockSpec{Type: blockType}
+	if len(blocks) > 0 && blocks[0].Type == notionapi.BlockText {
+		spec.Properties = blocks[0].Properties
+		blocks = blocks[1:]
+	}
+	spec.Content = blocks
+	return spec
+}
+
+// blocks converts children of n
+func (p *blockConverter) blocks(n ast.Node) ([]*notionapi.BlockSpec, error) {
+	var res []*notionapi.BlockSpec
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		specs, err := p.block(c)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, specs...)
+	}
+	return res, nil
+}
+
+func (p *blockConverter) block(n ast.Node) ([]*notionapi.BlockSpec, error) {
+	var spec *notionapi.BlockSpec
+	var err error
+	switch n := n.(type) {
+	case *ast.Heading:
+		spec = heading(n.Level, inlineSpans(p.source, n))
+	case *ast.Paragraph, *ast.TextBlock:
+		if img := p.onlyImage(n); img != nil {
+			spec, err = p.image(inlineSpans(p.source, img), unescape(img.Destination))
+		} else {
+			spec = notionapi.NewTextSpec(notionapi.BlockText, inlineSpans(p.source, n))
+		}
+	case *ast.ThematicBreak:
+		spec = &notionapi.BlockSpec{Type: notionapi.BlockDivider}
+	case *ast.FencedCodeBlock:
+		spec = codeSpec(string(n.Language(p.source)), p.lines(n))
+	case *ast.CodeBlock:
+		spec = codeSpec("", p.lines(n))
+	case *ast.HTMLBlock:
+		html := p.lines(n)
+		if n.HasClosure() {
+			html += "\n" + strings.TrimRight(string(n.ClosureLine.Value(p.source)), "\n")
+		}
+		spec = notionapi.NewTextSpec(notionapi.BlockText, []*notionapi.TextSpan{{Text: html}})
+	case *mathBlock:
+		spec = &notionapi.BlockSpec{
+			Type: notionapi.BlockEquation,
+			Properties: map[string]interface{}{
+				"title": [][]string{{strings.TrimSpace(strings.Join(n.tex, "\n"))}},
+			},
+		}
+	case *ast.Blockquote:
+		var children []*notionapi.BlockSpec
+		if children, err = p.blocks(n); err == nil {
+			spec = withTitle(notionapi.BlockQuote, children)
+		}
+	case *ast.List:
+		var res []*notionapi.BlockSpec
+		for item := n.FirstChild(); item != nil && err == nil; item = item.NextSibling() {
+			spec, err = p.listItem(n, item)
+			res = append(res, spec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	case *extast.Table:
+		spec = p.table(n)
+	default:
+		return nil, fmt.Errorf("unsupported markdown block %s", n.Kind())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*notionapi.BlockSpec{spec}, nil
+}
+
+// onlyImage returns an image if it's the only thing in a paragraph
+func (p *blockConverter) onlyImage(n ast.Node) *ast.Image {
+	var img *ast.Image
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if i, ok := c.(*ast.Image); ok && img == nil {
+			img = i
+			continue
+		}
+		if t, ok := c.(*ast.Text); ok && strings.TrimSpace(string(t.Segment.Value(p.source))) == "" {
+			continue
+		}
+		return nil
+	}
+	return img
+}
+
+func (p *blockConverter) listItem(list *ast.List, item ast.Node) (*notionapi.BlockSpec, error) {
+	blockType := notionapi.BlockBulletedList
+	if list.IsOrdered() {
+		blockType = notionapi.BlockNumberedList
+	}
+	checked := ""
+	if first := item.FirstChild(); first != nil {
+		if cb, ok := first.FirstChild().(*extast.TaskCheckBox); ok {
+			blockType = notionapi.BlockTodo
+			checked = "No"
+			if cb.IsChecked {
+				checked = "Yes"
+			}
+		}
+	}
+	children, err := p.blocks(item)
+	if err != nil {
+		return nil, err
+	}
+	spec := withTitle(blockType, children)
+	if checked != "" {
+		if spec.Properties == nil {
+			spec.Properties = map[string]interface{}{}
+		}
+		spec.Properties["checked"] = [][]string{{checked}}
+	}
+	return spec, nil
+}
+
+func (p *blockConverter) table(n *extast.Table) *notionapi.BlockSpec {
+	coll := &notionapi.CollectionSpec{}
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		_, isHeader := row.(*extast.TableHeader)
+		var cells [][]*notionapi.TextSpan
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			spans := inlineSpans(p.source, cell)
+			if isHeader {
+				coll.Columns = append(coll.Columns, notionapi.TextSpansToString(spans))
+			}
+			cells = append(cells, spans)
+		}
+		if !isHeader {
+			coll.Rows = append(coll.Rows, cells)
+		}
+	}
+	return &notionapi.BlockSpec{
+		Type:       notionapi.BlockCollectionView,
+		Collection: coll,
+	}
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "data:")
+}
+
+// image returns a block for an image. Local images are uploaded
+func (p *blockConverter) image(alt []*notionapi.TextSpan, src string) (*notionapi.BlockSpec, error) {
+	spec := &notionapi.BlockSpec{
+		Type:       notionapi.BlockImage,
+		Properties: map[string]interface{}{},
+	}
+	if !isURL(src) {
+		fileID, fileURL, err := p.c.uploadImage(src)
+		if err != nil {
+			return nil, err
+		}
+		spec.FileIDs = []string{fileID}
+		src = fileURL
+	}
+	spec.Properties["source"] = [][]string{{src}}
+	if len(alt) > 0 {
+		spec.Properties["caption"] = notionapi.EncodeTextSpans(alt)
+	}
+	spec.Format = map[string]interface{}{
+		"display_source": src,
+	}
+	return spec, nil
+}
+
+func (c *Converter) uploadImage(path string) (string, string, error) {
+	if c.Client == nil {
+		return "", "", fmt.Errorf("can't upload image '%s' without Client", path)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.BaseDir, filepath.FromSlash(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	return c.Client.UploadFile(f)
+}
+
+// Parse parses markdown into blocks. Local images are uploaded
+func (c *Converter) Parse(md []byte) ([]*notionapi.BlockSpec, error) {
+	source := []byte(strings.Replace(string(md), "\r\n", "\n", -1))
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	p := &blockConverter{c: c, source: source}
+	return p.blocks(doc)
+}
+
+// ToOps converts markdown into operations that add blocks at the end of
+// parent. Submit them with Client.SubmitTransaction or add them to
+// a notionapi.Transaction
+func (c *Converter) ToOps(parent *notionapi.Block, md []byte) ([]*notionapi.Operation, error) {
+	blocks, err := c.Parse(md)
+	if err != nil {
+		return nil, err
+	}
+	return notionapi.NewBlocksOps(c.UserID, parent, blocks), nil
+}
+
+// ToOps is like Converter.ToOps, for markdown without local images
+func ToOps(userID string, parent *notionapi.Block, md []byte) ([]*notionapi.Operation, error) {
+	return NewConverter(userID).ToOps(parent, md)
+}
@@ -0,0 +1,212 @@
+package frommarkdown
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func title(spec *notionapi.BlockSpec) string {
+	v, _ := spec.Properties["title"].([]interface{})
+	var s string
+	for _, el := range v {
+		s += el.([]interface{})[0].(string)
+	}
+	return s
+}
+
+func TestParseInline(t *testing.T) {
+	spans := ParseInline("a **b** *c* ***d*** ~~e~~ `f` [g](http://g.com) <https://h.com> snake_case_name \\*i\\*")
+	var texts []string
+	var attrs [][]notionapi.TextAttr
+	for _, ts := range spans {
+		texts = append(texts, ts.Text)
+		attrs = append(attrs, ts.Attrs)
+	}
+	exp := []string{"a ", "b", " ", "c", " ", "d", " ", "e", " ", "f", " ", "g", " ", "https://h.com", " snake_case_name *i*"}
+	assert.Equal(t, exp, texts)
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrBold}}, attrs[1])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrItalic}}, attrs[3])
+	assert.Equal(t, 2, len(attrs[5]))
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrStrikeThrought}}, attrs[7])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrCode}}, attrs[9])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrLink, "http://g.com"}}, attrs[11])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrLink, "https://h.com"}}, attrs[13])
+	assert.Nil(t, attrs[14])
+}
+
+func TestParse(t *testing.T) {
+	md := `# Title
+
+Setext
+------
+
+A paragraph
+on two lines.
+
+- one
+- two
+  - nested
+1. first
+2. second
+
+- [ ] todo
+- [x] done
+
+` + "```go\nfunc main() {}\n```" + `
+
+> quoted
+
+---
+
+| Name | Age |
+| ---- | --: |
+| Bob  | 42  |
+
+![alt](https://example.com/a.png)
+
+$$
+e^{i\pi} = -1
+$$
+`
+	blocks, err := NewConverter("u").Parse([]byte(md))
+	assert.NoError(t, err)
+	var types []string
+	for _, b := range blocks {
+		types = append(types, b.Type)
+	}
+	exp := []string{
+		notionapi.BlockHeader,
+		notionapi.BlockSubHeader,
+		notionapi.BlockText,
+		notionapi.BlockBulletedList,
+		notionapi.BlockBulletedList,
+		notionapi.BlockNumberedList,
+		notionapi.BlockNumberedList,
+		notionapi.BlockTodo,
+		notionapi.BlockTodo,
+		notionapi.BlockCode,
+		notionapi.BlockQuote,
+		notionapi.BlockDivider,
+		notionapi.BlockCollectionView,
+		notionapi.BlockImage,
+		notionapi.BlockEquation,
+	}
+	assert.Equal(t, exp, types)
+
+	assert.Equal(t, "Title", title(blocks[0]))
+	assert.Equal(t, "Setext", title(blocks[1]))
+	assert.Equal(t, "A paragraph on two lines.", title(blocks[2]))
+
+	nested := blocks[4].Content
+	assert.Equal(t, 1, len(nested))
+	assert.Equal(t, "nested", title(nested[0]))
+
+	assert.Equal(t, "todo", title(blocks[7]))
+	assert.Equal(t, [][]string{{"No"}}, blocks[7].Properties["checked"])
+	assert.Equal(t, [][]string{{"Yes"}}, blocks[8].Properties["checked"])
+
+	assert.Equal(t, [][]string{{"func main() {}"}}, blocks[9].Properties["title"])
+	assert.Equal(t, [][]string{{"Go"}}, blocks[9].Properties["language"])
+	assert.Equal(t, "quoted", title(blocks[10]))
+
+	coll := blocks[12].Collection
+	assert.Equal(t, []string{"Name", "Age"}, coll.Columns)
+	assert.Equal(t, 1, len(coll.Rows))
+	assert.Equal(t, "42", coll.Rows[0][1][0].Text)
+
+	assert.Equal(t, [][]string{{"https://example.com/a.png"}}, blocks[13].Properties["source"])
+	assert.Equal(t, [][]string{{`e^{i\pi} = -1`}}, blocks[14].Properties["title"])
+}
+
+func spanAttrs(spans []*notionapi.TextSpan) ([]string, [][]notionapi.TextAttr) {
+	var texts []string
+	var attrs [][]notionapi.TextAttr
+	for _, ts := range spans {
+		texts = append(texts, ts.Text)
+		attrs = append(attrs, ts.Attrs)
+	}
+	return texts, attrs
+}
+
+func TestParseInlineCommonMark(t *testing.T) {
+	// link destinations can have balanced parentheses
+	spans := ParseInline("[Go](https://en.wikipedia.org/wiki/Go_(programming_language)) is")
+	texts, attrs := spanAttrs(spans)
+	assert.Equal(t, []string{"Go", " is"}, texts)
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrLink, "https://en.wikipedia.org/wiki/Go_(programming_language)"}}, attrs[0])
+
+	// nested emphasis
+	texts, attrs = spanAttrs(ParseInline("*a **b** c* **d *e***"))
+	assert.Equal(t, []string{"a ", "b", " c", " ", "d ", "e"}, texts)
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrItalic}}, attrs[0])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrItalic}, {notionapi.AttrBold}}, attrs[1])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrItalic}}, attrs[2])
+	assert.Nil(t, attrs[3])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrBold}}, attrs[4])
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrBold}, {notionapi.AttrItalic}}, attrs[5])
+
+	// hard line breaks, an inline image is a link
+	texts, attrs = spanAttrs(ParseInline("a\\\nb  \nc ![img](https://example.com/a.png)"))
+	assert.Equal(t, []string{"a\nb\nc ", "img"}, texts)
+	assert.Equal(t, []notionapi.TextAttr{{notionapi.AttrLink, "https://example.com/a.png"}}, attrs[1])
+
+	texts, _ = spanAttrs(ParseInline("<mailto:a@b.com> and https://c.com"))
+	assert.Equal(t, []string{"a@b.com", " and ", "https://c.com"}, texts)
+}
+
+func TestParseLazyContinuation(t *testing.T) {
+	md := "> quoted\nlazy line\n\n- item\ncontinued\n"
+	blocks, err := NewConverter("u").Parse([]byte(md))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(blocks))
+	assert.Equal(t, "quoted lazy line", title(blocks[0]))
+	assert.Equal(t, "item continued", title(blocks[1]))
+}
+
+func TestParseLocalImageWithoutClient(t *testing.T) {
+	_, err := NewConverter("u").Parse([]byte("![img](img/a.png)\n"))
+	assert.Error(t, err)
+	_, err = NewConverter("u").Parse([]byte("- a\n\n  ![img](img/a.png)\n\n> ![img](img/b.png)\n"))
+	assert.Error(t, err)
+}
+
+func TestToOps(t *testing.T) {
+	parent := &notionapi.Block{ID: "3b617da4-0945-4a52-bc3a-920ba8832bf7", SpaceID: "space"}
+	md := "- a\n  - b\n\n| x | y |\n|---|---|\n| 1 | 2 |\n"
+	ops, err := ToOps("user", parent, []byte(md))
+	assert.NoError(t, err)
+
+	var sets, listAfter int
+	created := map[string]string{}
+	for _, op := range ops {
+		assert.NoError(t, notionapi.ValidateOperation(op))
+		switch op.Command {
+		case notionapi.CommandSet:
+			sets++
+			args := op.Args.(map[string]interface{})
+			// parents are created before their children, including
+			// the collection, its view and rows of the table
+			parentID := args["parent_id"].(string)
+			assert.True(t, parentID == parent.ID || created[parentID] != "", op.Table)
+			if parentID != parent.ID {
+				assert.Equal(t, created[parentID], args["parent_table"])
+			}
+			created[op.ID] = op.Table
+		case notionapi.CommandListAfter:
+			listAfter++
+			assert.Equal(t, parent.ID, op.ID)
+		}
+	}
+	// 2 list items, table block, collection, view and a row
+	assert.Equal(t, 6, sets)
+	tables := map[string]int{}
+	for _, table := range created {
+		tables[table]++
+	}
+	assert.Equal(t, 4, tables[notionapi.TableBlock])
+	assert.Equal(t, 1, tables[notionapi.TableCollection])
+	assert.Equal(t, 1, tables[notionapi.TableCollectionView])
+	assert.Equal(t, 2, listAfter)
+}
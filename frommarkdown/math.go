@@ -0,0 +1,89 @@
+package frommarkdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+var kindMathBlock = ast.NewNodeKind("MathBlock")
+
+// mathBlock is a $$ block with TeX, which can be on a single line: $$ x^2 $$
+type mathBlock struct {
+	ast.BaseBlock
+	tex    []string
+	closed bool
+}
+
+func (n *mathBlock) Kind() ast.NodeKind {
+	return kindMathBlock
+}
+
+func (n *mathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *mathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mathParser parses $$ blocks
+type mathParser struct{}
+
+var mathDelim = []byte("$$")
+
+func (b *mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *mathParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathDelim) {
+		return nil, parser.NoChildren
+	}
+	node := &mathBlock{}
+	rest := bytes.TrimSpace(line[pos+len(mathDelim):])
+	if bytes.HasSuffix(rest, mathDelim) {
+		node.tex = append(node.tex, string(bytes.TrimSuffix(rest, mathDelim)))
+		node.closed = true
+	} else if len(rest) > 0 {
+		node.tex = append(node.tex, string(rest))
+	}
+	return node, parser.NoChildren
+}
+
+func (b *mathParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*mathBlock)
+	if n.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	// leave the new line to the parser
+	advance := segment.Len()
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		advance--
+	}
+	t := bytes.TrimSpace(line)
+	if bytes.HasSuffix(t, mathDelim) {
+		n.tex = append(n.tex, string(bytes.TrimSuffix(t, mathDelim)))
+		reader.Advance(advance)
+		return parser.Close
+	}
+	n.tex = append(n.tex, string(bytes.TrimRight(line, "\r\n")))
+	reader.Advance(advance)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+func (b *mathParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathParser) CanAcceptIndentedLine() bool {
+	return false
+}
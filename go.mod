@@ -5,8 +5,16 @@ require (
 	github.com/json-iterator/go v1.1.12
 	github.com/kjk/common v0.0.0-20211010101831-6203abf05163
 	github.com/kjk/siser v0.0.0-20220410204903-1b1e84ea1397
-	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/tidwall/pretty v1.2.0
+	github.com/yuin/goldmark v1.6.0
+	golang.org/x/net v0.33.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
 
-go 1.11
+go 1.18
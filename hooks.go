@@ -0,0 +1,142 @@
+package notionapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RequestEvent describes a single HTTP request sent by Client.
+// It's passed to Client.OnRequest before the request is sent and to
+// Client.OnResponse after it finishes. Both hooks get the same *RequestEvent
+// so UserData can be used to e.g. carry a tracing span from one to the other.
+type RequestEvent struct {
+	// Ctx is the context of the request
+	Ctx context.Context
+	// Endpoint is the name of Notion API endpoint (e.g. "loadCachedPageChunk")
+	// or "download" / "upload" for file transfers
+	Endpoint string
+	// Method is HTTP method
+	Method string
+	// URL of the request
+	URL string
+	// RequestSize is size of request body in bytes
+	RequestSize int64
+	// Retries is the number of times this request was retried before.
+	// 0 for the first attempt
+	Retries int
+
+	// the fields below are set before calling OnResponse
+
+	// StatusCode is HTTP status code, 0 if we didn't get a response
+	StatusCode int
+	// Latency is the time it took to get and read the response
+	Latency time.Duration
+	// BytesDownloaded is size of response body in bytes
+	BytesDownloaded int64
+	// Err is set if the request failed
+	Err error
+
+	// UserData is for use by hooks
+	UserData interface{}
+}
+
+const (
+	endpointDownload = "download"
+	endpointUpload   = "upload"
+)
+
+// StructuredLogger is a logger that takes a message and key / value pairs.
+// It's implemented by *slog.Logger from log/slog
+type StructuredLogger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+func (c *Client) startRequest(ctx context.Context, endpoint string, method string, uri string, size int64, retries int) (*RequestEvent, time.Time) {
+	ev := &RequestEvent{
+		Ctx:         ctx,
+		Endpoint:    endpoint,
+		Method:      method,
+		URL:         uri,
+		RequestSize: size,
+		Retries:     retries,
+	}
+	if c.OnRequest != nil {
+		c.OnRequest(ev)
+	}
+	return ev, time.Now()
+}
+
+func (c *Client) finishRequest(ev *RequestEvent, timeStart time.Time, status int, nDownloaded int64, err error) {
+	ev.StatusCode = status
+	ev.Latency = time.Since(timeStart)
+	ev.BytesDownloaded = nDownloaded
+	ev.Err = err
+	if err != nil {
+		c.logWarn("request failed", "endpoint", ev.Endpoint, "url", ev.URL, "status", status, "latency", ev.Latency, "retries", ev.Retries, "err", err)
+	} else {
+		c.logDebug("request finished", "endpoint", ev.Endpoint, "url", ev.URL, "status", status, "latency", ev.Latency, "retries", ev.Retries, "bytes", nDownloaded)
+	}
+	if c.OnResponse != nil {
+		c.OnResponse(ev)
+	}
+}
+
+// formatLogArgs formats key / value pairs as "key=value key2=value2"
+func formatLogArgs(msg string, args []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&sb, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&sb, " %v", args[i])
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (c *Client) logDebug(msg string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Debug(msg, args...)
+		return
+	}
+	if c.DebugLog && c.Logger != nil {
+		fmt.Fprint(c.Logger, formatLogArgs(msg, args))
+	}
+}
+
+func (c *Client) logInfo(msg string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Info(msg, args...)
+		return
+	}
+	if c.Logger != nil {
+		fmt.Fprint(c.Logger, formatLogArgs(msg, args))
+	}
+}
+
+func (c *Client) logWarn(msg string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Warn(msg, args...)
+		return
+	}
+	if c.Logger != nil {
+		fmt.Fprint(c.Logger, formatLogArgs(msg, args))
+	}
+}
+
+func (c *Client) logError(msg string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log.Error(msg, args...)
+		return
+	}
+	if c.Logger != nil {
+		fmt.Fprint(c.Logger, formatLogArgs(msg, args))
+	}
+}
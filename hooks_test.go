@@ -0,0 +1,101 @@
+package notionapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+type testLogger struct {
+	msgs []string
+}
+
+func (l *testLogger) log(level string, msg string, args []interface{}) {
+	l.msgs = append(l.msgs, level+" "+formatLogArgs(msg, args))
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("ERROR", msg, args) }
+
+func TestRequestHooks(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, "file")
+			return
+		}
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"results":[]}`)
+	}))
+	defer ts.Close()
+
+	var requests, responses []*RequestEvent
+	logger := &testLogger{}
+	c := &Client{
+		BaseURL:     ts.URL,
+		RateLimiter: NewTokenBucket(1e6, 1e6),
+		RetryPolicy: &ExponentialBackoff{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		Log:         logger,
+		OnRequest: func(ev *RequestEvent) {
+			ev.UserData = len(requests)
+			requests = append(requests, ev)
+		},
+		OnResponse: func(ev *RequestEvent) {
+			responses = append(responses, ev)
+		},
+	}
+	_, err := c.GetSubscriptionData("space-id")
+	assert.NoError(t, err)
+	_, err = c.DownloadURL(ts.URL + "/a.png")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, 3, len(responses))
+	for i, ev := range responses {
+		assert.Equal(t, i, ev.UserData)
+	}
+
+	ev := responses[0]
+	assert.Equal(t, "getSubscriptionData", ev.Endpoint)
+	assert.Equal(t, "POST", ev.Method)
+	assert.True(t, ev.RequestSize > 0)
+	assert.Equal(t, 0, ev.Retries)
+	assert.Equal(t, http.StatusServiceUnavailable, ev.StatusCode)
+	_, ok := AsAPIError(ev.Err)
+	assert.True(t, ok)
+
+	ev = responses[1]
+	assert.Equal(t, 1, ev.Retries)
+	assert.Equal(t, http.StatusOK, ev.StatusCode)
+	assert.Equal(t, int64(len(`{"results":[]}`)), ev.BytesDownloaded)
+	assert.NoError(t, ev.Err)
+
+	ev = responses[2]
+	assert.Equal(t, endpointDownload, ev.Endpoint)
+	assert.Equal(t, "GET", ev.Method)
+	assert.Equal(t, int64(4), ev.BytesDownloaded)
+
+	var nWarn int
+	for _, s := range logger.msgs {
+		if s[:4] == "WARN" {
+			nWarn++
+		}
+	}
+	// failed request and retry
+	assert.Equal(t, 2, nWarn)
+}
+
+func TestFormatLogArgs(t *testing.T) {
+	assert.Equal(t, "msg\n", formatLogArgs("msg", nil))
+	assert.Equal(t, "msg a=1 b=x c\n", formatLogArgs("msg", []interface{}{"a", 1, "b", "x", "c"}))
+}
@@ -0,0 +1,347 @@
+package notionapi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kjk/siser"
+)
+
+const (
+	recHTTPRecordingName = "httprecording"
+)
+
+// RecorderMode determines what HTTPRecorder does
+type RecorderMode int
+
+const (
+	// RecorderPassthrough sends requests to the network without recording
+	RecorderPassthrough RecorderMode = iota
+	// RecorderRecord sends requests to the network and records them
+	RecorderRecord
+	// RecorderReplay returns recorded responses, never touches the network
+	RecorderReplay
+)
+
+var (
+	rxUUID = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// "created_time": 1633890600000, "timestamp": 1633890600000 etc.
+	rxTimestamp = regexp.MustCompile(`("[A-Za-z_]*(?:time|Time|timestamp|Timestamp)"\s*:\s*)\d+`)
+)
+
+// recorderNormalizedEndpoints are API calls whose bodies have ids and
+// timestamps generated by the client. HTTPRecorder matches their bodies
+// after normalizing them. Bodies of other calls must match exactly, so
+// that e.g. reads of different blocks don't match each other
+var recorderNormalizedEndpoints = map[string]bool{
+	"submitTransaction": true,
+}
+
+// isNormalizedEndpoint returns true if body of a request to uri can be
+// matched after normalization
+func isNormalizedEndpoint(uri string) bool {
+	uri = urlWithoutQuery(uri)
+	if !strings.Contains(uri, "/api/v3/") {
+		return false
+	}
+	return recorderNormalizedEndpoints[path.Base(uri)]
+}
+
+// NormalizeVolatileJSON replaces values that change between runs (uuids,
+// timestamps) with fixed values. Used by HTTPRecorder to match requests that
+// create new records, e.g. with SubmitTransaction
+func NormalizeVolatileJSON(d []byte) []byte {
+	d = rxUUID.ReplaceAll(d, []byte("00000000-0000-0000-0000-000000000000"))
+	d = rxTimestamp.ReplaceAll(d, []byte("${1}0"))
+	return d
+}
+
+// RecordedRequest is a request and response recorded by HTTPRecorder
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Body   []byte
+
+	StatusCode  int
+	ContentType string
+	Response    []byte
+
+	normalizedBody []byte
+	used           bool
+}
+
+// HTTPRecorder is http.RoundTripper that records requests and responses to
+// a file (cassette) and can replay them later, for deterministic tests
+// that don't need network access.
+// Use it as Client.HTTPClient.Transport. It handles both Notion API calls
+// and file downloads (Client.DownloadURL).
+//
+// When replaying, a request matches a recording if the method, url and body
+// are the same. If there's no exact match we try to match after removing
+// query from url (which has signatures for files in s3) and, for calls
+// that create records (submitTransaction), after normalizing the body with
+// NormalizeBody. Other calls must have the same body.
+// Each recording is replayed once, in order, so that repeated requests
+// (e.g. polling for export task status) get responses in the order they were
+// recorded.
+type HTTPRecorder struct {
+	Mode RecorderMode
+	// Path is the file where requests are recorded
+	Path string
+	// Transport sends requests to the network, http.DefaultTransport if not set
+	Transport http.RoundTripper
+	// NormalizeBody is used when matching request bodies.
+	// NormalizeVolatileJSON if not set
+	NormalizeBody func([]byte) []byte
+
+	mu         sync.Mutex
+	recordings []*RecordedRequest
+}
+
+// NewHTTPRecorder creates a recorder for a given cassette file.
+// In RecorderReplay mode it loads the recordings, in RecorderRecord mode
+// the file is truncated.
+func NewHTTPRecorder(path string, mode RecorderMode) (*HTTPRecorder, error) {
+	r := &HTTPRecorder{
+		Mode: mode,
+		Path: path,
+	}
+	switch mode {
+	case RecorderReplay:
+		d, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r.recordings, err = deserializeRecordedRequests(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+		}
+	case RecorderRecord:
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Recordings returns recorded requests
+func (r *HTTPRecorder) Recordings() []*RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedRequest(nil), r.recordings...)
+}
+
+func (r *HTTPRecorder) getTransport() http.RoundTripper {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (r *HTTPRecorder) normalize(d []byte) []byte {
+	if r.NormalizeBody != nil {
+		return r.NormalizeBody(d)
+	}
+	return NormalizeVolatileJSON(d)
+}
+
+func urlWithoutQuery(uri string) string {
+	if idx := strings.IndexByte(uri, '?'); idx >= 0 {
+		return uri[:idx]
+	}
+	return uri
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	d, err := ioutil.ReadAll(req.Body)
+	closeNoError(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(d))
+	return d, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *HTTPRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.Mode {
+	case RecorderReplay:
+		return r.replay(req)
+	case RecorderRecord:
+		return r.record(req)
+	}
+	return r.getTransport().RoundTrip(req)
+}
+
+func (r *HTTPRecorder) findRecording(method string, uri string, body []byte) *RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	normalizeBody := isNormalizedEndpoint(uri)
+	var normalizedBody []byte
+	if normalizeBody {
+		normalizedBody = r.normalize(body)
+	}
+	uriNoQuery := urlWithoutQuery(uri)
+	isExact := func(rr *RecordedRequest) bool {
+		return rr.Method == method && rr.URL == uri && bytes.Equal(rr.Body, body)
+	}
+	isNormalized := func(rr *RecordedRequest) bool {
+		if rr.Method != method || urlWithoutQuery(rr.URL) != uriNoQuery {
+			return false
+		}
+		if !normalizeBody {
+			return bytes.Equal(rr.Body, body)
+		}
+		if rr.normalizedBody == nil {
+			rr.normalizedBody = r.normalize(rr.Body)
+		}
+		return bytes.Equal(rr.normalizedBody, normalizedBody)
+	}
+	// prefer recordings we haven't replayed yet
+	for _, allowUsed := range []bool{false, true} {
+		for _, match := range []func(*RecordedRequest) bool{isExact, isNormalized} {
+			var res *RecordedRequest
+			for _, rr := range r.recordings {
+				if rr.used && !allowUsed {
+					continue
+				}
+				if match(rr) {
+					// when re-using pick the last matching recording
+					res = rr
+					if !allowUsed {
+						break
+					}
+				}
+			}
+			if res != nil {
+				res.used = true
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+func (r *HTTPRecorder) replay(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	uri := req.URL.String()
+	rr := r.findRecording(req.Method, uri, body)
+	if rr == nil {
+		return nil, fmt.Errorf("HTTPRecorder: no recording for %s '%s' in '%s'", req.Method, uri, r.Path)
+	}
+	rsp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          ioutil.NopCloser(bytes.NewReader(rr.Response)),
+		ContentLength: int64(len(rr.Response)),
+		Request:       req,
+	}
+	if rr.ContentType != "" {
+		rsp.Header.Set("Content-Type", rr.ContentType)
+	}
+	return rsp, nil
+}
+
+func (r *HTTPRecorder) record(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := r.getTransport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ioutil.ReadAll(rsp.Body)
+	closeNoError(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(d))
+
+	rr := &RecordedRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		Body:        body,
+		StatusCode:  rsp.StatusCode,
+		ContentType: rsp.Header.Get("Content-Type"),
+		Response:    d,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordings = append(r.recordings, rr)
+	if err = appendRecordedRequest(r.Path, rr); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func appendRecordedRequest(path string, rr *RecordedRequest) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := siser.NewWriter(f)
+	w.NoTimestamp = true
+	var rec siser.Record
+	rec.Write("Method", rr.Method)
+	rec.Write("URL", rr.URL)
+	rec.Write("Body", string(rr.Body))
+	rec.Write("Status", strconv.Itoa(rr.StatusCode))
+	rec.Write("ContentType", rr.ContentType)
+	rec.Write("Response", string(rr.Response))
+	rec.Name = recHTTPRecordingName
+	_, err = w.WriteRecord(&rec)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func deserializeRecordedRequests(d []byte) ([]*RecordedRequest, error) {
+	r := siser.NewReader(bufio.NewReader(bytes.NewReader(d)))
+	r.NoTimestamp = true
+	var err error
+	var res []*RecordedRequest
+	for r.ReadNextRecord() {
+		if r.Name != recHTTPRecordingName {
+			return nil, fmt.Errorf("unexpected record type '%s', wanted '%s'", r.Name, recHTTPRecordingName)
+		}
+		rr := &RecordedRequest{}
+		rr.Method = recGetKey(r.Record, "Method", &err)
+		rr.URL = recGetKey(r.Record, "URL", &err)
+		rr.Body = recGetKeyBytes(r.Record, "Body", &err)
+		status := recGetKey(r.Record, "Status", &err)
+		rr.ContentType = recGetKey(r.Record, "ContentType", &err)
+		rr.Response = recGetKeyBytes(r.Record, "Response", &err)
+		if err != nil {
+			return nil, err
+		}
+		if rr.StatusCode, err = strconv.Atoi(status); err != nil {
+			return nil, fmt.Errorf("invalid status '%s'", status)
+		}
+		res = append(res, rr)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
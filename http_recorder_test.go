@@ -0,0 +1,89 @@
+package notionapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestNormalizeVolatileJSON(t *testing.T) {
+	s := `{"id":"4c6a54c6-8b3e-4ea2-af9c-faabcc88d58d","last_edited_time":1633890600000,"count":5}`
+	got := string(NormalizeVolatileJSON([]byte(s)))
+	exp := `{"id":"00000000-0000-0000-0000-000000000000","last_edited_time":0,"count":5}`
+	assert.Equal(t, exp, got)
+}
+
+func TestHTTPRecorder(t *testing.T) {
+	var nRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&nRequests, 1)
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprintf(w, "file %s", r.URL.Path)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"results":[], "n": %d}`, n)
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.txt")
+
+	newClient := func(rec *HTTPRecorder) *Client {
+		return &Client{
+			BaseURL:     ts.URL,
+			HTTPClient:  &http.Client{Transport: rec},
+			RateLimiter: NewTokenBucket(1e6, 1e6),
+		}
+	}
+	submit := func(c *Client, id string) error {
+		op := &Operation{
+			ID:      id,
+			Table:   TableBlock,
+			Path:    []string{},
+			Command: CommandUpdate,
+			Args:    map[string]interface{}{"last_edited_time": Now()},
+		}
+		return c.SubmitTransaction([]*Operation{op})
+	}
+
+	rec, err := NewHTTPRecorder(path, RecorderRecord)
+	assert.NoError(t, err)
+	c := newClient(rec)
+	_, err = c.GetSubscriptionData("space-id")
+	assert.NoError(t, err)
+	assert.NoError(t, submit(c, "4c6a54c6-8b3e-4ea2-af9c-faabcc88d58d"))
+	d, err := c.DownloadURL(ts.URL + "/a.png?X-Amz-Signature=123")
+	assert.NoError(t, err)
+	assert.Equal(t, "file /a.png", string(d.Data))
+	assert.Equal(t, 3, len(rec.Recordings()))
+
+	ts.Close()
+
+	rec, err = NewHTTPRecorder(path, RecorderReplay)
+	assert.NoError(t, err)
+	c = newClient(rec)
+	rsp, err := c.GetSubscriptionData("space-id")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), rsp.RawJSON["n"])
+	// different id and timestamp, matched after normalization
+	assert.NoError(t, submit(c, "5d7b65d7-9c4f-4fb3-b0ad-0bbccd99e69e"))
+	d, err = c.DownloadURL(ts.URL + "/a.png?X-Amz-Signature=456")
+	assert.NoError(t, err)
+	assert.Equal(t, "file /a.png", string(d.Data))
+
+	_, err = c.DownloadURL(ts.URL + "/b.png")
+	assert.Error(t, err)
+	// reads of different ids don't match each other
+	_, err = c.GetSubscriptionData("5d7b65d7-9c4f-4fb3-b0ad-0bbccd99e69e")
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&nRequests))
+
+	d2, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, len(d2) > 0)
+}
@@ -213,6 +213,36 @@ func ParseTextSpans(raw interface{}) ([]*TextSpan, error) {
 	return res, nil
 }
 
+// EncodeTextSpans converts text spans to the JSON form used by Notion for
+// values of properties. It's the reverse of ParseTextSpans
+func EncodeTextSpans(spans []*TextSpan) []interface{} {
+	res := []interface{}{}
+	for _, ts := range spans {
+		if len(ts.Attrs) == 0 {
+			res = append(res, []interface{}{ts.Text})
+			continue
+		}
+		var attrs []interface{}
+		for _, attr := range ts.Attrs {
+			a := []interface{}{}
+			for i, v := range attr {
+				if i == 1 && AttrGetType(attr) == AttrDate {
+					// date is stored as an object, not a string
+					var m map[string]interface{}
+					if err := jsonit.Unmarshal([]byte(v), &m); err == nil {
+						a = append(a, m)
+						continue
+					}
+				}
+				a = append(a, v)
+			}
+			attrs = append(attrs, a)
+		}
+		res = append(res, []interface{}{ts.Text, attrs})
+	}
+	return res
+}
+
 // TextSpansToString returns flattened content of inline blocks, without formatting
 func TextSpansToString(blocks []*TextSpan) string {
 	s := ""
@@ -0,0 +1,173 @@
+package notionapi
+
+import (
+	"github.com/google/uuid"
+)
+
+// BlockSpec describes a block, with its children, to be created with
+// NewBlocksOps. It's used by importers like frommarkdown
+type BlockSpec struct {
+	Type       string
+	Properties map[string]interface{}
+	Format     map[string]interface{}
+	// for BlockImage, BlockFile etc. with files uploaded with UploadFile
+	FileIDs []string
+	Content []*BlockSpec
+	// for BlockCollectionView
+	Collection *CollectionSpec
+
+	// set by NewBlocksOps
+	ID string
+}
+
+// CollectionSpec describes a collection with a single table view, all
+// columns are ColumnTypeText except the first one, which is the title
+type CollectionSpec struct {
+	Name    string
+	Columns []string
+	// cells of rows, in the order of Columns
+	Rows [][][]*TextSpan
+}
+
+// NewTextSpec returns a spec of a block of a given type with "title"
+// property
+func NewTextSpec(blockType string, spans []*TextSpan) *BlockSpec {
+	return &BlockSpec{
+		Type: blockType,
+		Properties: map[string]interface{}{
+			"title": EncodeTextSpans(spans),
+		},
+	}
+}
+
+// blocksOpsBuilder creates operations for block specs
+type blocksOpsBuilder struct {
+	userID  string
+	spaceID string
+	now     int64
+	ops     []*Operation
+}
+
+func (b *blocksOpsBuilder) newRecord(id string, parentID string, parentTable string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               id,
+		"version":          1,
+		"alive":            true,
+		"created_by":       b.userID,
+		"created_time":     b.now,
+		"last_edited_by":   b.userID,
+		"last_edited_time": b.now,
+		"parent_id":        parentID,
+		"parent_table":     parentTable,
+		"space_id":         b.spaceID,
+	}
+}
+
+func (b *blocksOpsBuilder) set(table string, args map[string]interface{}) {
+	b.ops = append(b.ops, &Operation{
+		ID:      args["id"].(string),
+		Table:   table,
+		Path:    []string{},
+		Command: CommandSet,
+		Args:    args,
+	})
+}
+
+// collection creates a collection collID, its view viewID and rows for
+// a collection view block blockID
+func (b *blocksOpsBuilder) collection(blockID string, collID string, viewID string, spec *CollectionSpec) {
+	c := &Collection{ID: collID, Schema: map[string]*ColumnSchema{}}
+	var colIDs []string
+	var tableProps []*TableProperty
+	for i, name := range spec.Columns {
+		id, typ := "title", ColumnTypeTitle
+		if i > 0 {
+			id, typ = c.newColumnID(), ColumnTypeText
+		}
+		c.Schema[id] = &ColumnSchema{Name: name, Type: typ}
+		colIDs = append(colIDs, id)
+		tableProps = append(tableProps, &TableProperty{Property: id, Visible: true})
+	}
+
+	coll := b.newRecord(c.ID, blockID, TableBlock)
+	coll["name"] = [][]string{{spec.Name}}
+	coll["schema"] = c.Schema
+	b.set(TableCollection, coll)
+
+	view := b.newRecord(viewID, blockID, TableBlock)
+	view["type"] = CollectionViewTypeTable
+	view["name"] = "Default view"
+	view["format"] = map[string]interface{}{
+		"table_properties": tableProps,
+	}
+	b.set(TableCollectionView, view)
+
+	for _, row := range spec.Rows {
+		props := map[string]interface{}{}
+		for i, spans := range row {
+			if i < len(colIDs) && len(spans) > 0 {
+				props[colIDs[i]] = EncodeTextSpans(spans)
+			}
+		}
+		rec := b.newRecord(uuid.New().String(), c.ID, TableCollection)
+		rec["type"] = BlockPage
+		rec["properties"] = props
+		b.set(TableBlock, rec)
+	}
+}
+
+// block creates a block spec.ID and its children. Records are created
+// after their parents
+func (b *blocksOpsBuilder) block(spec *BlockSpec, parentID string) {
+	args := b.newRecord(spec.ID, parentID, TableBlock)
+	args["type"] = spec.Type
+	if spec.Properties != nil {
+		args["properties"] = spec.Properties
+	}
+	if spec.Format != nil {
+		args["format"] = spec.Format
+	}
+	if len(spec.FileIDs) > 0 {
+		args["file_ids"] = spec.FileIDs
+	}
+	var collID, viewID string
+	if spec.Collection != nil {
+		collID = uuid.New().String()
+		viewID = uuid.New().String()
+		args["collection_id"] = collID
+		args["view_ids"] = []string{viewID}
+	}
+	if len(spec.Content) > 0 {
+		var content []string
+		for _, child := range spec.Content {
+			child.ID = uuid.New().String()
+			content = append(content, child.ID)
+		}
+		args["content"] = content
+	}
+	b.set(TableBlock, args)
+
+	if spec.Collection != nil {
+		b.collection(spec.ID, collID, viewID, spec.Collection)
+	}
+	for _, child := range spec.Content {
+		b.block(child, spec.ID)
+	}
+}
+
+// NewBlocksOps creates operations that add blocks, with their children,
+// at the end of parent, as user userID.
+// It sets BlockSpec.ID of created blocks
+func NewBlocksOps(userID string, parent *Block, blocks []*BlockSpec) []*Operation {
+	b := &blocksOpsBuilder{
+		userID:  userID,
+		spaceID: parent.SpaceID,
+		now:     Now(),
+	}
+	for _, spec := range blocks {
+		spec.ID = uuid.New().String()
+		b.block(spec, parent.ID)
+		b.ops = append(b.ops, parent.ListAfterContentOp(spec.ID, ""))
+	}
+	return b.ops
+}
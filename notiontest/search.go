@@ -0,0 +1,264 @@
+package notiontest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kjk/notionapi"
+)
+
+// Notion wraps matched text in highlights with this tag
+const highlightTag = "gzkNfoUU"
+
+type searchDate struct {
+	StartDate string `json:"start_date"`
+}
+
+type searchRequest struct {
+	Query   string `json:"query"`
+	SpaceID string `json:"spaceId"`
+	Limit   int    `json:"limit"`
+	Filters struct {
+		Ancestors      []string `json:"ancestors"`
+		CreatedBy      []string `json:"createdBy"`
+		LastEditedTime *struct {
+			Starting *searchDate `json:"starting"`
+			Ending   *searchDate `json:"ending"`
+		} `json:"lastEditedTime"`
+	} `json:"filters"`
+	Sort struct {
+		Field string `json:"field"`
+	} `json:"sort"`
+}
+
+type searchResult struct {
+	ID          string                 `json:"id"`
+	IsNavigable bool                   `json:"isNavigable"`
+	Score       float64                `json:"score"`
+	SpaceID     string                 `json:"spaceId"`
+	Highlight   map[string]interface{} `json:"highlight"`
+
+	block map[string]interface{}
+}
+
+func getInt64(m map[string]interface{}, key string) int64 {
+	switch v := m[key].(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
+
+// blockText returns plain text of "title" property of a block
+func blockText(block map[string]interface{}) string {
+	props, _ := block["properties"].(map[string]interface{})
+	spans, _ := props["title"].([]interface{})
+	var sb strings.Builder
+	for _, span := range spans {
+		if a, ok := span.([]interface{}); ok && len(a) > 0 {
+			s, _ := a[0].(string)
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// highlight wraps case-insensitive matches of query in s with highlightTag.
+// Returns number of matches
+func highlight(s string, query string) (string, int) {
+	if query == "" {
+		return s, 0
+	}
+	lower := strings.ToLower(s)
+	query = strings.ToLower(query)
+	var sb strings.Builder
+	n := 0
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), n
+		}
+		n++
+		end := i + len(query)
+		sb.WriteString(s[:i])
+		sb.WriteString("<" + highlightTag + ">" + s[i:end] + "</" + highlightTag + ">")
+		s, lower = s[end:], lower[end:]
+	}
+}
+
+// parentBlock returns the block that is a parent of a block, skipping
+// over collections. nil if there is none
+func (s *Server) parentBlock(block map[string]interface{}) map[string]interface{} {
+	table, id := getString(block, "parent_table"), getString(block, "parent_id")
+	if table == notionapi.TableCollection {
+		coll := s.Store.Get(table, id)
+		if coll == nil {
+			return nil
+		}
+		table, id = getString(coll, "parent_table"), getString(coll, "parent_id")
+	}
+	if table != notionapi.TableBlock {
+		return nil
+	}
+	return s.Store.Get(table, id)
+}
+
+func isPage(block map[string]interface{}) bool {
+	typ := getString(block, "type")
+	return typ == notionapi.BlockPage || typ == notionapi.BlockCollectionViewPage
+}
+
+// ancestors returns titles of pages that are ancestors of a block,
+// as path text, and ids of all its ancestors
+func (s *Server) ancestors(block map[string]interface{}) (string, map[string]bool) {
+	var titles []string
+	ids := map[string]bool{}
+	for b := s.parentBlock(block); b != nil && len(ids) < 100; b = s.parentBlock(b) {
+		ids[getString(b, "id")] = true
+		if isPage(b) {
+			titles = append([]string{blockText(b)}, titles...)
+		}
+	}
+	return strings.Join(titles, " / "), ids
+}
+
+// matchContent returns highlighted text of the first content block of
+// a page that matches the query and number of matches in all of them.
+// Doesn't descend into sub-pages
+func (s *Server) matchContent(page map[string]interface{}, query string) (string, int) {
+	text, total := "", 0
+	for _, id := range getStrings(page, "content") {
+		b := s.Store.Get(notionapi.TableBlock, id)
+		if b == nil || b["alive"] != true || isPage(b) {
+			continue
+		}
+		t, n := highlight(blockText(b), query)
+		if n > 0 && text == "" {
+			text = t
+		}
+		t, n2 := s.matchContent(b, query)
+		if n2 > 0 && text == "" {
+			text = t
+		}
+		total += n + n2
+	}
+	return text, total
+}
+
+func parseSearchDate(d *searchDate) (time.Time, bool) {
+	if d == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02", d.StartDate)
+	return t, err == nil
+}
+
+func (s *Server) searchBlock(req *searchRequest, block map[string]interface{}) *searchResult {
+	f := &req.Filters
+	if block["alive"] != true || !isPage(block) {
+		return nil
+	}
+	if req.SpaceID != "" && getString(block, "space_id") != "" && getString(block, "space_id") != req.SpaceID {
+		return nil
+	}
+	if len(f.CreatedBy) > 0 && !containsString(f.CreatedBy, getString(block, "created_by")) {
+		return nil
+	}
+	if r := f.LastEditedTime; r != nil {
+		edited := getInt64(block, "last_edited_time")
+		if t, ok := parseSearchDate(r.Starting); ok && edited < t.UnixNano()/1e6 {
+			return nil
+		}
+		if t, ok := parseSearchDate(r.Ending); ok && edited >= t.AddDate(0, 0, 1).UnixNano()/1e6 {
+			return nil
+		}
+	}
+	path, ancestors := s.ancestors(block)
+	for _, id := range f.Ancestors {
+		if !ancestors[id] {
+			return nil
+		}
+	}
+
+	text, n := highlight(blockText(block), req.Query)
+	score := float64(10 * n)
+	content, n2 := s.matchContent(block, req.Query)
+	if n == 0 && n2 > 0 {
+		text = content
+	}
+	n += n2
+	score += float64(n2)
+	if req.Query != "" && n == 0 {
+		return nil
+	}
+	return &searchResult{
+		ID:          getString(block, "id"),
+		IsNavigable: true,
+		Score:       score,
+		SpaceID:     getString(block, "space_id"),
+		Highlight: map[string]interface{}{
+			"text":     text,
+			"pathText": path,
+		},
+		block: block,
+	}
+}
+
+func containsString(a []string, s string) bool {
+	for _, el := range a {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var req searchRequest
+	if !readRequest(w, r, &req) {
+		return
+	}
+	results := []*searchResult{}
+	for _, id := range s.Store.ids(notionapi.TableBlock) {
+		block := s.Store.Get(notionapi.TableBlock, id)
+		if res := s.searchBlock(&req, block); res != nil {
+			results = append(results, res)
+		}
+	}
+	sortKey := func(res *searchResult) float64 {
+		switch req.Sort.Field {
+		case notionapi.SearchSortLastEdited:
+			return float64(getInt64(res.block, "last_edited_time"))
+		case notionapi.SearchSortCreated:
+			return float64(getInt64(res.block, "created_time"))
+		}
+		return res.Score
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return sortKey(results[i]) > sortKey(results[j])
+	})
+	total := len(results)
+	if req.Limit > 0 && req.Limit < len(results) {
+		results = results[:req.Limit]
+	}
+	rm := recordMap{}
+	for _, res := range results {
+		rm.add(notionapi.TableBlock, res.ID, res.block)
+	}
+	writeJSON(w, map[string]interface{}{
+		"results":   results,
+		"total":     total,
+		"recordMap": rm,
+	})
+}
@@ -0,0 +1,516 @@
+// Package notiontest implements a fake Notion server for testing code
+// that uses notionapi.Client without talking to notion.so
+package notiontest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kjk/notionapi"
+)
+
+// Server is a fake Notion server serving a subset of /api/v3 from Store.
+//
+// Supported endpoints: syncRecordValues, loadCachedPageChunk,
+// queryCollection, submitTransaction, getSignedFileUrls, enqueueTask,
+// getTasks, search and getActivityLog.
+// queryCollection ignores filters and sorts and returns rows in the order
+// they were added to the store.
+// getActivityLog returns activities of a space, most recent first.
+// search does a case-insensitive substring match of titles (and text
+// of content) of pages.
+type Server struct {
+	// URL of the server, use as notionapi.Client.BaseURL
+	URL string
+	// Store has records served by the server and modified by submitTransaction
+	Store *Store
+	// if set, requests must send it as token_v2 cookie
+	AuthToken string
+
+	srv *httptest.Server
+
+	mu    sync.Mutex
+	tasks map[string]string // task id => exported block id
+}
+
+// NewServer starts a fake Notion server with an empty Store.
+// Call Close() when done.
+func NewServer() *Server {
+	s := &Server{
+		Store: NewStore(),
+		tasks: map[string]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/syncRecordValues", s.handleSyncRecordValues)
+	mux.HandleFunc("/api/v3/loadCachedPageChunk", s.handleLoadCachedPageChunk)
+	mux.HandleFunc("/api/v3/queryCollection", s.handleQueryCollection)
+	mux.HandleFunc("/api/v3/submitTransaction", s.handleSubmitTransaction)
+	mux.HandleFunc("/api/v3/getSignedFileUrls", s.handleGetSignedFileURLs)
+	mux.HandleFunc("/api/v3/enqueueTask", s.handleEnqueueTask)
+	mux.HandleFunc("/api/v3/getTasks", s.handleGetTasks)
+	mux.HandleFunc("/api/v3/search", s.handleSearch)
+	mux.HandleFunc("/api/v3/getActivityLog", s.handleGetActivityLog)
+	mux.HandleFunc("/export/", s.handleExport)
+	s.srv = httptest.NewServer(s.authorize(mux))
+	s.URL = s.srv.URL
+	return s
+}
+
+// NewServerFromCacheDir starts a fake Notion server with Store
+// seeded from notionapi.CachingClient cache directory
+func NewServerFromCacheDir(dir string) (*Server, error) {
+	s := NewServer()
+	if err := s.Store.LoadCacheDir(dir); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// NewClient returns notionapi.Client that talks to this server.
+// It's not rate limited.
+func (s *Server) NewClient() *notionapi.Client {
+	return &notionapi.Client{
+		AuthToken:   s.AuthToken,
+		BaseURL:     s.URL,
+		HTTPClient:  s.srv.Client(),
+		RateLimiter: notionapi.NewTokenBucket(1e6, 1e6),
+	}
+}
+
+func (s *Server) authorize(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.AuthToken != "" {
+			c, err := r.Cookie("token_v2")
+			if err != nil || c.Value != s.AuthToken {
+				writeError(w, http.StatusUnauthorized, "UnauthorizedError", "Token was invalid or expired.")
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(d)
+}
+
+func writeError(w http.ResponseWriter, status int, name string, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	d, _ := json.Marshal(map[string]string{
+		"errorId": uuid.New().String(),
+		"name":    name,
+		"message": msg,
+	})
+	_, _ = w.Write(d)
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "ValidationError", "expected POST")
+		return false
+	}
+	d, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = decodeJSON(d, v)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationError", err.Error())
+		return false
+	}
+	return true
+}
+
+type record struct {
+	Role  string                 `json:"role"`
+	Value map[string]interface{} `json:"value,omitempty"`
+}
+
+// recordMap is "recordMap" in responses
+type recordMap map[string]map[string]*record
+
+func (m recordMap) add(table string, id string, value map[string]interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if m[table] == nil {
+		m[table] = map[string]*record{}
+	}
+	if _, ok := m[table][id]; ok {
+		return false
+	}
+	m[table][id] = &record{Role: notionapi.RoleEditor, Value: value}
+	return true
+}
+
+func getString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func getStrings(m map[string]interface{}, key string) []string {
+	var res []string
+	a, _ := m[key].([]interface{})
+	for _, v := range a {
+		if s, ok := v.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (s *Server) handleSyncRecordValues(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Requests []notionapi.PointerWithVersion `json:"requests"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	rm := recordMap{}
+	for _, p := range req.Requests {
+		table, id := p.Pointer.Table, p.Pointer.ID
+		if !rm.add(table, id, s.Store.Get(table, id)) {
+			if rm[table] == nil {
+				rm[table] = map[string]*record{}
+			}
+			if rm[table][id] == nil {
+				rm[table][id] = &record{Role: "none"}
+			}
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"recordMap": rm,
+	})
+}
+
+// addCollection adds collection and collection views of a collection_view block
+func (s *Server) addCollection(rm recordMap, block map[string]interface{}) {
+	collectionID := getString(block, "collection_id")
+	if collectionID == "" {
+		if format, ok := block["format"].(map[string]interface{}); ok {
+			if ptr, ok := format["collection_pointer"].(map[string]interface{}); ok {
+				collectionID = getString(ptr, "id")
+			}
+		}
+	}
+	if collectionID != "" {
+		rm.add(notionapi.TableCollection, collectionID, s.Store.Get(notionapi.TableCollection, collectionID))
+	}
+	for _, id := range getStrings(block, "view_ids") {
+		rm.add(notionapi.TableCollectionView, id, s.Store.Get(notionapi.TableCollectionView, id))
+	}
+}
+
+func (s *Server) addDiscussions(rm recordMap, block map[string]interface{}) {
+	for _, id := range getStrings(block, "discussions") {
+		d := s.Store.Get(notionapi.TableDiscussion, id)
+		if !rm.add(notionapi.TableDiscussion, id, d) {
+			continue
+		}
+		for _, commentID := range getStrings(d, "comments") {
+			rm.add(notionapi.TableComment, commentID, s.Store.Get(notionapi.TableComment, commentID))
+		}
+	}
+}
+
+// we return the whole page in one chunk: the page block, all blocks it
+// contains (but not the content of sub-pages) and collections
+func (s *Server) handleLoadCachedPageChunk(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Page struct {
+			ID string `json:"id"`
+		} `json:"page"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	rm := recordMap{}
+	toVisit := []string{req.Page.ID}
+	for len(toVisit) > 0 {
+		id := toVisit[0]
+		toVisit = toVisit[1:]
+		block := s.Store.Get(notionapi.TableBlock, id)
+		if !rm.add(notionapi.TableBlock, id, block) {
+			continue
+		}
+		if spaceID := getString(block, "space_id"); spaceID != "" {
+			rm.add(notionapi.TableSpace, spaceID, s.Store.Get(notionapi.TableSpace, spaceID))
+		}
+		s.addCollection(rm, block)
+		s.addDiscussions(rm, block)
+		typ := getString(block, "type")
+		isPage := typ == notionapi.BlockPage || typ == notionapi.BlockCollectionViewPage
+		if isPage && id != req.Page.ID {
+			continue
+		}
+		toVisit = append(toVisit, getStrings(block, "content")...)
+	}
+	writeJSON(w, map[string]interface{}{
+		"recordMap": rm,
+		"cursor": map[string]interface{}{
+			"stack": []interface{}{},
+		},
+	})
+}
+
+type queryCollectionRequest struct {
+	Collection struct {
+		ID string `json:"id"`
+	} `json:"collection"`
+	CollectionView struct {
+		ID string `json:"id"`
+	} `json:"collectionView"`
+	Loader struct {
+		Reducers map[string]struct {
+			Type  string `json:"type"`
+			Limit int    `json:"limit"`
+		} `json:"reducers"`
+	} `json:"loader"`
+}
+
+func (s *Server) handleQueryCollection(w http.ResponseWriter, r *http.Request) {
+	var req queryCollectionRequest
+	if !readRequest(w, r, &req) {
+		return
+	}
+	collectionID := req.Collection.ID
+	rm := recordMap{}
+	rm.add(notionapi.TableCollection, collectionID, s.Store.Get(notionapi.TableCollection, collectionID))
+	viewID := req.CollectionView.ID
+	rm.add(notionapi.TableCollectionView, viewID, s.Store.Get(notionapi.TableCollectionView, viewID))
+
+	var rows []string
+	for _, id := range s.Store.CollectionRows(collectionID) {
+		block := s.Store.Get(notionapi.TableBlock, id)
+		if block == nil || block["alive"] != true || getString(block, "parent_id") != collectionID {
+			continue
+		}
+		rows = append(rows, id)
+	}
+	total := len(rows)
+	if reducer, ok := req.Loader.Reducers[notionapi.ReducerCollectionGroupResultsName]; ok {
+		if reducer.Limit > 0 && reducer.Limit < len(rows) {
+			rows = rows[:reducer.Limit]
+		}
+	}
+	for _, id := range rows {
+		rm.add(notionapi.TableBlock, id, s.Store.Get(notionapi.TableBlock, id))
+	}
+	if rows == nil {
+		rows = []string{}
+	}
+	writeJSON(w, map[string]interface{}{
+		"recordMap": rm,
+		"result": map[string]interface{}{
+			"type": "reducer",
+			"reducerResults": map[string]interface{}{
+				notionapi.ReducerCollectionGroupResultsName: map[string]interface{}{
+					"type":     "results",
+					"blockIds": rows,
+					"total":    total,
+				},
+			},
+		},
+	})
+}
+
+func (s *Server) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Operations []*notionapi.Operation `json:"operations"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	if err := s.Store.Apply(req.Operations); err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationError", err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+// we don't sign urls, just return them as they are
+func (s *Server) handleGetSignedFileURLs(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URLs []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	urls := []string{}
+	for _, u := range req.URLs {
+		urls = append(urls, u.URL)
+	}
+	writeJSON(w, map[string]interface{}{
+		"signedUrls": urls,
+	})
+}
+
+func (s *Server) handleEnqueueTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Task struct {
+			EventName string `json:"eventName"`
+			Request   struct {
+				BlockID string `json:"blockId"`
+			} `json:"request"`
+		} `json:"task"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	if req.Task.EventName != "exportBlock" {
+		writeError(w, http.StatusBadRequest, "ValidationError", fmt.Sprintf("unsupported task '%s'", req.Task.EventName))
+		return
+	}
+	taskID := uuid.New().String()
+	s.mu.Lock()
+	s.tasks[taskID] = req.Task.Request.BlockID
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"taskId": taskID,
+	})
+}
+
+// tasks complete immediately
+func (s *Server) handleGetTasks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskIDs []string `json:"taskIds"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	results := []interface{}{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range req.TaskIDs {
+		blockID, ok := s.tasks[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NotFoundError", fmt.Sprintf("no task '%s'", id))
+			return
+		}
+		results = append(results, map[string]interface{}{
+			"id":        id,
+			"eventName": "exportBlock",
+			"request": map[string]interface{}{
+				"blockId": blockID,
+			},
+			"state": "success",
+			"status": map[string]interface{}{
+				"type":          "complete",
+				"exportURL":     s.URL + "/export/" + id + ".zip",
+				"pagesExported": 1,
+			},
+		})
+	}
+	writeJSON(w, map[string]interface{}{
+		"results": results,
+	})
+}
+
+func (s *Server) handleGetActivityLog(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SpaceID         string `json:"spaceId"`
+		StartingAfterID string `json:"startingAfterId"`
+		Limit           int    `json:"limit"`
+	}
+	if !readRequest(w, r, &req) {
+		return
+	}
+	var activities []map[string]interface{}
+	for _, id := range s.Store.ids(notionapi.TableActivity) {
+		a := s.Store.Get(notionapi.TableActivity, id)
+		if getString(a, "space_id") == req.SpaceID {
+			activities = append(activities, a)
+		}
+	}
+	startTime := func(a map[string]interface{}) int64 {
+		n, _ := strconv.ParseInt(getString(a, "start_time"), 10, 64)
+		return n
+	}
+	sort.SliceStable(activities, func(i, j int) bool {
+		return startTime(activities[i]) > startTime(activities[j])
+	})
+	if req.StartingAfterID != "" {
+		for i, a := range activities {
+			if getString(a, "id") == req.StartingAfterID {
+				activities = activities[i+1:]
+				break
+			}
+		}
+	}
+	if req.Limit > 0 && req.Limit < len(activities) {
+		activities = activities[:req.Limit]
+	}
+	ids := []string{}
+	rm := recordMap{}
+	for _, a := range activities {
+		id := getString(a, "id")
+		ids = append(ids, id)
+		rm.add(notionapi.TableActivity, id, a)
+		if blockID := getString(a, "navigable_block_id"); blockID != "" {
+			rm.add(notionapi.TableBlock, blockID, s.Store.Get(notionapi.TableBlock, blockID))
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"activityIds": ids,
+		"recordMap":   rm,
+	})
+}
+
+// export is a .zip file with a single ${blockID}.md file with the title
+// of the block
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	taskID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/export/"), ".zip")
+	s.mu.Lock()
+	blockID, ok := s.tasks[taskID]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	title := ""
+	block := &notionapi.Block{}
+	if d, err := json.Marshal(s.Store.Get(notionapi.TableBlock, blockID)); err == nil {
+		if err = json.Unmarshal(d, block); err == nil {
+			title = notionapi.TextSpansToString(block.GetTitle())
+		}
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(notionapi.ToNoDashID(blockID) + ".md")
+	if err == nil {
+		_, err = fmt.Fprintf(f, "# %s\n", title)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	_, _ = w.Write(buf.Bytes())
+}
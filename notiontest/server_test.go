@@ -0,0 +1,89 @@
+package notiontest
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+)
+
+func newTestServer(t *testing.T) *Server {
+	s, err := NewServerFromCacheDir("../caching_client_testdata")
+	require.NoError(t, err)
+	return s
+}
+
+// https://www.notion.so/Test-table-94167af6567043279811dc923edd1f04
+func TestDownloadPage(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	p, err := client.DownloadPage("94167af6567043279811dc923edd1f04")
+	require.NoError(t, err)
+	require.Equal(t, "Test table", p.Root().Title)
+	require.Equal(t, 2, len(p.TableViews))
+	for _, tv := range p.TableViews {
+		require.True(t, tv.RowCount() > 0)
+	}
+
+	_, err = client.DownloadPage("00000000000000000000000000000000")
+	require.True(t, notionapi.IsErrPageNotFound(err))
+}
+
+func TestSubmitTransaction(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	pageID := "6682351e44bb4f9ca0e149b703265bdb"
+	p, err := client.DownloadPage(pageID)
+	require.NoError(t, err)
+	ver := p.Root().Version
+	require.NoError(t, p.SetTitle("New title"))
+	require.Equal(t, 1, len(s.Store.Transactions()))
+
+	p, err = client.DownloadPage(pageID)
+	require.NoError(t, err)
+	require.Equal(t, "New title", p.Root().Title)
+	require.Equal(t, ver+1, p.Root().Version)
+
+	// invalid operation is rejected
+	op := &notionapi.Operation{
+		ID:      p.ID,
+		Table:   notionapi.TableBlock,
+		Path:    []string{"content"},
+		Command: notionapi.CommandListAfter,
+		Args:    map[string]string{},
+	}
+	err = client.SubmitTransaction([]*notionapi.Operation{op})
+	require.True(t, notionapi.IsValidationError(err))
+}
+
+func TestExportPages(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	client := s.NewClient()
+
+	d, err := client.ExportPages("6682351e44bb4f9ca0e149b703265bdb", notionapi.ExportTypeMarkdown, false)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(d), int64(len(d)))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(zr.File))
+	require.Equal(t, "6682351e44bb4f9ca0e149b703265bdb.md", zr.File[0].Name)
+}
+
+func TestUnauthorized(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	s.AuthToken = "secret"
+	client := s.NewClient()
+	_, err := client.DownloadPage("6682351e44bb4f9ca0e149b703265bdb")
+	require.NoError(t, err)
+
+	client.AuthToken = "bad"
+	_, err = client.DownloadPage("6682351e44bb4f9ca0e149b703265bdb")
+	require.True(t, notionapi.IsUnauthorized(err))
+}
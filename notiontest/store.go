@@ -0,0 +1,268 @@
+package notiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kjk/notionapi"
+)
+
+// Store is an in-memory store of Notion records, keyed by table
+// (notionapi.TableBlock etc.) and record id.
+// Records are stored as json values, the way Notion API sends them.
+// It's safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	records map[string]map[string]map[string]interface{}
+	// ids of row blocks of a collection, in the order returned by queryCollection
+	collectionRows map[string][]string
+	// operations applied with submitTransaction, in order
+	transactions [][]*notionapi.Operation
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{
+		records:        map[string]map[string]map[string]interface{}{},
+		collectionRows: map[string][]string{},
+	}
+}
+
+func decodeJSON(d []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(d))
+	// preserve exact value of large numbers like created_time
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func (s *Store) put(table string, id string, value map[string]interface{}) {
+	m := s.records[table]
+	if m == nil {
+		m = map[string]map[string]interface{}{}
+		s.records[table] = m
+	}
+	m[id] = value
+	if table != notionapi.TableBlock || value["parent_table"] != notionapi.TableCollection {
+		return
+	}
+	collectionID, _ := value["parent_id"].(string)
+	for _, rowID := range s.collectionRows[collectionID] {
+		if rowID == id {
+			return
+		}
+	}
+	s.collectionRows[collectionID] = append(s.collectionRows[collectionID], id)
+}
+
+// Put adds or replaces a record. value must have "id"
+func (s *Store) Put(table string, value map[string]interface{}) error {
+	id, ok := value["id"].(string)
+	if !ok {
+		return fmt.Errorf("record in table '%s' has no id", table)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(table, id, value)
+	return nil
+}
+
+// Get returns a record or nil if doesn't exist
+func (s *Store) Get(table string, id string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[table][id]
+}
+
+// CollectionRows returns ids of blocks that are rows of a given collection
+func (s *Store) CollectionRows(collectionID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.collectionRows[collectionID]...)
+}
+
+// ids returns ids of all records in a table, sorted
+func (s *Store) ids(table string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []string
+	for id := range s.records[table] {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Transactions returns operations submitted with submitTransaction
+func (s *Store) Transactions() [][]*notionapi.Operation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]*notionapi.Operation(nil), s.transactions...)
+}
+
+// LoadRecordMap adds records from "recordMap" part of Notion API
+// response, which looks like:
+// { "block": { "${id}": { "role": "reader", "value": { ... } } } }
+func (s *Store) LoadRecordMap(d []byte) error {
+	var recordMap map[string]map[string]struct {
+		Role  string                 `json:"role"`
+		Value map[string]interface{} `json:"value"`
+	}
+	if err := decodeJSON(d, &recordMap); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for table, records := range recordMap {
+		if table == "__version__" {
+			continue
+		}
+		for id, rec := range records {
+			if rec.Value != nil {
+				s.put(table, id, rec.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadCacheEntries adds records from requests cached by notionapi.CachingClient
+func (s *Store) LoadCacheEntries(entries []*notionapi.RequestCacheEntry) error {
+	for _, e := range entries {
+		var rsp struct {
+			RecordMap json.RawMessage `json:"recordMap"`
+			Result    struct {
+				ReducerResults struct {
+					CollectionGroupResults struct {
+						BlockIds []string `json:"blockIds"`
+					} `json:"collection_group_results"`
+				} `json:"reducerResults"`
+			} `json:"result"`
+		}
+		if err := decodeJSON(e.Response, &rsp); err != nil {
+			return fmt.Errorf("failed to decode response to '%s': %w", e.URL, err)
+		}
+		if len(rsp.RecordMap) > 0 {
+			if err := s.LoadRecordMap(rsp.RecordMap); err != nil {
+				return err
+			}
+		}
+		if !strings.HasSuffix(e.URL, "/queryCollection") {
+			continue
+		}
+		// remember the order of rows returned by the server
+		var req queryCollectionRequest
+		if err := decodeJSON([]byte(e.Body), &req); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		rows := rsp.Result.ReducerResults.CollectionGroupResults.BlockIds
+		s.collectionRows[req.Collection.ID] = mergeIDs(rows, s.collectionRows[req.Collection.ID])
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// mergeIDs returns ids in first followed by those ids in second
+// that are not in first
+func mergeIDs(first, second []string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, a := range [][]string{first, second} {
+		for _, id := range a {
+			if !seen[id] {
+				seen[id] = true
+				res = append(res, id)
+			}
+		}
+	}
+	return res
+}
+
+// LoadCacheFile adds records from a file written by notionapi.CachingClient
+func (s *Store) LoadCacheFile(path string) error {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	entries, err := notionapi.ParseCacheEntries(d)
+	if err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	return s.LoadCacheEntries(entries)
+}
+
+// LoadCacheDir adds records from all files in notionapi.CachingClient
+// cache directory (e.g. caching_client_testdata)
+func (s *Store) LoadCacheDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := s.LoadCacheFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply applies operations, like /api/v3/submitTransaction
+func (s *Store) Apply(ops []*notionapi.Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// apply to copies so that a failed transaction doesn't change anything
+	changed := map[string]map[string]interface{}{}
+	for _, op := range ops {
+		key := op.Table + ":" + op.ID
+		rec, ok := changed[key]
+		if !ok {
+			rec = copyRecord(s.records[op.Table][op.ID])
+		}
+		rec, err := notionapi.ApplyOperation(rec, op)
+		if err != nil {
+			return fmt.Errorf("operation on %s '%s' failed: %w", op.Table, op.ID, err)
+		}
+		changed[key] = rec
+	}
+	for _, op := range ops {
+		key := op.Table + ":" + op.ID
+		if rec, ok := changed[key]; ok {
+			rec["id"] = op.ID
+			rec["version"] = json.Number(strconv.FormatInt(recordVersion(rec)+1, 10))
+			s.put(op.Table, op.ID, rec)
+			delete(changed, key)
+		}
+	}
+	s.transactions = append(s.transactions, ops)
+	return nil
+}
+
+func recordVersion(rec map[string]interface{}) int64 {
+	if n, ok := rec["version"].(json.Number); ok {
+		v, _ := n.Int64()
+		return v
+	}
+	return 0
+}
+
+func copyRecord(rec map[string]interface{}) map[string]interface{} {
+	if rec == nil {
+		return nil
+	}
+	d, err := json.Marshal(rec)
+	if err != nil {
+		panic(err)
+	}
+	var res map[string]interface{}
+	if err = decodeJSON(d, &res); err != nil {
+		panic(err)
+	}
+	return res
+}
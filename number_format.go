@@ -0,0 +1,72 @@
+package notionapi
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currency symbols for ColumnSchema.NumberFormat
+var numberFormatCurrency = map[string]string{
+	"dollar":          "$",
+	"canadian_dollar": "CA$",
+	"euro":            "€",
+	"pound":           "£",
+	"yen":             "¥",
+	"ruble":           "₽",
+	"rupee":           "₹",
+	"won":             "₩",
+	"yuan":            "CN¥",
+	"real":            "R$",
+	"lira":            "TL",
+	"franc":           "CHF",
+}
+
+// addThousandsSeparator formats "1234.5" as "1,234.5"
+func addThousandsSeparator(s string) string {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	parts := strings.SplitN(s, ".", 2)
+	intPart := parts[0]
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	res := b.String()
+	if len(parts) == 2 {
+		res += "." + parts[1]
+	}
+	if neg {
+		res = "-" + res
+	}
+	return res
+}
+
+// fmtFloat formats n without trailing zeros and without floating
+// point noise like 7.000000000000001
+func fmtFloat(n float64) string {
+	return strconv.FormatFloat(math.Round(n*1e10)/1e10, 'f', -1, 64)
+}
+
+// FormatNumber formats a value of a number column according to
+// ColumnSchema.NumberFormat ("number", "number_with_commas", "percent",
+// "dollar" etc.)
+func FormatNumber(n float64, numFmt string) string {
+	switch numFmt {
+	case "number_with_commas":
+		return addThousandsSeparator(fmtFloat(n))
+	case "percent":
+		return fmtFloat(n*100) + "%"
+	}
+	if sym, ok := numberFormatCurrency[numFmt]; ok {
+		s := addThousandsSeparator(strconv.FormatFloat(n, 'f', 2, 64))
+		if strings.HasPrefix(s, "-") {
+			return "-" + sym + s[1:]
+		}
+		return sym + s
+	}
+	return fmtFloat(n)
+}
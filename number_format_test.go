@@ -0,0 +1,27 @@
+package notionapi
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		n      float64
+		numFmt string
+		exp    string
+	}{
+		{1234.5, "", "1234.5"},
+		{1234.5, "number", "1234.5"},
+		{1234567.25, "number_with_commas", "1,234,567.25"},
+		{-1234, "number_with_commas", "-1,234"},
+		{123, "number_with_commas", "123"},
+		{0.07, "percent", "7%"},
+		{1234.5, "dollar", "$1,234.50"},
+		{-3, "euro", "-€3.00"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.exp, FormatNumber(test.n, test.numFmt))
+	}
+}
@@ -46,6 +46,8 @@ type Page struct {
 
 	client   *Client
 	subPages []*NotionID
+	// changes made with editing methods, see page_edit.go
+	tx *Transaction
 }
 
 func (p *Page) GetNotionID() *NotionID {
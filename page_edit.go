@@ -0,0 +1,326 @@
+package notionapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Editing methods of Page (AppendText, MoveBlock etc.) change the page
+// right away and collect operations in a transaction returned by
+// Page.Transaction. Call Page.SetEditor before editing and Page.Commit
+// to submit the changes to Notion.
+
+// CodeLanguagePlainText is Notion's language of code blocks without
+// syntax highlighting
+const CodeLanguagePlainText = "Plain Text"
+
+func textProperty(s string) [][]string {
+	return [][]string{{s}}
+}
+
+func checkedProperty(checked bool) [][]string {
+	if checked {
+		return textProperty("Yes")
+	}
+	return textProperty("No")
+}
+
+// SetCheckedOp creates an operation to check or uncheck a todo block
+func (b *Block) SetCheckedOp(checked bool) *Operation {
+	return b.buildOp(CommandSet, []string{"properties", "checked"}, checkedProperty(checked))
+}
+
+// Transaction returns a transaction with operations made by editing
+// methods since the last Commit
+func (p *Page) Transaction() *Transaction {
+	if p.tx == nil {
+		p.tx = NewTransaction("", p)
+	}
+	return p.tx
+}
+
+// SetEditor sets id of the user making changes with editing methods.
+// It's used for created_by and last_edited_by of edited blocks
+func (p *Page) SetEditor(userID string) {
+	p.Transaction().UserID = userID
+}
+
+// Commit submits changes made with editing methods
+func (p *Page) Commit() error {
+	return p.CommitCtx(context.Background())
+}
+
+// CommitCtx is like Commit but takes a context
+func (p *Page) CommitCtx(ctx context.Context) error {
+	if p.tx == nil || p.tx.Len() == 0 {
+		return nil
+	}
+	if p.client == nil {
+		return errors.New("page has no client to submit changes")
+	}
+	if err := p.client.CommitTransactionCtx(ctx, p.tx); err != nil {
+		return err
+	}
+	p.tx = NewTransaction(p.tx.UserID, p)
+	return nil
+}
+
+// edit applies operations to the page and adds them to the page's
+// transaction. If any of them fails, neither the page nor the
+// transaction change
+func (p *Page) edit(ops ...*Operation) error {
+	tx := p.Transaction()
+	if tx.UserID == "" {
+		return errors.New("page has no editor, call SetEditor first")
+	}
+	for _, op := range ops {
+		if err := ValidateOperation(op); err != nil {
+			return err
+		}
+	}
+	if err := p.applyOps(ops); err != nil {
+		return err
+	}
+	return tx.Add(ops...)
+}
+
+// pageBlock returns b if it's a block of this page, root of the page if
+// b is nil
+func (p *Page) pageBlock(b *Block) (*Block, error) {
+	if b == nil {
+		b = p.Root()
+		if b == nil {
+			return nil, fmt.Errorf("page '%s' has no root block", p.ID)
+		}
+		return b, nil
+	}
+	if p.idToBlock[b.ID] != b {
+		return nil, fmt.Errorf("block '%s' is not in page '%s'", b.ID, p.ID)
+	}
+	return b, nil
+}
+
+// parentOf returns the parent block of a block of this page
+func (p *Page) parentOf(b *Block) (*Block, error) {
+	if b.ParentTable == TableBlock {
+		if parent := p.idToBlock[b.ParentID]; parent != nil {
+			return parent, nil
+		}
+	}
+	return nil, fmt.Errorf("parent of block '%s' is not in page '%s'", b.ID, p.ID)
+}
+
+// newBlockOp creates an operation to create a block of type blockType
+// with a given parent, properties and format (both can be nil)
+func (p *Page) newBlockOp(parent *Block, blockType string, props map[string]interface{}, format map[string]interface{}) (string, *Operation) {
+	userID := p.Transaction().UserID
+	now := Now()
+	b := &Block{ID: uuid.New().String()}
+	args := map[string]interface{}{
+		"id":               b.ID,
+		"version":          1,
+		"alive":            true,
+		"type":             blockType,
+		"created_by":       userID,
+		"created_time":     now,
+		"last_edited_by":   userID,
+		"last_edited_time": now,
+		"parent_id":        parent.ID,
+		"parent_table":     TableBlock,
+		"space_id":         parent.SpaceID,
+	}
+	if props != nil {
+		args["properties"] = props
+	}
+	if format != nil {
+		args["format"] = format
+	}
+	return b.ID, b.buildOp(CommandSet, []string{}, args)
+}
+
+// insert creates a block of type blockType in parent, after block with
+// id afterID or as the last one if afterID is ""
+func (p *Page) insert(parent *Block, afterID string, blockType string, props map[string]interface{}, format map[string]interface{}) (*Block, error) {
+	id, op := p.newBlockOp(parent, blockType, props, format)
+	if err := p.edit(op, parent.ListAfterContentOp(id, afterID)); err != nil {
+		return nil, err
+	}
+	return p.idToBlock[id], nil
+}
+
+func (p *Page) appendBlock(parent *Block, blockType string, props map[string]interface{}, format map[string]interface{}) (*Block, error) {
+	parent, err := p.pageBlock(parent)
+	if err != nil {
+		return nil, err
+	}
+	return p.insert(parent, "", blockType, props, format)
+}
+
+// AppendText adds a text block at the end of parent (root of the page if nil)
+func (p *Page) AppendText(parent *Block, text string) (*Block, error) {
+	return p.appendBlock(parent, BlockText, map[string]interface{}{
+		"title": textProperty(text),
+	}, nil)
+}
+
+// AppendHeading adds a heading block of level 1, 2 or 3 at the end of
+// parent (root of the page if nil)
+func (p *Page) AppendHeading(parent *Block, level int, text string) (*Block, error) {
+	types := []string{BlockHeader, BlockSubHeader, BlockSubSubHeader}
+	if level < 1 || level > len(types) {
+		return nil, fmt.Errorf("invalid heading level %d, must be 1, 2 or 3", level)
+	}
+	return p.appendBlock(parent, types[level-1], map[string]interface{}{
+		"title": textProperty(text),
+	}, nil)
+}
+
+// AppendTodo adds a todo block at the end of parent (root of the page if nil)
+func (p *Page) AppendTodo(parent *Block, text string, checked bool) (*Block, error) {
+	return p.appendBlock(parent, BlockTodo, map[string]interface{}{
+		"title":   textProperty(text),
+		"checked": checkedProperty(checked),
+	}, nil)
+}
+
+// AppendCode adds a code block at the end of parent (root of the page
+// if nil). lang is a name of language, like "Go" or "JavaScript", as
+// shown by Notion. If "", it's CodeLanguagePlainText
+func (p *Page) AppendCode(parent *Block, lang string, code string) (*Block, error) {
+	if lang == "" {
+		lang = CodeLanguagePlainText
+	}
+	return p.appendBlock(parent, BlockCode, map[string]interface{}{
+		"title":    textProperty(code),
+		"language": textProperty(lang),
+	}, map[string]interface{}{
+		"code_wrap": true,
+	})
+}
+
+// AppendBulletedList adds a bulleted list block for each item at the
+// end of parent (root of the page if nil)
+func (p *Page) AppendBulletedList(parent *Block, items ...string) ([]*Block, error) {
+	var res []*Block
+	for _, item := range items {
+		b, err := p.appendBlock(parent, BlockBulletedList, map[string]interface{}{
+			"title": textProperty(item),
+		}, nil)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+// InsertAfter adds a block of type blockType (BlockText, BlockHeader,
+// BlockTodo, BlockBulletedList etc.) with a given text right after block
+// after, in the same parent
+func (p *Page) InsertAfter(after *Block, blockType string, text string) (*Block, error) {
+	after, err := p.pageBlock(after)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := p.parentOf(after)
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{
+		"title": textProperty(text),
+	}
+	var format map[string]interface{}
+	switch blockType {
+	case BlockTodo:
+		props["checked"] = checkedProperty(false)
+	case BlockCode:
+		props["language"] = textProperty(CodeLanguagePlainText)
+		format = map[string]interface{}{"code_wrap": true}
+	}
+	return p.insert(parent, after.ID, blockType, props, format)
+}
+
+// MoveBlock moves block b to newParent, after block after or as the
+// first block of newParent if after is nil. after must be a child of
+// newParent
+func (p *Page) MoveBlock(b *Block, newParent *Block, after *Block) error {
+	b, err := p.pageBlock(b)
+	if err != nil {
+		return err
+	}
+	oldParent, err := p.parentOf(b)
+	if err != nil {
+		return err
+	}
+	if newParent, err = p.pageBlock(newParent); err != nil {
+		return err
+	}
+	for parent := newParent; parent != nil; parent = p.idToBlock[parent.ParentID] {
+		if parent == b {
+			return fmt.Errorf("can't move block '%s' into itself", b.ID)
+		}
+		if parent.ParentTable != TableBlock {
+			break
+		}
+	}
+	if after != nil {
+		if after, err = p.pageBlock(after); err != nil {
+			return err
+		}
+		if after == b {
+			return fmt.Errorf("can't move block '%s' after itself", b.ID)
+		}
+		if after.ParentTable != TableBlock || after.ParentID != newParent.ID {
+			return fmt.Errorf("block '%s' is not a child of block '%s'", after.ID, newParent.ID)
+		}
+	}
+	ops := []*Operation{oldParent.ListRemoveContentOp(b.ID)}
+	if oldParent != newParent {
+		ops = append(ops, b.buildOp(CommandUpdate, []string{}, map[string]interface{}{
+			"parent_id":    newParent.ID,
+			"parent_table": TableBlock,
+		}))
+	}
+	if after == nil {
+		ops = append(ops, newParent.buildOp(CommandListBefore, []string{"content"}, map[string]string{
+			"id": b.ID,
+		}))
+	} else {
+		ops = append(ops, newParent.ListAfterContentOp(b.ID, after.ID))
+	}
+	return p.edit(ops...)
+}
+
+// DeleteBlock deletes block b. Like in Notion, the block is archived
+// (alive is false) and removed from content of its parent
+func (p *Page) DeleteBlock(b *Block) error {
+	b, err := p.pageBlock(b)
+	if err != nil {
+		return err
+	}
+	parent, err := p.parentOf(b)
+	if err != nil {
+		return err
+	}
+	return p.edit(
+		b.buildOp(CommandUpdate, []string{}, map[string]interface{}{
+			"alive": false,
+		}),
+		parent.ListRemoveContentOp(b.ID),
+	)
+}
+
+// SetChecked checks or unchecks todo block b
+func (p *Page) SetChecked(b *Block, checked bool) error {
+	b, err := p.pageBlock(b)
+	if err != nil {
+		return err
+	}
+	if b.Type != BlockTodo {
+		return fmt.Errorf("block '%s' is '%s', not '%s'", b.ID, b.Type, BlockTodo)
+	}
+	return p.edit(b.SetCheckedOp(checked))
+}
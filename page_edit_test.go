@@ -0,0 +1,99 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestPageEdit(t *testing.T) {
+	pageID := "11111111-1111-1111-1111-111111111111"
+	p := newTestPage(t,
+		map[string]interface{}{"id": pageID, "type": "page", "alive": true, "space_id": "space"},
+	)
+	root := p.Root()
+	_, err := p.AppendText(nil, "no editor")
+	assert.Error(t, err)
+	assert.Equal(t, 0, p.Transaction().Len())
+	p.SetEditor("user-1")
+
+	text, err := p.AppendText(nil, "hello")
+	assert.NoError(t, err)
+	h, err := p.AppendHeading(nil, 2, "Section")
+	assert.NoError(t, err)
+	todo, err := p.AppendTodo(h, "task", false)
+	assert.NoError(t, err)
+	code, err := p.AppendCode(nil, "Go", "fmt.Println()")
+	assert.NoError(t, err)
+	items, err := p.AppendBulletedList(nil, "a", "b")
+	assert.NoError(t, err)
+	_, err = p.AppendHeading(nil, 4, "nope")
+	assert.Error(t, err)
+
+	assert.Equal(t, []*Block{text, h, code, items[0], items[1]}, root.Content)
+	assert.Equal(t, "hello", text.InlineContent[0].Text)
+	assert.Equal(t, BlockSubHeader, h.Type)
+	assert.Equal(t, "space", h.SpaceID)
+	assert.Equal(t, "user-1", h.CreatedBy)
+	assert.Equal(t, []*Block{todo}, h.Content)
+	assert.Equal(t, h, todo.Parent)
+	assert.False(t, todo.IsChecked)
+	assert.Equal(t, "fmt.Println()", code.Code)
+	assert.Equal(t, "Go", code.CodeLanguage)
+	assert.Equal(t, BlockBulletedList, items[1].Type)
+
+	assert.NoError(t, p.SetChecked(todo, true))
+	assert.True(t, todo.IsChecked)
+	assert.Error(t, p.SetChecked(text, true))
+
+	ins, err := p.InsertAfter(text, BlockTodo, "inserted")
+	assert.NoError(t, err)
+	assert.Equal(t, ins, root.Content[1])
+
+	// move todo out of the heading, as the first block of the page
+	assert.NoError(t, p.MoveBlock(todo, nil, nil))
+	assert.Equal(t, 0, len(h.Content))
+	assert.Equal(t, todo, root.Content[0])
+	assert.Equal(t, root, todo.Parent)
+	assert.NoError(t, p.MoveBlock(todo, nil, code))
+	assert.Equal(t, []*Block{text, ins, h, code, todo, items[0], items[1]}, root.Content)
+	assert.Error(t, p.MoveBlock(h, h, nil))
+	// after must be a child of newParent in this page
+	n := p.Transaction().Len()
+	assert.Error(t, p.MoveBlock(code, h, text))
+	assert.Error(t, p.MoveBlock(code, nil, code))
+	assert.Error(t, p.MoveBlock(code, nil, &Block{ID: text.ID}))
+	assert.Equal(t, n, p.Transaction().Len())
+	assert.Equal(t, []*Block{text, ins, h, code, todo, items[0], items[1]}, root.Content)
+
+	assert.NoError(t, p.DeleteBlock(items[0]))
+	assert.Nil(t, p.BlockByID(items[0].GetNotionID()))
+	assert.Equal(t, 6, len(root.Content))
+
+	// operations that can't be applied are not added to the transaction
+	n = p.Transaction().Len()
+	err = p.edit(root.buildOp(CommandListAfter, []string{"type"}, map[string]interface{}{"id": text.ID}))
+	assert.Error(t, err)
+	assert.Equal(t, n, p.Transaction().Len())
+	assert.Equal(t, BlockPage, root.Type)
+
+	var submitted []*Operation
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, _ := ioutil.ReadAll(r.Body)
+		var req submitTransactionRequest
+		assert.NoError(t, json.Unmarshal(d, &req))
+		submitted = append(submitted, req.Operations...)
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+	p.client = &Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+	n = p.Transaction().Len()
+	assert.NoError(t, p.Commit())
+	assert.True(t, len(submitted) > n)
+	assert.Equal(t, 0, p.Transaction().Len())
+	assert.Equal(t, "user-1", p.Transaction().UserID)
+}
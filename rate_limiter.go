@@ -0,0 +1,124 @@
+package notionapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRequestsPerSecond is the average rate of requests allowed by
+	// default rate limiter, per https://developers.notion.com/reference/errors#rate-limits
+	DefaultRequestsPerSecond = 3
+	// DefaultRateLimitBurst is how many requests in a burst we allow
+	// by default
+	DefaultRateLimitBurst = 5
+)
+
+// RateLimiter limits the rate of requests sent to Notion.
+// It must be safe to use from multiple goroutines.
+type RateLimiter interface {
+	// Wait blocks until a request can be sent or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a RateLimiter that allows, on average, rate requests
+// per second with bursts of up to burst requests.
+// It's safe for concurrent use and can be shared by multiple Clients.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // max tokens in the bucket
+	tokens float64 // negative when there are goroutines waiting
+	last   time.Time
+}
+
+// NewTokenBucket returns a rate limiter that allows rate requests per second
+// on average, with bursts of up to burst requests
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and returns how long
+// we have to wait for it to be available
+func (b *TokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait implements RateLimiter
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	wait := b.reserve(time.Now())
+	if err := sleepCtx(ctx, wait); err != nil {
+		// we didn't use the token so give it back
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+type tokenBucketKey struct {
+	authToken string
+	rate      float64
+	burst     int
+}
+
+var (
+	sharedTokenBucketsMu sync.Mutex
+	sharedTokenBuckets   = map[tokenBucketKey]*TokenBucket{}
+)
+
+func sharedTokenBucket(authToken string, rate float64, burst int) *TokenBucket {
+	key := tokenBucketKey{authToken, rate, burst}
+	sharedTokenBucketsMu.Lock()
+	defer sharedTokenBucketsMu.Unlock()
+	b := sharedTokenBuckets[key]
+	if b == nil {
+		b = NewTokenBucket(rate, burst)
+		sharedTokenBuckets[key] = b
+	}
+	return b
+}
+
+// SharedRateLimiter returns a default rate limiter shared by all Clients
+// using a given authToken. Notion rate limits per integration token so
+// this is what Client uses if RateLimiter and MinRequestDelay are not set.
+func SharedRateLimiter(authToken string) RateLimiter {
+	return sharedTokenBucket(authToken, DefaultRequestsPerSecond, DefaultRateLimitBurst)
+}
+
+func (c *Client) getRateLimiter() RateLimiter {
+	if c.RateLimiter != nil {
+		return c.RateLimiter
+	}
+	if c.MinRequestDelay > 0 {
+		rate := float64(time.Second) / float64(c.MinRequestDelay)
+		return sharedTokenBucket(c.AuthToken, rate, 1)
+	}
+	return SharedRateLimiter(c.AuthToken)
+}
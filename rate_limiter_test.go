@@ -0,0 +1,64 @@
+package notionapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(10, 3)
+	now := b.last
+	// burst
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), b.reserve(now))
+	}
+	// bucket is empty, each request waits 100 ms longer than the previous
+	assert.Equal(t, time.Millisecond*100, b.reserve(now))
+	assert.Equal(t, time.Millisecond*200, b.reserve(now))
+	// after a second the bucket is refilled but never above burst
+	now = now.Add(time.Second * 10)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), b.reserve(now))
+	}
+	assert.Equal(t, time.Millisecond*100, b.reserve(now))
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	ctx := context.Background()
+	assert.NoError(t, b.Wait(ctx))
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, b.Wait(ctx))
+}
+
+func TestTokenBucketConcurrent(t *testing.T) {
+	b := NewTokenBucket(1000, 5)
+	var wg sync.WaitGroup
+	timeStart := time.Now()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, b.Wait(context.Background()))
+		}()
+	}
+	wg.Wait()
+	// 15 requests above the burst need at least 15 ms at 1000 req/sec
+	assert.True(t, time.Since(timeStart) >= time.Millisecond*14)
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	c1 := &Client{AuthToken: "token1"}
+	c2 := *c1
+	c3 := &Client{AuthToken: "token2"}
+	assert.True(t, c1.getRateLimiter() == c2.getRateLimiter())
+	assert.True(t, c1.getRateLimiter() != c3.getRateLimiter())
+	limiter := NewTokenBucket(1, 1)
+	c3.RateLimiter = limiter
+	assert.True(t, c3.getRateLimiter() == RateLimiter(limiter))
+}
@@ -0,0 +1,165 @@
+package notionapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RelationResolver follows relation columns into target collections
+// and computes rollups locally.
+// It remembers blocks and collections it downloaded so it should be
+// re-used when resolving many rows. It's safe for concurrent use
+type RelationResolver struct {
+	Client *Client
+
+	mu          sync.Mutex
+	blocks      map[string]*Block
+	collections map[string]*Collection
+}
+
+// NewRelationResolver creates RelationResolver that uses client for
+// downloading records
+func NewRelationResolver(client *Client) *RelationResolver {
+	return &RelationResolver{
+		Client:      client,
+		blocks:      map[string]*Block{},
+		collections: map[string]*Collection{},
+	}
+}
+
+// AddPage adds blocks and collections of a page we already have,
+// so that we don't download them again
+func (r *RelationResolver) AddPage(p *Page) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, b := range p.idToBlock {
+		r.blocks[id] = b
+	}
+	for id, c := range p.idToCollection {
+		r.collections[id] = c
+	}
+}
+
+func (r *RelationResolver) getBlocks(ctx context.Context, ids []string) ([]*Block, error) {
+	var missing []string
+	r.mu.Lock()
+	for _, id := range ids {
+		if _, ok := r.blocks[ToDashID(id)]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(missing) > 0 {
+		blocks, err := r.Client.GetBlockRecordsCtx(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		for i, b := range blocks {
+			// remember blocks we don't have access to so we don't ask again
+			r.blocks[ToDashID(missing[i])] = b
+		}
+		r.mu.Unlock()
+	}
+
+	var res []*Block
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if b := r.blocks[ToDashID(id)]; b != nil && b.Alive {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+// Collection returns a collection with a given id
+func (r *RelationResolver) Collection(ctx context.Context, id string) (*Collection, error) {
+	id = ToDashID(id)
+	r.mu.Lock()
+	c, ok := r.collections[id]
+	r.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	colls, err := r.Client.GetCollectionRecordsCtx(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	c = colls[0]
+	if c == nil {
+		return nil, fmt.Errorf("couldn't get collection '%s'", id)
+	}
+	r.mu.Lock()
+	r.collections[id] = c
+	r.mu.Unlock()
+	return c, nil
+}
+
+func (r *RelationResolver) relationColumn(row *TableRow, colID string) (*ColumnSchema, error) {
+	schema := row.columnSchema(colID)
+	if schema == nil {
+		return nil, fmt.Errorf("no column '%s'", colID)
+	}
+	if schema.Type != ColumnTypeRelation {
+		return nil, fmt.Errorf("column '%s' is '%s', not a relation", colID, schema.Type)
+	}
+	return schema, nil
+}
+
+// Related returns rows of the target collection that the row is
+// related to via relation column with id colID
+func (r *RelationResolver) Related(ctx context.Context, row *TableRow, colID string) ([]*TableRow, error) {
+	schema, err := r.relationColumn(row, colID)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := r.Collection(ctx, schema.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	ids := row.Value(colID).BlockIDs
+	blocks, err := r.getBlocks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// rows from other collections can't be resolved
+	tv := &TableView{
+		Collection: coll,
+	}
+	if row.TableView != nil {
+		tv.Page = row.TableView.Page
+	}
+	var res []*TableRow
+	for _, b := range blocks {
+		res = append(res, &TableRow{
+			TableView: tv,
+			Page:      b,
+		})
+	}
+	return res, nil
+}
+
+// Rollup calculates the value of a rollup column with id colID.
+// It follows the relation to get values of target property and
+// aggregates them according to ColumnSchema.Aggregation
+func (r *RelationResolver) Rollup(ctx context.Context, row *TableRow, colID string) (*FormulaValue, error) {
+	schema := row.columnSchema(colID)
+	if schema == nil {
+		return nil, fmt.Errorf("no column '%s'", colID)
+	}
+	if schema.Type != ColumnTypeRollup {
+		return nil, fmt.Errorf("column '%s' is '%s', not a rollup", colID, schema.Type)
+	}
+	related, err := r.Related(ctx, row, schema.RelationProperty)
+	if err != nil {
+		return nil, err
+	}
+	var values []*CellValue
+	for _, rel := range related {
+		values = append(values, rel.Value(schema.TargetProperty))
+	}
+	return aggregate(schema.Aggregation, values)
+}
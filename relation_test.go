@@ -0,0 +1,92 @@
+package notionapi_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kjk/common/require"
+	"github.com/kjk/notionapi"
+	"github.com/kjk/notionapi/notiontest"
+)
+
+func TestRelationResolver(t *testing.T) {
+	s := notiontest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+
+	projectsID := "11111111-1111-1111-1111-111111111111"
+	require.NoError(t, s.Store.Put(notionapi.TableCollection, map[string]interface{}{
+		"id":    projectsID,
+		"alive": true,
+		"schema": map[string]interface{}{
+			"title": map[string]interface{}{"name": "Name", "type": "title"},
+			"hrs":   map[string]interface{}{"name": "Hours", "type": "number"},
+			"done":  map[string]interface{}{"name": "Done", "type": "checkbox"},
+		},
+	}))
+	var projectIDs []string
+	for i, hours := range []string{"3", "5"} {
+		id := fmt.Sprintf("22222222-2222-2222-2222-22222222222%d", i)
+		projectIDs = append(projectIDs, id)
+		done := "No"
+		if i == 0 {
+			done = "Yes"
+		}
+		require.NoError(t, s.Store.Put(notionapi.TableBlock, map[string]interface{}{
+			"id":           id,
+			"type":         "page",
+			"alive":        true,
+			"parent_id":    projectsID,
+			"parent_table": "collection",
+			"properties": map[string]interface{}{
+				"title": []interface{}{[]interface{}{fmt.Sprintf("Project %d", i)}},
+				"hrs":   []interface{}{[]interface{}{hours}},
+				"done":  []interface{}{[]interface{}{done}},
+			},
+		}))
+	}
+
+	tasks := &notionapi.Collection{
+		Schema: map[string]*notionapi.ColumnSchema{
+			"rel":  {Name: "Projects", Type: notionapi.ColumnTypeRelation, CollectionID: projectsID},
+			"sum":  {Name: "Total hours", Type: notionapi.ColumnTypeRollup, RelationProperty: "rel", TargetProperty: "hrs", Aggregation: notionapi.AggregationSum},
+			"pct":  {Name: "Done", Type: notionapi.ColumnTypeRollup, RelationProperty: "rel", TargetProperty: "done", Aggregation: notionapi.AggregationPercentChecked},
+			"orig": {Name: "Names", Type: notionapi.ColumnTypeRollup, RelationProperty: "rel", TargetProperty: "title"},
+		},
+	}
+	var rel []interface{}
+	for i, id := range projectIDs {
+		if i > 0 {
+			rel = append(rel, []interface{}{","})
+		}
+		rel = append(rel, []interface{}{"‣", []interface{}{[]interface{}{"p", id}}})
+	}
+	row := &notionapi.TableRow{
+		TableView: &notionapi.TableView{Collection: tasks},
+		Page: &notionapi.Block{
+			ID:         "33333333-3333-3333-3333-333333333333",
+			Properties: map[string]interface{}{"rel": rel},
+		},
+	}
+
+	r := notionapi.NewRelationResolver(client)
+	ctx := context.Background()
+	related, err := r.Related(ctx, row, "rel")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(related))
+	require.Equal(t, "Project 1", related[1].Value("title").Text)
+
+	v, err := r.Rollup(ctx, row, "sum")
+	require.NoError(t, err)
+	require.Equal(t, 8.0, v.Number)
+	v, err = r.Rollup(ctx, row, "pct")
+	require.NoError(t, err)
+	require.Equal(t, 50.0, v.Number)
+	v, err = r.Rollup(ctx, row, "orig")
+	require.NoError(t, err)
+	require.Equal(t, "Project 0, Project 1", v.String())
+
+	_, err = r.Rollup(ctx, row, "rel")
+	require.True(t, err != nil)
+}
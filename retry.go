@@ -0,0 +1,139 @@
+package notionapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy decides if a failed API request should be retried
+type RetryPolicy interface {
+	// RetryDelay is called when attempt (0 for the first request) to call
+	// endpoint (e.g. "syncRecordValues") failed with err. For non-200
+	// responses err is *APIError.
+	// Returns how long to wait before the next attempt and false if
+	// the request should not be retried.
+	RetryDelay(endpoint string, attempt int, err error) (time.Duration, bool)
+}
+
+var (
+	// DefaultRetryPolicy is used when Client.RetryPolicy is not set
+	DefaultRetryPolicy RetryPolicy = &ExponentialBackoff{
+		MaxRetries: 3,
+		BaseDelay:  time.Second * 2,
+		MaxDelay:   time.Second * 30,
+	}
+
+	// NoRetryPolicy never retries requests
+	NoRetryPolicy RetryPolicy = &ExponentialBackoff{}
+
+	// endpoints that change data on the server. We don't know if a request
+	// that timed out or failed with 5xx was applied, so it's not safe to
+	// blindly repeat them
+	nonIdempotentEndpoints = map[string]bool{
+		"submitTransaction": true,
+		"enqueueTask":       true,
+		"createEmailUser":   true,
+		"getUploadFileUrl":  true,
+	}
+)
+
+// IsIdempotentEndpoint returns true if it's safe to repeat a request
+// to a given endpoint (e.g. "syncRecordValues") even if it might have
+// reached the server.
+func IsIdempotentEndpoint(endpoint string) bool {
+	return !nonIdempotentEndpoints[endpoint]
+}
+
+// ExponentialBackoff is a RetryPolicy that waits exponentially longer
+// (with random jitter) between attempts.
+// 429 (rate limited) responses are retried for all endpoints.
+// 5xx responses and network errors are only retried for idempotent
+// endpoints.
+type ExponentialBackoff struct {
+	// how many times to retry, 0 means never
+	MaxRetries int
+	// delay before first retry, doubled on each subsequent retry
+	BaseDelay time.Duration
+	// cap on delay between retries, including delay from Retry-After header.
+	// 0 means no cap
+	MaxDelay time.Duration
+	// over-rides IsIdempotentEndpoint for a given endpoint
+	Idempotent map[string]bool
+}
+
+func (p *ExponentialBackoff) isIdempotent(endpoint string) bool {
+	if v, ok := p.Idempotent[endpoint]; ok {
+		return v
+	}
+	return IsIdempotentEndpoint(endpoint)
+}
+
+// RetryDelay implements RetryPolicy
+func (p *ExponentialBackoff) RetryDelay(endpoint string, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+	var retryAfter time.Duration
+	if e, ok := AsAPIError(err); ok {
+		switch e.StatusCode {
+		case http.StatusTooManyRequests:
+			// the server didn't process the request so it's always safe to retry
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if !p.isIdempotent(endpoint) {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+		retryAfter = e.RetryAfter
+	} else if !isTransientNetworkError(err) || !p.isIdempotent(endpoint) {
+		return 0, false
+	}
+
+	delay := retryAfter
+	if delay == 0 {
+		delay = p.BaseDelay
+		for i := 0; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+			delay *= 2
+		}
+		// "equal jitter": random value between delay/2 and delay
+		if half := int64(delay / 2); half > 0 {
+			delay = time.Duration(half + rand.Int63n(half+1))
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// isTransientNetworkError returns true if err looks like a network error
+// that might go away if we repeat the request
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	// *url.Error is a net.Error but it wraps every error from http.Client.Do()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *Client) getRetryPolicy() RetryPolicy {
+	if c.RetryPolicy != nil {
+		return c.RetryPolicy
+	}
+	return DefaultRetryPolicy
+}
@@ -0,0 +1,94 @@
+package notionapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	p := &ExponentialBackoff{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   time.Second * 10,
+	}
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests}
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	d, ok := p.RetryDelay("syncRecordValues", 0, unavailable)
+	assert.True(t, ok)
+	assert.True(t, d >= time.Second/2 && d <= time.Second)
+
+	d, ok = p.RetryDelay("syncRecordValues", 2, unavailable)
+	assert.True(t, ok)
+	assert.True(t, d >= time.Second*2 && d <= time.Second*4)
+
+	_, ok = p.RetryDelay("syncRecordValues", 3, unavailable)
+	assert.False(t, ok)
+
+	// non-idempotent endpoints are only retried when rate limited
+	_, ok = p.RetryDelay("submitTransaction", 0, unavailable)
+	assert.False(t, ok)
+	_, ok = p.RetryDelay("submitTransaction", 0, io.ErrUnexpectedEOF)
+	assert.False(t, ok)
+	_, ok = p.RetryDelay("submitTransaction", 0, rateLimited)
+	assert.True(t, ok)
+	_, ok = p.RetryDelay("syncRecordValues", 0, io.ErrUnexpectedEOF)
+	assert.True(t, ok)
+
+	// Retry-After is honored but capped at MaxDelay
+	d, _ = p.RetryDelay("syncRecordValues", 0, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second * 5})
+	assert.Equal(t, time.Second*5, d)
+	d, _ = p.RetryDelay("syncRecordValues", 0, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute})
+	assert.Equal(t, time.Second*10, d)
+
+	// other errors are not retried
+	_, ok = p.RetryDelay("syncRecordValues", 0, &APIError{StatusCode: http.StatusUnauthorized})
+	assert.False(t, ok)
+	_, ok = p.RetryDelay("syncRecordValues", 0, context.Canceled)
+	assert.False(t, ok)
+}
+
+func TestClientRetries(t *testing.T) {
+	nRequests := 0
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nRequests++
+		if nRequests == 1 {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{
+		MinRequestDelay: time.Nanosecond,
+		RetryPolicy: &ExponentialBackoff{
+			MaxRetries: 2,
+			BaseDelay:  time.Millisecond,
+		},
+	}
+	ctx := context.Background()
+	_, err := client.doPostInternal(ctx, ts.URL+"/api/v3/syncRecordValues", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, nRequests)
+
+	nRequests = 0
+	_, err = client.doPostInternal(ctx, ts.URL+"/api/v3/submitTransaction", nil)
+	e, ok := AsAPIError(err)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, e.StatusCode)
+	assert.Equal(t, 1, nRequests)
+
+	nRequests = 0
+	status = http.StatusTooManyRequests
+	_, err = client.doPostInternal(ctx, ts.URL+"/api/v3/submitTransaction", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, nRequests)
+}
@@ -0,0 +1,325 @@
+package notionapi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// buildOp creates an Operation for this collection
+func (c *Collection) buildOp(command string, path []string, args interface{}) *Operation {
+	return &Operation{
+		ID:      c.ID,
+		Table:   TableCollection,
+		Path:    path,
+		Command: command,
+		Args:    args,
+	}
+}
+
+// AddOptionOp creates an operation to add an option to a select or
+// multi select column
+func (c *Collection) AddOptionOp(colID string, option *CollectionColumnOption) *Operation {
+	return c.buildOp(CommandKeyedObjectListAfter, []string{"schema", colID, "options"}, map[string]interface{}{
+		"value": option,
+	})
+}
+
+// rowEncoder encodes cell values of rows of a collection and remembers
+// options of select and multi select columns that have to be created
+type rowEncoder struct {
+	collection *Collection
+	// options we'll create, by column id
+	newOptions map[string][]*CollectionColumnOption
+	ops        []*Operation
+}
+
+func (e *rowEncoder) option(colID string, schema *ColumnSchema, value string) string {
+	for _, o := range schema.Options {
+		if o.Value == value {
+			return value
+		}
+	}
+	for _, o := range e.newOptions[colID] {
+		if o.Value == value {
+			return value
+		}
+	}
+	o := &CollectionColumnOption{
+		ID:    uuid.New().String(),
+		Color: "default",
+		Value: value,
+	}
+	if e.newOptions == nil {
+		e.newOptions = map[string][]*CollectionColumnOption{}
+	}
+	e.newOptions[colID] = append(e.newOptions[colID], o)
+	e.ops = append(e.ops, e.collection.AddOptionOp(colID, o))
+	return value
+}
+
+// addNewOptions adds options created by the encoder to the schema of the
+// collection, so that the next call doesn't create them again
+func (e *rowEncoder) addNewOptions() {
+	for colID, opts := range e.newOptions {
+		if schema := e.collection.Schema[colID]; schema != nil {
+			schema.Options = append(schema.Options, opts...)
+		}
+	}
+}
+
+// mentions returns "‣" spans with attribute attr for each id, separated by ","
+func mentions(attr string, ids []string) []*TextSpan {
+	var res []*TextSpan
+	for i, id := range ids {
+		if i > 0 {
+			res = append(res, &TextSpan{Text: ","})
+		}
+		res = append(res, &TextSpan{
+			Text:  TextSpanSpecial,
+			Attrs: []TextAttr{{attr, id}},
+		})
+	}
+	return res
+}
+
+func optionValues(v *CellValue) []string {
+	var res []string
+	for _, o := range v.Options {
+		res = append(res, o.Value)
+	}
+	if len(res) == 0 && v.Text != "" {
+		for _, s := range strings.Split(v.Text, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
+
+// cellDate returns the date of a cell, from Date or Time. nil if not set
+func cellDate(v *CellValue) *Date {
+	if v.Date != nil {
+		return v.Date
+	}
+	if v.Time.IsZero() {
+		return nil
+	}
+	d := &Date{
+		Type:      "date",
+		StartDate: v.Time.Format("2006-01-02"),
+	}
+	if v.Time.Hour() != 0 || v.Time.Minute() != 0 {
+		d.Type = "datetime"
+		d.StartTime = v.Time.Format("15:04")
+		if loc := v.Time.Location(); loc != time.UTC && loc != time.Local {
+			tz := loc.String()
+			d.TimeZone = &tz
+		}
+	}
+	return d
+}
+
+// encode returns text spans for value v of a column colID. Returns nil
+// spans if the value is empty and the property should be cleared.
+// For number and checkbox columns Number and Checkbox are always used
+func (e *rowEncoder) encode(colID string, schema *ColumnSchema, v *CellValue) ([]*TextSpan, error) {
+	text := func(s string) []*TextSpan {
+		if s == "" {
+			return nil
+		}
+		return []*TextSpan{{Text: s}}
+	}
+	switch schema.Type {
+	case ColumnTypeTitle, ColumnTypeText:
+		if len(v.TextSpans) > 0 {
+			return v.TextSpans, nil
+		}
+		return text(v.Text), nil
+	case ColumnTypeURL, ColumnTypeEmail, ColumnTypePhoneNumber:
+		return text(v.Text), nil
+	case ColumnTypeNumber:
+		return text(strconv.FormatFloat(v.Number, 'f', -1, 64)), nil
+	case ColumnTypeCheckbox:
+		if v.Checkbox {
+			return text("Yes"), nil
+		}
+		return text("No"), nil
+	case ColumnTypeSelect:
+		vals := optionValues(v)
+		if len(vals) == 0 {
+			return nil, nil
+		}
+		if len(vals) > 1 {
+			return nil, fmt.Errorf("select column '%s' can only have one value, got %d", colID, len(vals))
+		}
+		return text(e.option(colID, schema, vals[0])), nil
+	case ColumnTypeMultiSelect:
+		vals := optionValues(v)
+		for i, s := range vals {
+			if strings.Contains(s, ",") {
+				return nil, fmt.Errorf("option '%s' of column '%s' can't contain ','", s, colID)
+			}
+			vals[i] = e.option(colID, schema, s)
+		}
+		return text(strings.Join(vals, ",")), nil
+	case ColumnTypeDate:
+		d := cellDate(v)
+		if d == nil {
+			return nil, nil
+		}
+		js, err := jsonit.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		return []*TextSpan{{
+			Text:  TextSpanSpecial,
+			Attrs: []TextAttr{{AttrDate, string(js)}},
+		}}, nil
+	case ColumnTypePerson:
+		ids := v.UserIDs
+		if len(ids) == 0 {
+			for _, u := range v.Users {
+				ids = append(ids, u.ID)
+			}
+		}
+		return mentions(AttrUser, ids), nil
+	case ColumnTypeRelation:
+		return mentions(AttrPage, v.BlockIDs), nil
+	case ColumnTypeFile:
+		var res []*TextSpan
+		for i, uri := range v.FileURLs {
+			if i > 0 {
+				res = append(res, &TextSpan{Text: ","})
+			}
+			res = append(res, &TextSpan{
+				Text:  path.Base(uri),
+				Attrs: []TextAttr{{AttrLink, uri}},
+			})
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("column '%s' of type '%s' can't be set", colID, schema.Type)
+}
+
+// encodeValues encodes values, keyed by column id or name, into
+// properties of a row
+func (e *rowEncoder) encodeValues(values map[string]CellValue) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	for nameOrID, v := range values {
+		colID, schema := findColumn(e.collection, nameOrID)
+		if schema == nil {
+			return nil, fmt.Errorf("collection '%s' has no column '%s'", e.collection.ID, nameOrID)
+		}
+		spans, err := e.encode(colID, schema, &v)
+		if err != nil {
+			return nil, err
+		}
+		if len(spans) == 0 {
+			props[colID] = nil
+			continue
+		}
+		props[colID] = EncodeTextSpans(spans)
+	}
+	return props, nil
+}
+
+// NewRowOps creates operations that add a row (a page) to the collection.
+// values are keyed by column id or name. Options of select and multi
+// select columns that are not in the schema are created
+func (c *Collection) NewRowOps(userID string, values map[string]CellValue) (*Block, []*Operation, error) {
+	e := &rowEncoder{collection: c}
+	props, err := e.encodeValues(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	for id, v := range props {
+		if v == nil {
+			delete(props, id)
+		}
+	}
+	now := Now()
+	row := &Block{
+		ID:             uuid.New().String(),
+		Version:        1,
+		Alive:          true,
+		Type:           BlockPage,
+		CreatedBy:      userID,
+		CreatedTime:    now,
+		LastEditedBy:   userID,
+		LastEditedTime: now,
+		ParentID:       c.ID,
+		ParentTable:    TableCollection,
+		SpaceID:        c.SpaceID,
+		Properties:     props,
+	}
+	op := row.buildOp(CommandSet, []string{}, map[string]interface{}{
+		"id":               row.ID,
+		"version":          row.Version,
+		"alive":            row.Alive,
+		"type":             row.Type,
+		"created_by":       row.CreatedBy,
+		"created_time":     row.CreatedTime,
+		"last_edited_by":   row.LastEditedBy,
+		"last_edited_time": row.LastEditedTime,
+		"parent_id":        row.ParentID,
+		"parent_table":     row.ParentTable,
+		"space_id":         row.SpaceID,
+		"properties":       props,
+	})
+	// options must exist before the row that uses them
+	ops := append(e.ops, op)
+	e.addNewOptions()
+	return row, ops, nil
+}
+
+func (r *TableRow) collection() (*Collection, error) {
+	if r.TableView == nil || r.TableView.Collection == nil {
+		return nil, fmt.Errorf("row '%s' has no collection", r.Page.ID)
+	}
+	return r.TableView.Collection, nil
+}
+
+// UpdateOps creates operations that set values of cells of the row.
+// values are keyed by column id or name. An empty value clears the cell
+func (r *TableRow) UpdateOps(userID string, values map[string]CellValue) ([]*Operation, error) {
+	c, err := r.collection()
+	if err != nil {
+		return nil, err
+	}
+	e := &rowEncoder{collection: c}
+	props, err := e.encodeValues(values)
+	if err != nil {
+		return nil, err
+	}
+	var colIDs []string
+	for colID := range props {
+		colIDs = append(colIDs, colID)
+	}
+	sort.Strings(colIDs)
+	ops := e.ops
+	for _, colID := range colIDs {
+		ops = append(ops, r.Page.buildOp(CommandSet, []string{"properties", colID}, props[colID]))
+	}
+	ops = append(ops, r.Page.UpdateOp(&Block{LastEditedTime: Now(), LastEditedBy: userID}))
+	e.addNewOptions()
+	return ops, nil
+}
+
+// ArchiveOps creates operations that delete the row. Like in Notion,
+// the row can be restored by setting alive back to true
+func (r *TableRow) ArchiveOps(userID string) []*Operation {
+	return []*Operation{
+		r.Page.buildOp(CommandUpdate, []string{}, map[string]interface{}{
+			"alive":            false,
+			"last_edited_by":   userID,
+			"last_edited_time": Now(),
+		}),
+	}
+}
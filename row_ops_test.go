@@ -0,0 +1,126 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kjk/common/assert"
+)
+
+// applyRowOps applies ops to records keyed by table and id, like Notion would
+func applyRowOps(t *testing.T, records map[string]map[string]interface{}, ops []*Operation) {
+	for _, op := range ops {
+		key := op.Table + ":" + op.ID
+		rec, err := ApplyOperation(records[key], op)
+		assert.NoError(t, err)
+		records[key] = rec
+	}
+}
+
+func rowFromRecord(t *testing.T, rec map[string]interface{}, c *Collection) *TableRow {
+	d, err := json.Marshal(rec)
+	assert.NoError(t, err)
+	var b Block
+	assert.NoError(t, json.Unmarshal(d, &b))
+	return &TableRow{
+		TableView: &TableView{Collection: c},
+		Page:      &b,
+	}
+}
+
+func TestRowOps(t *testing.T) {
+	c := &Collection{
+		ID:      "coll",
+		SpaceID: "space",
+		Schema: map[string]*ColumnSchema{
+			"title": {Name: "Name", Type: ColumnTypeTitle},
+			"num":   {Name: "Num", Type: ColumnTypeNumber},
+			"chk":   {Name: "Check", Type: ColumnTypeCheckbox},
+			"sel":   {Name: "Status", Type: ColumnTypeSelect, Options: []*CollectionColumnOption{{ID: "o1", Value: "Done"}}},
+			"msel":  {Name: "Tags", Type: ColumnTypeMultiSelect},
+			"date":  {Name: "Due", Type: ColumnTypeDate},
+			"who":   {Name: "Owner", Type: ColumnTypePerson},
+			"rel":   {Name: "Projects", Type: ColumnTypeRelation},
+			"url":   {Name: "Link", Type: ColumnTypeURL},
+			"ct":    {Name: "Created", Type: ColumnTypeCreatedTime},
+		},
+	}
+	values := map[string]CellValue{
+		"Name":     {TextSpans: []*TextSpan{{Text: "bold", Attrs: []TextAttr{{AttrBold}}}, {Text: " page"}}},
+		"num":      {Number: 1.5},
+		"Check":    {Checkbox: true},
+		"Status":   {Text: "In progress"},
+		"Tags":     {Options: []*CollectionColumnOption{{Value: "go"}, {Value: "rust"}}},
+		"Due":      {Time: time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC)},
+		"Owner":    {UserIDs: []string{"user-1", "user-2"}},
+		"Projects": {BlockIDs: []string{"page-1"}},
+		"Link":     {Text: "https://example.com"},
+	}
+	row, ops, err := c.NewRowOps("user-1", values)
+	assert.NoError(t, err)
+	assert.Equal(t, TableCollection, row.ParentTable)
+	assert.Equal(t, "coll", row.ParentID)
+	// new options are created before the row
+	assert.Equal(t, 4, len(ops))
+	for _, op := range ops[:3] {
+		assert.Equal(t, CommandKeyedObjectListAfter, op.Command)
+		assert.Equal(t, TableCollection, op.Table)
+	}
+	assert.Equal(t, TableBlock, ops[3].Table)
+
+	records := map[string]map[string]interface{}{}
+	applyRowOps(t, records, ops)
+	assert.Equal(t, 2, len(records))
+	opts := records["collection:coll"]["schema"].(map[string]interface{})["msel"].(map[string]interface{})["options"].([]interface{})
+	assert.Equal(t, 2, len(opts))
+
+	r := rowFromRecord(t, records["block:"+row.ID], c)
+	assert.Equal(t, "bold page", r.Value("title").Text)
+	assert.Equal(t, [][]string{{AttrBold}}, r.Value("title").TextSpans[0].Attrs)
+	assert.Equal(t, 1.5, r.Value("num").Number)
+	assert.True(t, r.Value("chk").Checkbox)
+	assert.Equal(t, "In progress", r.Value("sel").Text)
+	assert.Equal(t, "go,rust", r.Value("msel").Text)
+	assert.Equal(t, "2021-05-03", r.Value("date").Date.StartDate)
+	assert.Equal(t, []string{"user-1", "user-2"}, r.Value("who").UserIDs)
+	assert.Equal(t, []string{"page-1"}, r.Value("rel").BlockIDs)
+	assert.Equal(t, "https://example.com", r.Value("url").Text)
+
+	assert.Equal(t, "space", records["block:"+row.ID]["space_id"])
+	// created options are added to the schema
+	assert.Equal(t, 2, len(c.Schema["sel"].Options))
+	assert.Equal(t, 2, len(c.Schema["msel"].Options))
+	_, ops, err = c.NewRowOps("user-1", map[string]CellValue{
+		"Status": {Text: "In progress"},
+		"Tags":   {Text: "rust,go"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ops))
+
+	// existing options are not re-created
+	ops, err = r.UpdateOps("user-2", map[string]CellValue{
+		"Status": {Text: "Done"},
+		"Owner":  {},
+		"num":    {Number: 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(ops))
+	applyRowOps(t, records, ops)
+	r = rowFromRecord(t, records["block:"+row.ID], c)
+	assert.Equal(t, "Done", r.Value("sel").Text)
+	assert.True(t, r.Value("who").IsEmpty())
+	assert.Equal(t, 3.0, r.Value("num").Number)
+	assert.Equal(t, "user-2", r.Page.LastEditedBy)
+
+	applyRowOps(t, records, r.ArchiveOps("user-2"))
+	r = rowFromRecord(t, records["block:"+row.ID], c)
+	assert.False(t, r.Page.Alive)
+
+	_, _, err = c.NewRowOps("user-1", map[string]CellValue{"nope": {Text: "x"}})
+	assert.Error(t, err)
+	_, _, err = c.NewRowOps("user-1", map[string]CellValue{"Created": {Time: time.Now()}})
+	assert.Error(t, err)
+	_, _, err = c.NewRowOps("user-1", map[string]CellValue{"Status": {Text: "a,b"}})
+	assert.Error(t, err)
+}
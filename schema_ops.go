@@ -0,0 +1,190 @@
+package notionapi
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// colors of options of select and multi select columns
+const (
+	ColorDefault = "default"
+	ColorGray    = "gray"
+	ColorBrown   = "brown"
+	ColorOrange  = "orange"
+	ColorYellow  = "yellow"
+	ColorGreen   = "green"
+	ColorBlue    = "blue"
+	ColorPurple  = "purple"
+	ColorPink    = "pink"
+	ColorRed     = "red"
+)
+
+const columnIDChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&()*+,-./:;<=>?@[]^_`{|}~"
+
+// newColumnID generates a random 4 character id of a column, like
+// the ones Notion uses, that is not yet used in the schema nor returned
+// by a previous call
+func (c *Collection) newColumnID() string {
+	for {
+		b := make([]byte, 4)
+		for i := range b {
+			b[i] = columnIDChars[rand.Intn(len(columnIDChars))]
+		}
+		id := string(b)
+		if _, exists := c.Schema[id]; exists || id == "title" || c.pendingColumnIDs[id] {
+			continue
+		}
+		if c.pendingColumnIDs == nil {
+			c.pendingColumnIDs = map[string]bool{}
+		}
+		c.pendingColumnIDs[id] = true
+		return id
+	}
+}
+
+// AddColumnOp creates an operation to add a column of type colType
+// (ColumnTypeText etc.). Returns id of the new column
+func (c *Collection) AddColumnOp(name string, colType string) (string, *Operation) {
+	id := c.newColumnID()
+	op := c.buildOp(CommandSet, []string{"schema", id}, map[string]interface{}{
+		"name": name,
+		"type": colType,
+	})
+	return id, op
+}
+
+// RenameColumnOp creates an operation to rename a column
+func (c *Collection) RenameColumnOp(colID string, name string) *Operation {
+	return c.buildOp(CommandSet, []string{"schema", colID, "name"}, name)
+}
+
+// ChangeColumnTypeOp creates an operation to change type of a column.
+// Like in Notion, values of cells are kept as they are
+func (c *Collection) ChangeColumnTypeOp(colID string, colType string) *Operation {
+	return c.buildOp(CommandSet, []string{"schema", colID, "type"}, colType)
+}
+
+// RemoveColumnOp creates an operation to remove a column.
+// Like Notion, it doesn't allow removing the title column
+func (c *Collection) RemoveColumnOp(colID string) (*Operation, error) {
+	if colID == "title" {
+		return nil, errors.New("title column can't be removed")
+	}
+	op := c.buildOp(CommandUpdate, []string{"schema"}, map[string]interface{}{
+		colID: nil,
+	})
+	return op, nil
+}
+
+// NewOptionOp creates an operation to add an option to a select or
+// multi select column. Returns the new option
+func (c *Collection) NewOptionOp(colID string, value string, color string) (*CollectionColumnOption, *Operation) {
+	o := &CollectionColumnOption{
+		ID:    uuid.New().String(),
+		Color: color,
+		Value: value,
+	}
+	return o, c.AddOptionOp(colID, o)
+}
+
+// SetOptionColorOp creates an operation to change color of an option
+// of a select or multi select column
+func (c *Collection) SetOptionColorOp(colID string, optionID string, color string) *Operation {
+	return c.buildOp(CommandKeyedObjectListUpdate, []string{"schema", colID, "options"}, map[string]interface{}{
+		"value": map[string]interface{}{
+			"id":    optionID,
+			"color": color,
+		},
+	})
+}
+
+// buildOp creates an Operation for this collection view
+func (cv *CollectionView) buildOp(command string, path []string, args interface{}) *Operation {
+	return &Operation{
+		ID:      cv.ID,
+		Table:   TableCollectionView,
+		Path:    path,
+		Command: command,
+		Args:    args,
+	}
+}
+
+// SetTablePropertiesOp creates an operation to set order, visibility and
+// width of columns of a table view
+func (cv *CollectionView) SetTablePropertiesOp(props []*TableProperty) *Operation {
+	return cv.buildOp(CommandSet, []string{"format", "table_properties"}, props)
+}
+
+// propertiesKey returns the key in format of a view of a given type
+// with properties it shows
+func propertiesKey(viewType string) string {
+	switch viewType {
+	case CollectionViewTypeList, CollectionViewTypeBoard, CollectionViewTypeGallery,
+		CollectionViewTypeCalendar, CollectionViewTypeTimeline:
+		return viewType + "_properties"
+	}
+	return "table_properties"
+}
+
+// viewProperties returns a copy of properties shown by the view
+func (cv *CollectionView) viewProperties() []*TableProperty {
+	var res []*TableProperty
+	if cv.Format != nil {
+		for _, p := range cv.Format.Properties(cv.Type) {
+			cp := *p
+			res = append(res, &cp)
+		}
+	}
+	return res
+}
+
+// setViewPropertiesOp creates an operation to set properties shown by
+// the view, in the list for its type
+func (cv *CollectionView) setViewPropertiesOp(props []*TableProperty) *Operation {
+	return cv.buildOp(CommandSet, []string{"format", propertiesKey(cv.Type)}, props)
+}
+
+func tablePropertyIndex(props []*TableProperty, colID string) int {
+	for i, p := range props {
+		if p.Property == colID {
+			return i
+		}
+	}
+	return -1
+}
+
+// MoveColumnOp creates an operation to move a column after a column
+// afterColID, in properties of the view's type (board_properties for
+// a board etc.). If afterColID is "", the column becomes the first one.
+// A column that is not in the view is added as visible
+func (cv *CollectionView) MoveColumnOp(colID string, afterColID string) *Operation {
+	props := cv.viewProperties()
+	prop := &TableProperty{Property: colID, Visible: true}
+	if i := tablePropertyIndex(props, colID); i >= 0 {
+		prop = props[i]
+		props = append(props[:i], props[i+1:]...)
+	}
+	idx := 0
+	if i := tablePropertyIndex(props, afterColID); i >= 0 {
+		idx = i + 1
+	}
+	props = append(props, nil)
+	copy(props[idx+1:], props[idx:])
+	props[idx] = prop
+	return cv.setViewPropertiesOp(props)
+}
+
+// SetColumnVisibleOp creates an operation to show or hide a column in
+// properties of the view's type. A column that is not in the view is
+// added at the end
+func (cv *CollectionView) SetColumnVisibleOp(colID string, visible bool) *Operation {
+	props := cv.viewProperties()
+	if i := tablePropertyIndex(props, colID); i >= 0 {
+		props[i].Visible = visible
+	} else {
+		props = append(props, &TableProperty{Property: colID, Visible: visible})
+	}
+	return cv.setViewPropertiesOp(props)
+}
@@ -0,0 +1,125 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+// applyToRecord applies ops to json representation of v and decodes
+// the result back into v
+func applyToRecord(t *testing.T, v interface{}, ops ...*Operation) {
+	d, err := json.Marshal(v)
+	assert.NoError(t, err)
+	var rec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(d, &rec))
+	for _, op := range ops {
+		rec, err = ApplyOperation(rec, op)
+		assert.NoError(t, err)
+	}
+	d, err = json.Marshal(rec)
+	assert.NoError(t, err)
+	// unmarshal into a zero value so that removed keys are gone
+	rv := reflect.ValueOf(v).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	assert.NoError(t, json.Unmarshal(d, v))
+}
+
+func TestSchemaOps(t *testing.T) {
+	c := &Collection{
+		ID: "coll",
+		Schema: map[string]*ColumnSchema{
+			"title": {Name: "Name", Type: ColumnTypeTitle},
+			"st":    {Name: "Status", Type: ColumnTypeSelect, Options: []*CollectionColumnOption{{ID: "o1", Value: "Done", Color: ColorGreen}}},
+			"old":   {Name: "Old", Type: ColumnTypeText},
+		},
+	}
+	id, addOp := c.AddColumnOp("Estimate", ColumnTypeText)
+	assert.Equal(t, 4, len(id))
+	assert.Equal(t, TableCollection, addOp.Table)
+	opt, optOp := c.NewOptionOp("st", "Todo", ColorRed)
+	removeOp, err := c.RemoveColumnOp("old")
+	assert.NoError(t, err)
+	_, err = c.RemoveColumnOp("title")
+	assert.Error(t, err)
+	applyToRecord(t, c,
+		addOp,
+		c.RenameColumnOp(id, "Points"),
+		c.ChangeColumnTypeOp(id, ColumnTypeNumber),
+		removeOp,
+		optOp,
+		c.SetOptionColorOp("st", "o1", ColorBlue),
+	)
+	assert.Equal(t, 3, len(c.Schema))
+	assert.Equal(t, "Points", c.Schema[id].Name)
+	assert.Equal(t, ColumnTypeNumber, c.Schema[id].Type)
+	assert.Nil(t, c.Schema["old"])
+	opts := c.Schema["st"].Options
+	assert.Equal(t, 2, len(opts))
+	assert.Equal(t, ColorBlue, opts[0].Color)
+	assert.Equal(t, "Done", opts[0].Value)
+	assert.Equal(t, opt.ID, opts[1].ID)
+	assert.Equal(t, ColorRed, opts[1].Color)
+
+	_, err = ApplyOperation(map[string]interface{}{}, c.SetOptionColorOp("st", "nope", ColorRed))
+	assert.Error(t, err)
+
+	cv := &CollectionView{
+		ID: "cv",
+		Format: &FormatTable{
+			TableProperties: []*TableProperty{
+				{Property: "title", Visible: true, Width: 200},
+				{Property: "st", Visible: true},
+				{Property: "old", Visible: false},
+			},
+		},
+	}
+	colIDs := func() []string {
+		var res []string
+		for _, p := range cv.Format.TableProperties {
+			res = append(res, p.Property)
+		}
+		return res
+	}
+	op := cv.MoveColumnOp("title", "st")
+	assert.Equal(t, TableCollectionView, op.Table)
+	applyToRecord(t, cv, op)
+	assert.Equal(t, []string{"st", "title", "old"}, colIDs())
+	assert.Equal(t, 200, cv.Format.TableProperties[1].Width)
+
+	applyToRecord(t, cv, cv.MoveColumnOp(id, ""))
+	assert.Equal(t, []string{id, "st", "title", "old"}, colIDs())
+	applyToRecord(t, cv, cv.SetColumnVisibleOp("st", false))
+	applyToRecord(t, cv, cv.SetColumnVisibleOp("old", true))
+	assert.False(t, cv.Format.TableProperties[1].Visible)
+	assert.True(t, cv.Format.TableProperties[3].Visible)
+
+	// boards have their own list of properties
+	board := &CollectionView{
+		ID:   "board",
+		Type: CollectionViewTypeBoard,
+		Format: &FormatTable{
+			TableProperties: []*TableProperty{{Property: "title", Visible: true}},
+			BoardProperties: []*TableProperty{{Property: "title", Visible: true}, {Property: "st", Visible: true}},
+		},
+	}
+	op = board.SetColumnVisibleOp("st", false)
+	assert.Equal(t, []string{"format", "board_properties"}, op.Path)
+	applyToRecord(t, board, op)
+	applyToRecord(t, board, board.MoveColumnOp("st", ""))
+	assert.Equal(t, 1, len(board.Format.TableProperties))
+	assert.Equal(t, "st", board.Format.BoardProperties[0].Property)
+	assert.False(t, board.Format.BoardProperties[0].Visible)
+}
+
+func TestNewColumnIDs(t *testing.T) {
+	c := &Collection{ID: "coll", Schema: map[string]*ColumnSchema{}}
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id, _ := c.AddColumnOp("col", ColumnTypeText)
+		assert.False(t, seen[id])
+		seen[id] = true
+	}
+}
@@ -1,13 +1,25 @@
 package notionapi
 
-import "time"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Command Types
 const (
 	CommandSet        = "set"
 	CommandUpdate     = "update"
 	CommandListAfter  = "listAfter"
+	CommandListBefore = "listBefore"
 	CommandListRemove = "listRemove"
+	// adds an object with "id" to a list e.g. an option to
+	// ColumnSchema.Options
+	CommandKeyedObjectListAfter = "keyedObjectListAfter"
+	// changes fields of an object with "id" in a list
+	CommandKeyedObjectListUpdate = "keyedObjectListUpdate"
 )
 
 type submitTransactionRequest struct {
@@ -23,14 +35,20 @@ type Operation struct {
 	Args    interface{} `json:"args"`
 }
 
+// SubmitTransaction executes a raw API call /api/v3/submitTransaction
 func (c *Client) SubmitTransaction(ops []*Operation) error {
+	return c.SubmitTransactionCtx(context.Background(), ops)
+}
+
+// SubmitTransactionCtx is like SubmitTransaction but takes a context
+func (c *Client) SubmitTransactionCtx(ctx context.Context, ops []*Operation) error {
 	req := &submitTransactionRequest{
 		Operations: ops,
 	}
 	// response is empty, as far as I can tell
 	var rsp map[string]interface{}
 	apiURL := "/api/v3/submitTransaction"
-	err := c.doNotionAPI(apiURL, req, &rsp, nil)
+	err := c.doNotionAPI(ctx, apiURL, req, &rsp, nil)
 	return err
 }
 
@@ -112,19 +130,6 @@ func (b *Block) ListAfterFileIDsOp(fileID string) *Operation {
 }
 
 /*
-func buildLastEditedTimeOp(id string) *Operation {
-	args := map[string]interface{}{
-		"last_edited_time": notionTimeNow(),
-	}
-	return &Operation{
-		ID:      id,
-		Table:   "block",
-		Path:    []string{},
-		Command: "update",
-		Args:    args,
-	}
-}
-
 // TODO: add constants for known languages
 func buildUpdateCodeBlockLang(id string, lang string) *Operation {
 	args := map[string]interface{}{
@@ -139,3 +144,187 @@ func buildUpdateCodeBlockLang(id string, lang string) *Operation {
 	}
 }
 */
+
+// toJSONValue converts v to a generic json value i.e. map[string]interface{},
+// []interface{}, string, json.Number etc.
+func toJSONValue(v interface{}) (interface{}, error) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(d))
+	// preserve exact value of large numbers like created_time
+	dec.UseNumber()
+	var res interface{}
+	err = dec.Decode(&res)
+	return res, err
+}
+
+func jsonGetList(v interface{}) []interface{} {
+	a, _ := v.([]interface{})
+	return a
+}
+
+func listIndexOf(a []interface{}, id string) int {
+	for i, v := range a {
+		if s, ok := v.(string); ok && s == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyedObjectIndex returns index of an object with a given "id" in a list
+func keyedObjectIndex(a []interface{}, id string) int {
+	for i, v := range a {
+		if o, ok := v.(map[string]interface{}); ok && o["id"] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func listInsert(a []interface{}, idx int, v interface{}) []interface{} {
+	a = append(a, nil)
+	copy(a[idx+1:], a[idx:])
+	a[idx] = v
+	return a
+}
+
+// ApplyOperation applies op to record, which is json value of a record
+// in op.Table, as decoded from Notion API responses.
+// record can be nil if op creates a new record.
+// Returns the updated record.
+func ApplyOperation(record map[string]interface{}, op *Operation) (map[string]interface{}, error) {
+	args, err := toJSONValue(op.Args)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.Path) == 0 && op.Command == CommandSet {
+		m, ok := args.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s on a record requires object args, got %T", op.Command, args)
+		}
+		return m, nil
+	}
+	if record == nil {
+		record = map[string]interface{}{}
+	}
+
+	// find the parent of the value at op.Path, creating objects as needed
+	parent := record
+	key := ""
+	if len(op.Path) > 0 {
+		for _, k := range op.Path[:len(op.Path)-1] {
+			v, ok := parent[k].(map[string]interface{})
+			if !ok {
+				v = map[string]interface{}{}
+				parent[k] = v
+			}
+			parent = v
+		}
+		key = op.Path[len(op.Path)-1]
+	}
+	getValue := func() interface{} {
+		if key == "" {
+			return record
+		}
+		return parent[key]
+	}
+
+	switch op.Command {
+	case CommandSet:
+		parent[key] = args
+
+	case CommandUpdate:
+		m, ok := args.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s requires object args, got %T", op.Command, args)
+		}
+		v, ok := getValue().(map[string]interface{})
+		if !ok {
+			v = map[string]interface{}{}
+			parent[key] = v
+		}
+		for k, val := range m {
+			if val == nil {
+				delete(v, k)
+			} else {
+				v[k] = val
+			}
+		}
+
+	case CommandListAfter, CommandListBefore, CommandListRemove:
+		if key == "" {
+			return nil, fmt.Errorf("%s requires a non-empty path", op.Command)
+		}
+		m, _ := args.(map[string]interface{})
+		id, ok := m["id"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s requires 'id' in args", op.Command)
+		}
+		a := jsonGetList(parent[key])
+		if idx := listIndexOf(a, id); idx >= 0 {
+			a = append(a[:idx], a[idx+1:]...)
+		}
+		switch op.Command {
+		case CommandListAfter:
+			idx := len(a)
+			if after, ok := m["after"].(string); ok {
+				if i := listIndexOf(a, after); i >= 0 {
+					idx = i + 1
+				}
+			}
+			a = listInsert(a, idx, id)
+		case CommandListBefore:
+			idx := 0
+			if before, ok := m["before"].(string); ok {
+				if i := listIndexOf(a, before); i >= 0 {
+					idx = i
+				}
+			}
+			a = listInsert(a, idx, id)
+		}
+		if a == nil {
+			a = []interface{}{}
+		}
+		parent[key] = a
+
+	case CommandKeyedObjectListAfter, CommandKeyedObjectListUpdate:
+		if key == "" {
+			return nil, fmt.Errorf("%s requires a non-empty path", op.Command)
+		}
+		m, _ := args.(map[string]interface{})
+		v, _ := m["value"].(map[string]interface{})
+		id, ok := v["id"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s requires 'value' with 'id' in args", op.Command)
+		}
+		a := jsonGetList(parent[key])
+		idx := keyedObjectIndex(a, id)
+		if op.Command == CommandKeyedObjectListUpdate {
+			if idx < 0 {
+				return nil, fmt.Errorf("%s: no object with id '%s'", op.Command, id)
+			}
+			o := a[idx].(map[string]interface{})
+			for k, val := range v {
+				o[k] = val
+			}
+			break
+		}
+		if idx >= 0 {
+			a = append(a[:idx], a[idx+1:]...)
+		}
+		idx = len(a)
+		if after, ok := m["after"].(string); ok {
+			if i := keyedObjectIndex(a, after); i >= 0 {
+				idx = i + 1
+			}
+		}
+		parent[key] = listInsert(a, idx, v)
+
+	default:
+		return nil, fmt.Errorf("unsupported command '%s'", op.Command)
+	}
+	return record, nil
+}
@@ -0,0 +1,282 @@
+package tocsv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kjk/notionapi"
+)
+
+// Converter writes rows of a TableView as CSV or JSON Lines,
+// with one column for each visible column of the view
+type Converter struct {
+	TableView *notionapi.TableView
+
+	// if true, persons are written as user ids and relations as page ids
+	// instead of names of users and titles of pages
+	RawIDs bool
+
+	// if true, numbers are written as is, not formatted according to
+	// ColumnSchema.NumberFormat
+	RawNumbers bool
+
+	// used to get titles of related pages that are not in TableView.Page.
+	// If nil, ids of those pages are written
+	RelationResolver *notionapi.RelationResolver
+}
+
+// NewConverter returns a converter for a table view
+func NewConverter(tv *notionapi.TableView) *Converter {
+	return &Converter{
+		TableView: tv,
+	}
+}
+
+// Header returns names of columns
+func (c *Converter) Header() []string {
+	var res []string
+	for _, col := range c.TableView.Columns {
+		name := col.Name()
+		if name == "" {
+			name = col.ID()
+		}
+		res = append(res, name)
+	}
+	return res
+}
+
+func (c *Converter) userName(userID string) string {
+	if c.RawIDs || c.TableView.Page == nil {
+		return userID
+	}
+	return notionapi.GetUserNameByID(c.TableView.Page, userID)
+}
+
+// recordKeys returns names of columns used as keys in Record. A name
+// used by more than one column gets a suffix " (2)", " (3)" etc. so
+// that values of columns don't overwrite each other
+func (c *Converter) recordKeys() []string {
+	header := c.Header()
+	taken := map[string]bool{}
+	for _, name := range header {
+		taken[name] = true
+	}
+	used := map[string]bool{}
+	var res []string
+	for _, name := range header {
+		key := name
+		for n := 2; used[key]; n++ {
+			key = fmt.Sprintf("%s (%d)", name, n)
+			if taken[key] {
+				key = name
+			}
+		}
+		used[key] = true
+		res = append(res, key)
+	}
+	return res
+}
+
+func (c *Converter) relatedTitles(ctx context.Context, row *notionapi.TableRow, colID string, ids []string) []string {
+	if c.RawIDs {
+		return ids
+	}
+	page := c.TableView.Page
+	var missing bool
+	var res []string
+	for _, id := range ids {
+		var b *notionapi.Block
+		if nid := notionapi.NewNotionID(id); nid != nil && page != nil {
+			b = page.BlockByID(nid)
+		}
+		if b == nil {
+			missing = true
+			res = append(res, id)
+			continue
+		}
+		res = append(res, notionapi.TextSpansToString(b.GetProperty("title")))
+	}
+	if !missing || c.RelationResolver == nil {
+		return res
+	}
+	related, err := c.RelationResolver.Related(ctx, row, colID)
+	if err != nil {
+		return res
+	}
+	res = nil
+	for _, r := range related {
+		res = append(res, r.Value("title").Text)
+	}
+	return res
+}
+
+// value returns a value of a cell as a string or, for cells that can
+// have many values, as a []string
+func (c *Converter) value(ctx context.Context, row *notionapi.TableRow, col *notionapi.ColumnInfo) interface{} {
+	v := row.Value(col.ID())
+	switch v.Type {
+	case notionapi.ColumnTypeNumber:
+		if v.IsEmpty() {
+			return ""
+		}
+		if c.RawNumbers {
+			return v.Text
+		}
+		numFmt := ""
+		if col.Schema != nil {
+			numFmt = col.Schema.NumberFormat
+		}
+		return notionapi.FormatNumber(v.Number, numFmt)
+	case notionapi.ColumnTypeCheckbox:
+		if v.Checkbox {
+			return "Yes"
+		}
+		return "No"
+	case notionapi.ColumnTypeSelect, notionapi.ColumnTypeMultiSelect:
+		res := []string{}
+		for _, o := range v.Options {
+			res = append(res, o.Value)
+		}
+		return res
+	case notionapi.ColumnTypeDate:
+		if v.Date == nil {
+			return ""
+		}
+		return notionapi.FormatDate(v.Date)
+	case notionapi.ColumnTypeCreatedTime, notionapi.ColumnTypeLastEditedTime:
+		if v.Time.IsZero() {
+			return ""
+		}
+		return v.Time.UTC().Format("January 2, 2006 3:04 PM")
+	case notionapi.ColumnTypePerson, notionapi.ColumnTypeCreatedBy, notionapi.ColumnTypeLastEditedBy:
+		res := []string{}
+		for _, id := range v.UserIDs {
+			res = append(res, c.userName(id))
+		}
+		return res
+	case notionapi.ColumnTypeRelation:
+		return append([]string{}, c.relatedTitles(ctx, row, col.ID(), v.BlockIDs)...)
+	case notionapi.ColumnTypeFile:
+		return append([]string{}, v.FileURLs...)
+	case notionapi.ColumnTypeFormula:
+		if v.Formula != nil {
+			return v.Formula.String()
+		}
+	}
+	return v.Text
+}
+
+// Values returns formatted values of cells in a row
+func (c *Converter) Values(row int) []string {
+	return c.ValuesCtx(context.Background(), row)
+}
+
+// ValuesCtx is like Values but takes a context, used to resolve relations
+func (c *Converter) ValuesCtx(ctx context.Context, row int) []string {
+	tr := c.TableView.Rows[row]
+	var res []string
+	for _, col := range c.TableView.Columns {
+		switch v := c.value(ctx, tr, col).(type) {
+		case []string:
+			res = append(res, strings.Join(v, ", "))
+		case string:
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Record returns values of cells in a row keyed by column name.
+// If several columns have the same name, the second one is "${name} (2)"
+// etc. Numbers and checkboxes have json types, cells that can have many
+// values (multi select, person, relation etc.) are arrays
+func (c *Converter) Record(row int) map[string]interface{} {
+	return c.RecordCtx(context.Background(), row)
+}
+
+// RecordCtx is like Record but takes a context, used to resolve relations
+func (c *Converter) RecordCtx(ctx context.Context, row int) map[string]interface{} {
+	tr := c.TableView.Rows[row]
+	keys := c.recordKeys()
+	res := map[string]interface{}{}
+	for i, col := range c.TableView.Columns {
+		val := c.value(ctx, tr, col)
+		v := tr.Value(col.ID())
+		switch v.Type {
+		case notionapi.ColumnTypeNumber:
+			if c.RawNumbers && !v.IsEmpty() {
+				val = v.Number
+			}
+		case notionapi.ColumnTypeCheckbox:
+			val = v.Checkbox
+		case notionapi.ColumnTypeSelect:
+			if a := val.([]string); len(a) > 0 {
+				val = a[0]
+			} else {
+				val = ""
+			}
+		}
+		res[keys[i]] = val
+	}
+	return res
+}
+
+// WriteCSV writes the header and all rows as CSV
+func (c *Converter) WriteCSV(w io.Writer) error {
+	return c.WriteCSVCtx(context.Background(), w)
+}
+
+// WriteCSVCtx is like WriteCSV but takes a context, used to resolve
+// relations
+func (c *Converter) WriteCSVCtx(ctx context.Context, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(c.Header()); err != nil {
+		return err
+	}
+	for row := range c.TableView.Rows {
+		if err := cw.Write(c.ValuesCtx(ctx, row)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONL writes each row as a json object in a separate line
+func (c *Converter) WriteJSONL(w io.Writer) error {
+	return c.WriteJSONLCtx(context.Background(), w)
+}
+
+// WriteJSONLCtx is like WriteJSONL but takes a context, used to resolve
+// relations
+func (c *Converter) WriteJSONLCtx(ctx context.Context, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for row := range c.TableView.Rows {
+		if err := enc.Encode(c.RecordCtx(ctx, row)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ToCSV returns rows of a table view as CSV
+func ToCSV(tv *notionapi.TableView) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewConverter(tv).WriteCSV(&buf)
+	return buf.Bytes(), err
+}
+
+// ToJSONL returns rows of a table view as JSON Lines
+func ToJSONL(tv *notionapi.TableView) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewConverter(tv).WriteJSONL(&buf)
+	return buf.Bytes(), err
+}
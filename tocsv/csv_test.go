@@ -0,0 +1,99 @@
+package tocsv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kjk/common/assert"
+	"github.com/kjk/notionapi"
+)
+
+func newTestTableView(t *testing.T) *notionapi.TableView {
+	schema := map[string]*notionapi.ColumnSchema{
+		"title": {Name: "Name", Type: notionapi.ColumnTypeTitle},
+		"num":   {Name: "Price", Type: notionapi.ColumnTypeNumber, NumberFormat: "dollar"},
+		"chk":   {Name: "Done", Type: notionapi.ColumnTypeCheckbox},
+		"tags":  {Name: "Tags", Type: notionapi.ColumnTypeMultiSelect},
+		"due":   {Name: "Due", Type: notionapi.ColumnTypeDate},
+		"who":   {Name: "Owner", Type: notionapi.ColumnTypePerson},
+		"rel":   {Name: "Related", Type: notionapi.ColumnTypeRelation},
+	}
+	tv := &notionapi.TableView{
+		Collection: &notionapi.Collection{Schema: schema},
+	}
+	for _, id := range []string{"title", "num", "chk", "tags", "due", "who", "rel"} {
+		tv.Columns = append(tv.Columns, &notionapi.ColumnInfo{
+			TableView: tv,
+			Property:  &notionapi.TableProperty{Property: id, Visible: true},
+			Schema:    schema[id],
+		})
+	}
+	rows := []string{
+		`{"title": [["Book, \"Go\""]], "num": [["1234.5"]], "chk": [["Yes"]], "tags": [["a,b"]],
+		  "due": [["‣", [["d", {"type": "date", "start_date": "2021-05-03"}]]]],
+		  "who": [["‣", [["u", "user-1"]]]],
+		  "rel": [["‣", [["p", "6682351e-44bb-4f9c-a0e1-49b703265bdb"]]]]}`,
+		`{"title": [["Pen"]]}`,
+	}
+	for i, js := range rows {
+		var props map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(js), &props))
+		tv.Rows = append(tv.Rows, &notionapi.TableRow{
+			TableView: tv,
+			Page:      &notionapi.Block{ID: string(rune('a' + i)), Properties: props},
+		})
+	}
+	return tv
+}
+
+func TestToCSV(t *testing.T) {
+	tv := newTestTableView(t)
+	d, err := ToCSV(tv)
+	assert.NoError(t, err)
+	exp := `Name,Price,Done,Tags,Due,Owner,Related
+"Book, ""Go""","$1,234.50",Yes,"a, b","May 03, 2021",user-1,6682351e-44bb-4f9c-a0e1-49b703265bdb
+Pen,,No,,,,
+`
+	assert.Equal(t, exp, string(d))
+
+	c := NewConverter(tv)
+	c.RawNumbers = true
+	assert.Equal(t, "1234.5", c.Values(0)[1])
+}
+
+func TestToJSONL(t *testing.T) {
+	tv := newTestTableView(t)
+	c := NewConverter(tv)
+	c.RawNumbers = true
+	var buf strings.Builder
+	assert.NoError(t, c.WriteJSONL(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+
+	var rec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, `Book, "Go"`, rec["Name"])
+	assert.Equal(t, 1234.5, rec["Price"])
+	assert.Equal(t, true, rec["Done"])
+	assert.Equal(t, []interface{}{"a", "b"}, rec["Tags"])
+	assert.Equal(t, []interface{}{"user-1"}, rec["Owner"])
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &rec))
+	assert.Equal(t, "", rec["Price"])
+	assert.Equal(t, false, rec["Done"])
+	assert.Equal(t, []interface{}{}, rec["Tags"])
+}
+
+func TestRecordDuplicateNames(t *testing.T) {
+	tv := newTestTableView(t)
+	tv.Columns[1].Schema = &notionapi.ColumnSchema{Name: "Name", Type: notionapi.ColumnTypeNumber}
+	tv.Columns[2].Schema = &notionapi.ColumnSchema{Name: "Name", Type: notionapi.ColumnTypeCheckbox}
+	tv.Columns[3].Schema = &notionapi.ColumnSchema{Name: "Name (2)", Type: notionapi.ColumnTypeMultiSelect}
+	rec := NewConverter(tv).Record(0)
+	assert.Equal(t, 7, len(rec))
+	assert.Equal(t, `Book, "Go"`, rec["Name"])
+	assert.Equal(t, "1234.5", rec["Name (3)"])
+	assert.Equal(t, true, rec["Name (4)"])
+	assert.Equal(t, []string{"a", "b"}, rec["Name (2)"])
+}
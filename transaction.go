@@ -0,0 +1,416 @@
+package notionapi
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMaxTransactionOps is the maximum number of operations sent
+// in a single /api/v3/submitTransaction request if Transaction.MaxOps
+// is not set
+const DefaultMaxTransactionOps = 256
+
+var validCommands = map[string]bool{
+	CommandSet:                   true,
+	CommandUpdate:                true,
+	CommandListAfter:             true,
+	CommandListBefore:            true,
+	CommandListRemove:            true,
+	CommandKeyedObjectListAfter:  true,
+	CommandKeyedObjectListUpdate: true,
+}
+
+// ValidateOperation checks that op has a known command, a path without
+// empty elements and args that make sense for the command
+func ValidateOperation(op *Operation) error {
+	if op.ID == "" {
+		return fmt.Errorf("operation '%s' on table '%s' has no id", op.Command, op.Table)
+	}
+	if op.Table == "" {
+		return fmt.Errorf("operation '%s' on '%s' has no table", op.Command, op.ID)
+	}
+	if !validCommands[op.Command] {
+		return fmt.Errorf("operation on %s '%s' has unsupported command '%s'", op.Table, op.ID, op.Command)
+	}
+	for _, k := range op.Path {
+		if k == "" {
+			return fmt.Errorf("operation '%s' on %s '%s' has empty element in path %v", op.Command, op.Table, op.ID, op.Path)
+		}
+	}
+	args, err := toJSONValue(op.Args)
+	if err != nil {
+		return err
+	}
+	m, isObject := args.(map[string]interface{})
+	switch op.Command {
+	case CommandSet:
+		if len(op.Path) > 0 {
+			return nil
+		}
+		if !isObject {
+			return fmt.Errorf("%s on %s '%s' requires object args, got %T", op.Command, op.Table, op.ID, args)
+		}
+		if id, ok := m["id"]; ok && id != op.ID {
+			return fmt.Errorf("%s on %s '%s' has args with different id '%v'", op.Command, op.Table, op.ID, id)
+		}
+	case CommandUpdate:
+		if !isObject {
+			return fmt.Errorf("%s on %s '%s' requires object args, got %T", op.Command, op.Table, op.ID, args)
+		}
+	case CommandListAfter, CommandListBefore, CommandListRemove:
+		if len(op.Path) == 0 {
+			return fmt.Errorf("%s on %s '%s' requires a non-empty path", op.Command, op.Table, op.ID)
+		}
+		if _, ok := m["id"].(string); !ok {
+			return fmt.Errorf("%s on %s '%s' requires 'id' in args", op.Command, op.Table, op.ID)
+		}
+	case CommandKeyedObjectListAfter, CommandKeyedObjectListUpdate:
+		if len(op.Path) == 0 {
+			return fmt.Errorf("%s on %s '%s' requires a non-empty path", op.Command, op.Table, op.ID)
+		}
+		v, _ := m["value"].(map[string]interface{})
+		if _, ok := v["id"].(string); !ok {
+			return fmt.Errorf("%s on %s '%s' requires 'value' with 'id' in args", op.Command, op.Table, op.ID)
+		}
+	}
+	return nil
+}
+
+// Transaction collects operations to be submitted together with
+// Client.CommitTransaction.
+//
+// Like Notion, it updates last_edited_time and last_edited_by of
+// every edited block and of the page that contains it.
+// Large transactions are split into several requests of at most MaxOps
+// operations each. Requests are not atomic with respect to each other.
+type Transaction struct {
+	// id of the user making changes, for last_edited_by. Can be ""
+	UserID string
+	// maximum number of operations in a single request
+	MaxOps int
+	// used to find pages of edited blocks. Can be nil
+	Page *Page
+
+	ops []*Operation
+	// blocks created in this transaction, with only id, type and parent
+	created map[string]*Block
+	// last edited time, the same in ApplyTo and CommitTransaction
+	now int64
+}
+
+// NewTransaction returns an empty transaction. page can be nil
+func NewTransaction(userID string, page *Page) *Transaction {
+	return &Transaction{
+		UserID: userID,
+		Page:   page,
+	}
+}
+
+// Add validates and adds operations to the transaction. If any of the
+// operations is invalid, none are added
+func (t *Transaction) Add(ops ...*Operation) error {
+	for _, op := range ops {
+		if err := ValidateOperation(op); err != nil {
+			return err
+		}
+	}
+	for _, op := range ops {
+		t.ops = append(t.ops, op)
+		if op.Table != TableBlock || op.Command != CommandSet || len(op.Path) > 0 {
+			continue
+		}
+		args, _ := toJSONValue(op.Args)
+		m := args.(map[string]interface{})
+		b := &Block{ID: op.ID}
+		b.ParentID, _ = m["parent_id"].(string)
+		b.ParentTable, _ = m["parent_table"].(string)
+		b.Type, _ = m["type"].(string)
+		if t.created == nil {
+			t.created = map[string]*Block{}
+		}
+		t.created[ToDashID(op.ID)] = b
+	}
+	return nil
+}
+
+// Len returns number of operations added to the transaction
+func (t *Transaction) Len() int {
+	return len(t.ops)
+}
+
+// block returns a block with a given id from the page or created in
+// this transaction. nil if we don't know it
+func (t *Transaction) block(id string) *Block {
+	id = ToDashID(id)
+	if b := t.created[id]; b != nil {
+		return b
+	}
+	if t.Page == nil {
+		return nil
+	}
+	return t.Page.idToBlock[id]
+}
+
+// pageOf returns id of a page that contains a block, "" if the block is
+// a page or we don't know its page
+func (t *Transaction) pageOf(id string) string {
+	b := t.block(id)
+	if b == nil || isPageBlock(b) {
+		return ""
+	}
+	// guard against cycles
+	for i := 0; i < 256 && b != nil && b.ParentTable == TableBlock; i++ {
+		parentID := b.ParentID
+		b = t.block(parentID)
+		if b != nil && isPageBlock(b) {
+			return b.ID
+		}
+	}
+	return ""
+}
+
+// editedBlocks returns ids of blocks whose last edited time changes
+// because of op
+func (t *Transaction) editedBlocks(op *Operation) []string {
+	if op.Table != TableBlock {
+		return nil
+	}
+	id := ToDashID(op.ID)
+	res := []string{id}
+	if pageID := t.pageOf(id); pageID != "" && pageID != id {
+		res = append(res, pageID)
+	}
+	return res
+}
+
+// timestamp returns last edited time of the transaction, set the first
+// time it's needed
+func (t *Transaction) timestamp() int64 {
+	if t.now == 0 {
+		t.now = Now()
+	}
+	return t.now
+}
+
+func (t *Transaction) lastEditedOps(ids []string, now int64) []*Operation {
+	var res []*Operation
+	for _, id := range ids {
+		b := &Block{ID: id}
+		res = append(res, b.UpdateOp(&Block{LastEditedTime: now, LastEditedBy: t.UserID}))
+	}
+	return res
+}
+
+// Batches returns operations split into requests, each followed by
+// operations updating last edited time of blocks edited in that request
+func (t *Transaction) Batches() [][]*Operation {
+	maxOps := t.MaxOps
+	if maxOps <= 0 {
+		maxOps = DefaultMaxTransactionOps
+	}
+	return t.batches(maxOps)
+}
+
+// batches splits operations into batches of at most maxOps operations.
+// If maxOps is 0, there's only one batch
+func (t *Transaction) batches(maxOps int) [][]*Operation {
+	now := t.timestamp()
+	var res [][]*Operation
+	var batch []*Operation
+	var edited []string
+	flush := func() {
+		all := append(batch, t.lastEditedOps(edited, now)...)
+		// a single op can edit more blocks than fit in a batch
+		for maxOps > 0 && len(all) > maxOps {
+			res = append(res, all[:maxOps])
+			all = all[maxOps:]
+		}
+		res = append(res, all)
+	}
+	seen := map[string]bool{}
+	for _, op := range t.ops {
+		var ids []string
+		for _, id := range t.editedBlocks(op) {
+			if !seen[id] {
+				ids = append(ids, id)
+			}
+		}
+		if maxOps > 0 && len(batch) > 0 && len(batch)+1+len(edited)+len(ids) > maxOps {
+			flush()
+			batch, edited = nil, nil
+			seen = map[string]bool{}
+			ids = t.editedBlocks(op)
+		}
+		batch = append(batch, op)
+		for _, id := range ids {
+			seen[id] = true
+			edited = append(edited, id)
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	return res
+}
+
+// Ops returns all operations of the transaction, including the ones
+// updating last edited time, as a single list
+func (t *Transaction) Ops() []*Operation {
+	var res []*Operation
+	for _, batch := range t.batches(0) {
+		res = append(res, batch...)
+	}
+	return res
+}
+
+// CommitTransaction submits operations of the transaction, in as many
+// requests as needed
+func (c *Client) CommitTransaction(t *Transaction) error {
+	return c.CommitTransactionCtx(context.Background(), t)
+}
+
+// CommitTransactionCtx is like CommitTransaction but takes a context
+func (c *Client) CommitTransactionCtx(ctx context.Context, t *Transaction) error {
+	for _, ops := range t.Batches() {
+		if err := c.SubmitTransactionCtx(ctx, ops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyTo applies operations of the transaction to blocks, collections
+// and collection views of a page, so that it reflects the changes
+// without downloading it again. Operations on other records are ignored
+func (t *Transaction) ApplyTo(p *Page) error {
+	return p.applyOps(t.Ops())
+}
+
+// rawRecord returns a copy of json value of a record in a page, nil if
+// the page doesn't have it
+func (p *Page) rawRecord(table string, id string) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	switch table {
+	case TableBlock:
+		if b := p.idToBlock[id]; b != nil {
+			raw = b.RawJSON
+		}
+	case TableCollection:
+		if c := p.idToCollection[id]; c != nil {
+			raw = c.RawJSON
+		}
+	case TableCollectionView:
+		if cv := p.idToCollectionView[id]; cv != nil {
+			raw = cv.RawJSON
+		}
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	v, err := toJSONValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// setRecord replaces a record in the page with its new json value.
+// Existing Block etc. are updated in place so that pointers to them
+// stay valid
+func (p *Page) setRecord(table string, id string, value map[string]interface{}) error {
+	d, err := jsonit.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r := &Record{Value: d}
+	if err = parseRecord(table, r); err != nil {
+		return err
+	}
+	switch table {
+	case TableBlock:
+		b := r.Block
+		if !b.Alive {
+			delete(p.idToBlock, id)
+			p.blocksToSkip[id] = struct{}{}
+			return nil
+		}
+		if old := p.idToBlock[id]; old != nil {
+			b.TableViews = old.TableViews
+			*old = *b
+			b = old
+		}
+		b.Page = p
+		p.idToBlock[id] = b
+		delete(p.blocksToSkip, id)
+	case TableCollection:
+		if old := p.idToCollection[id]; old != nil {
+			*old = *r.Collection
+		} else {
+			p.idToCollection[id] = r.Collection
+		}
+	case TableCollectionView:
+		if old := p.idToCollectionView[id]; old != nil {
+			*old = *r.CollectionView
+		} else {
+			p.idToCollectionView[id] = r.CollectionView
+		}
+	}
+	return nil
+}
+
+// applyOps applies operations to records of the page
+func (p *Page) applyOps(ops []*Operation) error {
+	type key struct {
+		table string
+		id    string
+	}
+	var changed []key
+	records := map[key]map[string]interface{}{}
+	for _, op := range ops {
+		switch op.Table {
+		case TableBlock, TableCollection, TableCollectionView:
+		default:
+			continue
+		}
+		k := key{op.Table, ToDashID(op.ID)}
+		rec, ok := records[k]
+		if !ok {
+			var err error
+			if rec, err = p.rawRecord(k.table, k.id); err != nil {
+				return err
+			}
+			// only ops that create a record apply to records not in the page
+			if rec == nil && (op.Command != CommandSet || len(op.Path) > 0) {
+				continue
+			}
+			changed = append(changed, k)
+		}
+		rec, err := ApplyOperation(rec, op)
+		if err != nil {
+			return fmt.Errorf("operation on %s '%s' failed: %w", op.Table, op.ID, err)
+		}
+		rec["id"] = k.id
+		records[k] = rec
+	}
+	for _, k := range changed {
+		if err := p.setRecord(k.table, k.id, records[k]); err != nil {
+			return err
+		}
+	}
+	// content and parents of changed blocks must be resolved after all
+	// blocks are updated, because they can refer to each other
+	for _, k := range changed {
+		b := p.idToBlock[k.id]
+		if k.table != TableBlock || b == nil {
+			continue
+		}
+		if err := resolveBlock(p, b); err != nil {
+			return err
+		}
+		b.Parent = nil
+		if b.ParentTable == TableBlock {
+			b.Parent = p.idToBlock[b.ParentID]
+		}
+	}
+	return nil
+}
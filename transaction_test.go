@@ -0,0 +1,137 @@
+package notionapi
+
+import (
+	"testing"
+
+	"github.com/kjk/common/assert"
+)
+
+// newTestPage returns a page with blocks given as json values, the
+// first one is the root
+func newTestPage(t *testing.T, blocks ...map[string]interface{}) *Page {
+	p := &Page{
+		idToBlock:          map[string]*Block{},
+		idToCollection:     map[string]*Collection{},
+		idToCollectionView: map[string]*CollectionView{},
+		blocksToSkip:       map[string]struct{}{},
+	}
+	for i, v := range blocks {
+		d, err := jsonit.Marshal(v)
+		assert.NoError(t, err)
+		r := &Record{Value: d}
+		assert.NoError(t, parseRecord(TableBlock, r))
+		if i == 0 {
+			p.ID = r.ID
+		}
+		r.Block.Page = p
+		p.idToBlock[r.ID] = r.Block
+	}
+	assert.NoError(t, p.resolveBlocks())
+	return p
+}
+
+func TestTransaction(t *testing.T) {
+	pageID := "11111111-1111-1111-1111-111111111111"
+	textID := "22222222-2222-2222-2222-222222222222"
+	p := newTestPage(t,
+		map[string]interface{}{"id": pageID, "type": "page", "alive": true, "content": []string{textID}},
+		map[string]interface{}{"id": textID, "type": "text", "alive": true, "parent_id": pageID, "parent_table": "block",
+			"properties": map[string]interface{}{"title": [][]string{{"hello"}}}},
+	)
+	text := p.idToBlock[textID]
+
+	tx := NewTransaction("user-1", p)
+	err := tx.Add(text.SetTitleOp("bye"))
+	assert.NoError(t, err)
+	newID := "33333333-3333-3333-3333-333333333333"
+	err = tx.Add(
+		&Operation{ID: newID, Table: TableBlock, Path: []string{}, Command: CommandSet, Args: map[string]interface{}{
+			"id": newID, "type": "text", "alive": true, "parent_id": pageID, "parent_table": "block",
+			"properties": map[string]interface{}{"title": [][]string{{"new"}}},
+		}},
+		p.Root().ListAfterContentOp(newID, textID),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tx.Len())
+
+	// invalid operations are rejected and not added
+	bad := []*Operation{
+		{ID: textID, Table: TableBlock, Path: []string{"properties", ""}, Command: CommandSet, Args: "x"},
+		{ID: textID, Table: TableBlock, Path: []string{"properties"}, Command: "merge", Args: map[string]interface{}{}},
+		{ID: textID, Table: TableBlock, Path: []string{"properties"}, Command: CommandUpdate, Args: "x"},
+		{ID: textID, Table: TableBlock, Path: []string{}, Command: CommandListAfter, Args: map[string]interface{}{"id": "x"}},
+		{ID: textID, Table: TableBlock, Path: []string{"content"}, Command: CommandListRemove, Args: map[string]interface{}{}},
+		{ID: "", Table: TableBlock, Path: []string{}, Command: CommandUpdate, Args: map[string]interface{}{}},
+		{ID: textID, Table: TableBlock, Path: []string{}, Command: CommandSet, Args: map[string]interface{}{"id": "other"}},
+	}
+	for _, op := range bad {
+		assert.Error(t, tx.Add(text.SetTitleOp("ok"), op))
+	}
+	assert.Equal(t, 3, tx.Len())
+
+	// edited blocks and their page get last edited time updated once
+	ops := tx.Ops()
+	assert.Equal(t, 6, len(ops))
+	var edited []string
+	for _, op := range ops[3:] {
+		assert.Equal(t, CommandUpdate, op.Command)
+		assert.Equal(t, "user-1", op.Args.(map[string]interface{})["last_edited_by"])
+		edited = append(edited, op.ID)
+	}
+	assert.Equal(t, []string{textID, pageID, newID}, edited)
+
+	tx.MaxOps = 3
+	batches := tx.Batches()
+	assert.Equal(t, 3, len(batches))
+	n := 0
+	for _, b := range batches {
+		assert.True(t, len(b) <= 3)
+		n += len(b)
+	}
+	// every batch updates the page again
+	assert.Equal(t, 3+2+2+1, n)
+
+	// an op editing more blocks than fit in a batch is sent alone,
+	// followed by updates of last edited time
+	for _, maxOps := range []int{1, 2} {
+		tx.MaxOps = maxOps
+		n = 0
+		for _, b := range tx.Batches() {
+			assert.True(t, len(b) <= maxOps)
+			n += len(b)
+		}
+		assert.True(t, n >= 6)
+	}
+
+	// last edited time is the same in every batch and in Ops
+	edTime := ops[3].Args.(map[string]interface{})["last_edited_time"]
+	for _, b := range tx.Batches() {
+		for _, op := range b {
+			if op.Command == CommandUpdate {
+				assert.Equal(t, edTime, op.Args.(map[string]interface{})["last_edited_time"])
+			}
+		}
+	}
+
+	assert.NoError(t, tx.ApplyTo(p))
+	assert.Equal(t, "bye", TextSpansToString(text.InlineContent))
+	root := p.Root()
+	assert.Equal(t, []string{textID, newID}, root.ContentIDs)
+	assert.Equal(t, 2, len(root.Content))
+	assert.Equal(t, text, root.Content[0])
+	nb := root.Content[1]
+	assert.Equal(t, "new", TextSpansToString(nb.InlineContent))
+	assert.Equal(t, root, nb.Parent)
+	assert.Equal(t, "user-1", root.LastEditedBy)
+	assert.Equal(t, tx.timestamp(), root.LastEditedTime)
+
+	// archived blocks are removed from the page
+	tx = NewTransaction("user-1", p)
+	assert.NoError(t, tx.Add(
+		nb.buildOp(CommandUpdate, []string{}, map[string]interface{}{"alive": false}),
+		root.ListRemoveContentOp(newID),
+	))
+	assert.NoError(t, tx.ApplyTo(p))
+	assert.Nil(t, p.BlockByID(NewNotionID(newID)))
+	assert.Equal(t, []*Block{text}, root.Content)
+}
@@ -1,9 +1,15 @@
 package notionapi
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type NotionID struct {
@@ -169,8 +175,119 @@ func (e *ErrPageNotFound) Error() string {
 
 // IsErrPageNotFound returns true if err is an instance of ErrPageNotFound
 func IsErrPageNotFound(err error) bool {
-	_, ok := err.(*ErrPageNotFound)
-	return ok
+	var e *ErrPageNotFound
+	return errors.As(err, &e)
+}
+
+// APIError is returned when Notion API returns a non-200 status code
+type APIError struct {
+	// http status code, e.g. 401
+	StatusCode int `json:"-"`
+	// name of API endpoint e.g. "syncRecordValues"
+	Endpoint string `json:"-"`
+	// full url of the request
+	URL string `json:"-"`
+
+	// decoded from the error json returned by Notion
+	ErrorID string `json:"errorId"`
+	Name    string `json:"name"` // e.g. "UnauthorizedError", "ValidationError"
+	Message string `json:"message"`
+
+	// from Retry-After header, 0 if not sent
+	RetryAfter time.Duration `json:"-"`
+
+	// raw body of the response
+	Body []byte `json:"-"`
+}
+
+// Error return error string
+func (e *APIError) Error() string {
+	s := fmt.Sprintf("http.Post('%s') returned non-200 status code of %d", e.URL, e.StatusCode)
+	if e.Name != "" {
+		s += ", " + e.Name
+	}
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
+
+func newAPIError(uri string, rsp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: rsp.StatusCode,
+		Endpoint:   endpointFromURL(uri),
+		URL:        uri,
+		RetryAfter: parseRetryAfter(rsp.Header.Get("Retry-After"), time.Now()),
+		Body:       body,
+	}
+	// not all errors have json body so ignore failures
+	_ = jsonit.Unmarshal(body, e)
+	return e
+}
+
+// endpointFromURL returns "syncRecordValues" for
+// "https://www.notion.so/api/v3/syncRecordValues"
+func endpointFromURL(uri string) string {
+	if u, err := url.Parse(uri); err == nil {
+		uri = u.Path
+	}
+	return path.Base(uri)
+}
+
+// parseRetryAfter parses value of Retry-After header which is either
+// number of seconds or http date
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	t, err := http.ParseTime(s)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
+
+// AsAPIError returns APIError if err is (or wraps) APIError
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsUnauthorized returns true if err is APIError because of missing
+// or invalid AuthToken
+func IsUnauthorized(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && (e.StatusCode == http.StatusUnauthorized || e.Name == "UnauthorizedError")
+}
+
+// IsRateLimited returns true if err is APIError because we've been
+// rate limited by Notion
+func IsRateLimited(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsNotFound returns true if err is APIError with 404 status code
+func IsNotFound(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// IsValidationError returns true if err is APIError because Notion
+// rejected the request as invalid (e.g. bad operation in SubmitTransaction)
+func IsValidationError(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && (e.StatusCode == http.StatusBadRequest || e.Name == "ValidationError")
 }
 
 func closeNoError(c io.Closer) {